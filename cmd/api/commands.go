@@ -0,0 +1,47 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/interimme/userapi/internal/cli"
+	"github.com/interimme/userapi/internal/config"
+)
+
+// commands returns the command tree of the binary. Without a command, serve runs.
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{Name: "serve", Summary: "Start the gRPC, gRPC-Gateway and Gin servers (default)", Run: serveCommand},
+		{Name: "migrate", Args: "up|down|status|to <version>", Summary: "Manage the database schema", Run: migrateCommand},
+		{Name: "seed", Summary: "Create sample users", Run: seedCommand},
+		{Name: "export", Summary: "Write users as JSON lines, optionally filtered", Run: exportCommand},
+		{Name: "import", Args: "[file]", Summary: "Create users from JSON lines written by export (default stdin)", Run: importCommand},
+		{Name: "user", Summary: "Manage individual users", Subs: []*cli.Command{
+			{Name: "get", Args: "<id>", Summary: "Print a user", Run: userGetCommand},
+			{Name: "create", Summary: "Create a user", Run: userCreateCommand},
+			{Name: "delete", Args: "<id>", Summary: "Delete a user", Run: userDeleteCommand},
+		}},
+		{Name: "email", Summary: "Maintain the email addresses of the users", Subs: []*cli.Command{
+			{Name: "recanonicalize", Summary: "Recompute the canonical email addresses with the configured rules", Run: emailRecanonicalizeCommand},
+		}},
+		{Name: "config", Summary: "Inspect the configuration", Subs: []*cli.Command{
+			{Name: "print", Summary: "Print the effective configuration with secrets redacted", Run: configPrintCommand},
+		}},
+	}
+}
+
+// loadConfig loads the configuration with the command's flags in fs,
+// exiting on -h or invalid settings.
+func loadConfig(fs *flag.FlagSet, args []string) *config.Config {
+	cfg, err := config.Load(fs, args)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	return cfg
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/cli"
+)
+
+func TestCommands(t *testing.T) {
+	root := &cli.Command{Name: "userapi", Subs: commands(), Default: "serve"}
+
+	for _, args := range [][]string{nil, {"migrate"}, {"seed"}, {"export"}, {"import"}, {"user", "get"}, {"user", "create"}, {"user", "delete"}, {"email", "recanonicalize"}, {"config", "print"}} {
+		cmd, _, err := root.Find(args)
+		require.NoError(t, err, args)
+		assert.NotNil(t, cmd.Run, args)
+	}
+}
+
+func TestSeedUser(t *testing.T) {
+	for i := 0; i < 200; i++ {
+		user := seedUser(i)
+		require.NoError(t, user.Validate(), i)
+	}
+	assert.NotEqual(t, seedUser(0).Email, seedUser(len(seedFirstnames)).Email)
+}
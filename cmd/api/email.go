@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/interimme/userapi/internal/cli"
+	"github.com/interimme/userapi/internal/email"
+	"github.com/interimme/userapi/internal/infrastructure/persistence"
+)
+
+// emailRecanonicalizeCommand recomputes the canonical email addresses with
+// the configured rules, e.g. after changing EMAIL_PROVIDER_RULES, and records
+// those rules in the database. It changes nothing if addresses would collide.
+func emailRecanonicalizeCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	dryRun := fs.Bool("dry-run", false, "Report the changes and collisions without applying them")
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 0 {
+		return cli.Usagef(cmd, "unexpected arguments %q", fs.Args())
+	}
+
+	// The check would refuse exactly the databases this command repairs
+	return withUncheckedStore(cfg, func(ctx context.Context, st *store) error {
+		rules := email.RuleSet(cfg.Email.ProviderRules)
+		report, err := persistence.RecanonicalizeEmails(ctx, st.db, rules, st.users.CanonicalEmail, *dryRun)
+		if err != nil {
+			return err
+		}
+
+		if len(report.Collisions) > 0 {
+			printCollisions(report.Collisions)
+			return fmt.Errorf("%d canonical addresses would be shared by several users; change or delete those users and retry", len(report.Collisions))
+		}
+		if *dryRun {
+			fmt.Printf("Would change %d of %d users to the %q rules\n", len(report.Changed), report.Users, rules)
+			return nil
+		}
+		fmt.Printf("Changed %d of %d users to the %q rules\n", len(report.Changed), report.Users, rules)
+		return nil
+	})
+}
+
+// printCollisions writes the users sharing a canonical address as a table to
+// stdout.
+func printCollisions(collisions map[string][]persistence.StoredEmail) {
+	canonicals := make([]string, 0, len(collisions))
+	for canonical := range collisions {
+		canonicals = append(canonicals, canonical)
+	}
+	sort.Strings(canonicals)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "CANONICAL\tID\tEMAIL")
+	for _, canonical := range canonicals {
+		for _, user := range collisions[canonical] {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", canonical, user.ID, user.Email)
+		}
+	}
+	w.Flush()
+}
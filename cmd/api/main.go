@@ -3,173 +3,240 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"sync"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 
+	"github.com/interimme/userapi/internal/auth"
+	"github.com/interimme/userapi/internal/cli"
 	"github.com/interimme/userapi/internal/config"
 	"github.com/interimme/userapi/internal/controller"
 	"github.com/interimme/userapi/internal/grpcserver"
+	"github.com/interimme/userapi/internal/health"
 	"github.com/interimme/userapi/internal/infrastructure"
-	"github.com/interimme/userapi/internal/infrastructure/db"
-	"github.com/interimme/userapi/internal/infrastructure/persistence"
-	"github.com/interimme/userapi/internal/usecase"
+	"github.com/interimme/userapi/internal/lifecycle"
+	"github.com/interimme/userapi/internal/logging"
+	"github.com/interimme/userapi/internal/metrics"
+	"github.com/interimme/userapi/internal/tracing"
 	userapi "github.com/interimme/userapi/proto"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 func main() {
-	if err := run(); err != nil {
-		log.Fatal(err)
+	root := &cli.Command{Name: filepath.Base(os.Args[0]), Subs: commands(), Default: "serve"}
+	os.Exit(cli.Execute(root, os.Args[1:], func(cmd *cli.Command, err error) {
+		slog.Error(fmt.Sprintf("%s failed", cmd.Path()), slog.String("error", err.Error()))
+	}))
+}
+
+// setupLogging installs the configured logger, writing to w, as the default
+// logger; the standard library logger is routed through it too.
+func setupLogging(cfg *config.Config, w io.Writer) (*slog.Logger, error) {
+	logger, err := logging.New(w, logging.Config{Level: cfg.Log.Level, Format: cfg.Log.Format})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up logging: %w", err)
 	}
+	slog.SetDefault(logger)
+	return logger, nil
 }
 
-func run() error {
-	// Load configuration
-	cfg := config.Init()
-
-	// Database connection string
-	dsn := fmt.Sprintf(
-		"host=%s user=%s password=%s dbname=%s port=%d sslmode=disable TimeZone=UTC",
-		cfg.Database.Host,
-		cfg.Database.User,
-		cfg.Database.Password,
-		cfg.Database.Name,
-		cfg.Database.Port,
-	)
+// serveCommand runs all servers until SIGINT or SIGTERM.
+func serveCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 0 {
+		return cli.Usagef(cmd, "unexpected arguments %q", fs.Args())
+	}
 
-	// Connect to the database
-	dbConn, err := db.Connect(dsn)
+	logger, err := setupLogging(cfg, os.Stdout)
 	if err != nil {
-		return fmt.Errorf("failed to connect to database: %w", err)
+		return err
 	}
 
-	// Retrieve the underlying *sql.DB to manage the connection pool
-	sqlDB, err := dbConn.DB()
+	// Set up tracing first so that every component picks up the global provider
+	shutdownTracing, err := tracing.Setup(context.Background(), tracing.Config{
+		Exporter:     cfg.Tracing.Exporter,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		OTLPInsecure: cfg.Tracing.OTLPInsecure,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+		ServiceName:  cfg.Tracing.ServiceName,
+	})
 	if err != nil {
-		return fmt.Errorf("failed to get underlying database connection: %w", err)
+		return fmt.Errorf("failed to set up tracing: %w", err)
 	}
 	defer func() {
-		if err := sqlDB.Close(); err != nil {
-			log.Printf("Error closing database connection: %v", err)
+		// Flush spans recorded while draining
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := shutdownTracing(ctx); err != nil {
+			logger.Error("Error flushing traces", slog.String("error", err.Error()))
 		}
 	}()
 
-	// Migrate the database schema
-	err = dbConn.AutoMigrate(&persistence.UserGorm{})
+	// Stop on SIGINT/SIGTERM, including while still waiting for the database
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Connect to the database and wire the use case, recording metrics for it
+	appMetrics := metrics.New()
+	st, err := openStore(ctx, cfg, appMetrics, auth.NewAPIKeys(cfg.Auth.Keys()))
 	if err != nil {
-		return fmt.Errorf("failed to migrate database: %w", err)
+		return err
+	}
+	defer func() {
+		if err := st.Close(); err != nil {
+			logger.Error("Error closing database connection", slog.String("error", err.Error()))
+		}
+	}()
+	if err := st.checkEmailRules(ctx, cfg.Email); err != nil {
+		return err
 	}
 
-	// Initialize repository, use case, and controller
-	userRepo := persistence.NewUserRepository(dbConn)
-	userUseCase := usecase.NewUserUseCase(userRepo)
-	userController := controller.NewUserController(userUseCase)
+	// Record a span for every query
+	if err := tracing.InstrumentGORM(st.db); err != nil {
+		return fmt.Errorf("failed to instrument database: %w", err)
+	}
+	for _, replica := range st.resolver.Replicas() {
+		if err := tracing.InstrumentGORM(replica.DB); err != nil {
+			return fmt.Errorf("failed to instrument database replica %s: %w", replica.Name, err)
+		}
+	}
+
+	// Skip replicas that stop answering until they recover
+	go st.resolver.Run(ctx, cfg.Database.ReplicaHealthInterval)
+
+	// Export connection pool statistics
+	if err := appMetrics.RegisterDB(st.sqlDB, st.db.Migrator().CurrentDatabase()); err != nil {
+		return fmt.Errorf("failed to register database metrics: %w", err)
+	}
+
+	userController := controller.NewUserController(st.users)
+
+	// The lifecycle manager owns server startup and shutdown; readiness follows its state
+	manager := lifecycle.NewManager(cfg.Server.DrainDelay, cfg.Server.ShutdownTimeout)
+
+	// Set up health checks: readiness requires a reachable database
+	healthChecker := health.NewChecker(cfg.Server.HealthCheckTimeout)
+	healthChecker.SetReadyFunc(manager.Ready)
+	healthChecker.AddCheck("database", st.sqlDB.PingContext)
 
 	// Initialize Gin router
-	router := infrastructure.NewRouter(userController)
+	router := infrastructure.NewRouter(userController, healthChecker, appMetrics, logger)
+	if err := router.SetTrustedProxies(cfg.Server.Proxies()); err != nil {
+		return fmt.Errorf("failed to set trusted proxies: %w", err)
+	}
+
+	// Only the gateway's own connection may name the client in X-Forwarded-For
+	callers, err := grpcserver.NewCallers(cfg.Server.Proxies())
+	if err != nil {
+		return fmt.Errorf("failed to set up caller identification: %w", err)
+	}
 
 	// Set up gRPC server
-	grpcAddr := fmt.Sprintf(":%d", cfg.Server.GrpcPort)
-	grpcServer := grpc.NewServer()
-	grpcSrv := grpcserver.NewServer(userUseCase)
+	grpcServer := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.ChainUnaryInterceptor(logging.UnaryServerInterceptor(logger), appMetrics.UnaryServerInterceptor(), grpcserver.UnaryCallerInterceptor(callers), grpcserver.UnaryAuthInterceptor()),
+		grpc.ChainStreamInterceptor(logging.StreamServerInterceptor(logger), appMetrics.StreamServerInterceptor(), grpcserver.StreamCallerInterceptor(callers), grpcserver.StreamAuthInterceptor()),
+	)
+	grpcSrv := grpcserver.NewServer(st.users)
 	userapi.RegisterUserServiceServer(grpcServer, grpcSrv)
 
 	// Register reflection service on gRPC server.
 	reflection.Register(grpcServer)
 
-	// Set up gRPC-Gateway
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	// Register the standard gRPC health service; it reports NOT_SERVING once shutdown starts
+	healthServer := grpchealth.NewServer()
+	healthServer.SetServingStatus(userapi.UserService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	manager.OnDrain(healthServer.Shutdown)
 
-	gwMux := runtime.NewServeMux()
-	opts := []grpc.DialOption{grpc.WithInsecure()} // Insecure for simplicity; use TLS in production
-	err = userapi.RegisterUserServiceHandlerFromEndpoint(ctx, gwMux, grpcAddr, opts)
-	if err != nil {
-		return fmt.Errorf("failed to register gRPC-Gateway: %w", err)
+	// Bind all listeners up front so that port conflicts fail fast
+	grpcAddr := fmt.Sprintf(":%d", cfg.Server.GrpcPort)
+	if cfg.Server.SinglePort() {
+		grpcAddr = fmt.Sprintf(":%d", cfg.Server.Port) // gRPC is reached through the shared listener
 	}
-
-	// Listen on gRPC port
 	grpcListener, err := net.Listen("tcp", grpcAddr)
 	if err != nil {
 		return fmt.Errorf("failed to listen on %s: %w", grpcAddr, err)
 	}
 
-	// Set up HTTP server for gRPC-Gateway
-	httpAddr := fmt.Sprintf(":%d", cfg.Server.HttpPort)
-	httpServer := &http.Server{
-		Addr:    httpAddr,
-		Handler: gwMux,
-	}
+	// Set up gRPC-Gateway. Its client connection stays open until the servers have drained.
+	gwCtx, gwCancel := context.WithCancel(context.Background())
+	defer gwCancel()
 
-	// Create WaitGroup and channels for error handling
-	var wg sync.WaitGroup
-	errc := make(chan error, 3)
-
-	// Start gRPC server
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Printf("gRPC server listening on %s", grpcAddr)
-		if err := grpcServer.Serve(grpcListener); err != nil {
-			errc <- fmt.Errorf("gRPC server failed: %w", err)
+	gwMux := infrastructure.NewGatewayMux(
+		runtime.WithMiddlewares(appMetrics.GatewayMiddleware, tracing.GatewayMiddleware),
+		runtime.WithMetadata(logging.GatewayMetadata), // Forwards X-Request-ID to gRPC
+	)
+	if err := infrastructure.RegisterHealthHandlers(gwMux, healthChecker); err != nil {
+		return fmt.Errorf("failed to register gateway health handlers: %w", err)
+	}
+	if err := infrastructure.RegisterMetricsHandler(gwMux, appMetrics); err != nil {
+		return fmt.Errorf("failed to register gateway metrics handler: %w", err)
+	}
+	if err := infrastructure.RegisterDocsHandlers(gwMux); err != nil {
+		return fmt.Errorf("failed to register gateway docs handlers: %w", err)
+	}
+	opts := []grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()), // Insecure for simplicity; use TLS in production
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),       // Propagates trace context into gRPC metadata
+		callers.GatewayDialOption(),
+	}
+	err = userapi.RegisterUserServiceHandlerFromEndpoint(gwCtx, gwMux, grpcAddr, opts)
+	if err != nil {
+		return fmt.Errorf("failed to register gRPC-Gateway: %w", err)
+	}
+	gwHandler := tracing.HTTPHandler(logging.HTTPMiddleware(logger, "gateway", gwMux), "gateway")
+
+	// Register servers with the lifecycle manager. Dependencies come first:
+	// servers are shut down in reverse order, so gRPC outlives the gateway.
+	if cfg.Server.SinglePort() {
+		// Serve gRPC, gRPC-Gateway and Gin on one listener
+		handler := infrastructure.NewSinglePortHandler(grpcServer, gwHandler, router, cfg.Server.GatewayPrefix, cfg.Server.GinPrefix)
+		server, err := infrastructure.NewSinglePortServer(grpcListener, handler, grpcServer)
+		if err != nil {
+			return fmt.Errorf("failed to set up single-port server: %w", err)
 		}
-	}()
-
-	// Start HTTP server for gRPC-Gateway
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
-		log.Printf("gRPC-Gateway HTTP server listening on %s", httpAddr)
-		if err := httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			errc <- fmt.Errorf("HTTP gateway failed: %w", err)
+		manager.Add("HTTP server", server)
+		logger.Info("gRPC, gRPC-Gateway and Gin listening on a single port",
+			slog.String("addr", grpcAddr),
+			slog.String("gateway_prefix", cfg.Server.GatewayPrefix+"/"),
+			slog.String("gin_prefix", cfg.Server.GinPrefix+"/"),
+		)
+	} else {
+		httpAddr := fmt.Sprintf(":%d", cfg.Server.HttpPort)
+		httpListener, err := net.Listen("tcp", httpAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", httpAddr, err)
 		}
-	}()
-
-	// Start Gin HTTP server
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
 		ginAddr := fmt.Sprintf(":%d", cfg.Server.GinPort)
-		log.Printf("Gin HTTP server listening on %s", ginAddr)
-		if err := router.Run(ginAddr); err != nil {
-			errc <- fmt.Errorf("Gin HTTP server failed: %w", err)
-		}
-	}()
-
-	// Handle graceful shutdown
-	go func() {
-		sigCh := make(chan os.Signal, 1)
-		signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
-		sig := <-sigCh
-		log.Printf("Received signal: %v. Shutting down...", sig)
-
-		// Gracefully stop gRPC server
-		grpcServer.GracefulStop()
-
-		// Shutdown HTTP server for gRPC-Gateway
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer shutdownCancel()
-		if err := httpServer.Shutdown(shutdownCtx); err != nil {
-			log.Printf("HTTP gateway shutdown failed: %v", err)
+		ginListener, err := net.Listen("tcp", ginAddr)
+		if err != nil {
+			return fmt.Errorf("failed to listen on %s: %w", ginAddr, err)
 		}
-	}()
 
-	// Wait for servers or errors
-	select {
-	case err := <-errc:
-		return err
-	case <-time.After(time.Hour): // Placeholder to prevent blocking
+		manager.Add("gRPC server", &lifecycle.GRPCServer{Server: grpcServer, Listener: grpcListener})
+		manager.Add("gRPC-Gateway HTTP server", &lifecycle.HTTPServer{Server: &http.Server{Handler: gwHandler}, Listener: httpListener})
+		manager.Add("Gin HTTP server", &lifecycle.HTTPServer{Server: &http.Server{Handler: router}, Listener: ginListener})
+		logger.Info("gRPC server listening", slog.String("addr", grpcAddr))
+		logger.Info("gRPC-Gateway HTTP server listening", slog.String("addr", httpAddr))
+		logger.Info("Gin HTTP server listening", slog.String("addr", ginAddr))
 	}
 
-	wg.Wait()
-	return nil
+	// Run until SIGINT/SIGTERM, then drain all servers within the shutdown timeout.
+	// The database is closed by the deferred call only after Run has returned.
+	return manager.Run(ctx)
 }
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/interimme/userapi/internal/cli"
+	"github.com/interimme/userapi/internal/infrastructure/db"
+	"github.com/interimme/userapi/internal/infrastructure/db/migrations"
+)
+
+// migrateCommand applies, reverts or lists schema migrations.
+func migrateCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	cfg := loadConfig(fs, args)
+	args = fs.Args()
+	if len(args) == 0 {
+		return cli.Usagef(cmd, "missing migrate command, want up, down, status or to <version>")
+	}
+	action, args := args[0], args[1:]
+
+	var target int64
+	switch action {
+	case "up", "down", "status":
+		if len(args) != 0 {
+			return cli.Usagef(cmd, "%s takes no arguments", action)
+		}
+	case "to":
+		if len(args) != 1 {
+			return cli.Usagef(cmd, "to takes exactly one version")
+		}
+		var err error
+		if target, err = strconv.ParseInt(args[0], 10, 64); err != nil {
+			return cli.Usagef(cmd, "invalid version %q", args[0])
+		}
+	default:
+		return cli.Usagef(cmd, "unknown migrate command %q, want up, down, status or to <version>", action)
+	}
+
+	if _, err := setupLogging(cfg, os.Stderr); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	dbConn, err := db.Connect(ctx, cfg.Database)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	sqlDB, err := dbConn.DB()
+	if err != nil {
+		return fmt.Errorf("failed to get underlying database connection: %w", err)
+	}
+	defer sqlDB.Close()
+
+	migrator, err := migrations.NewMigrator(sqlDB)
+	if err != nil {
+		return fmt.Errorf("failed to load migrations: %w", err)
+	}
+
+	switch action {
+	case "up":
+		return migrator.Up(ctx)
+	case "down":
+		return migrator.Down(ctx)
+	case "to":
+		return migrator.To(ctx, target)
+	default:
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		printStatus(statuses)
+		return nil
+	}
+}
+
+// printStatus writes the migration status as a table to stdout.
+func printStatus(statuses []migrations.Status) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Contract {
+			state = "pending (contract)"
+		}
+		if s.Applied {
+			state, appliedAt = "applied", s.AppliedAt.UTC().Format(time.RFC3339)
+		}
+		name := s.Name
+		if s.Unknown {
+			name = "(unknown to this build)"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, name, state, appliedAt)
+	}
+	_ = w.Flush()
+}
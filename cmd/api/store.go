@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/config"
+	"github.com/interimme/userapi/internal/email"
+	"github.com/interimme/userapi/internal/infrastructure/cache"
+	"github.com/interimme/userapi/internal/infrastructure/db"
+	"github.com/interimme/userapi/internal/infrastructure/db/migrations"
+	"github.com/interimme/userapi/internal/infrastructure/persistence"
+	"github.com/interimme/userapi/internal/metrics"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+// store is the database connection and the use case built on it, wired the
+// same way for the servers and for the maintenance commands.
+type store struct {
+	db       *gorm.DB
+	sqlDB    *sql.DB
+	resolver *db.Resolver
+	users    *usecase.UserUseCase
+}
+
+// openStore connects to the database, applies pending migrations unless
+// disabled, and wires the repository, its cache and the use case. m may be
+// nil when metrics are not exported. authz authorizes privileged use cases.
+func openStore(ctx context.Context, cfg *config.Config, m *metrics.Metrics, authz usecase.Authorizer) (*store, error) {
+	dbConn, err := db.Connect(ctx, cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	// Retrieve the underlying *sql.DB to manage the connection pool
+	sqlDB, err := dbConn.DB()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get underlying database connection: %w", err)
+	}
+
+	// Bring the database schema up to date unless migrations are run separately.
+	// Contract migrations wait for the migrate command, since instances of the
+	// previous release may still be running.
+	if cfg.Database.AutoMigrate {
+		migrator, err := migrations.NewMigrator(sqlDB)
+		if err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to load migrations: %w", err)
+		}
+		if err := migrator.Expand(ctx); err != nil {
+			_ = sqlDB.Close()
+			return nil, fmt.Errorf("failed to migrate database: %w", err)
+		}
+	}
+
+	// Replicas that are down do not hold up startup; reads avoid them until they answer
+	replicas, err := db.OpenReplicas(ctx, cfg.Database)
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("failed to open database replicas: %w", err)
+	}
+	resolver := db.NewResolver(dbConn, replicas, cfg.Database.ReplicaStickiness)
+
+	var (
+		useCaseOpts []usecase.Option
+		cacheOpts   []cache.Option
+	)
+	if m != nil {
+		useCaseOpts = append(useCaseOpts, usecase.WithMetrics(m))
+		cacheOpts = append(cacheOpts, cache.WithMetrics(m))
+	}
+
+	userRepo := persistence.NewReplicatedUserRepository(resolver)
+	unitOfWork := persistence.NewUnitOfWork(resolver, cfg.Database.TxAttempts)
+	if cfg.Cache.Enabled {
+		userRepo = cache.NewUserRepository(userRepo, cfg.Cache, cacheOpts...)
+		unitOfWork = cache.NewUnitOfWork(unitOfWork, userRepo)
+	}
+	if cfg.Email.ProviderRules {
+		useCaseOpts = append(useCaseOpts, usecase.WithProviderEmailRules())
+	}
+	useCaseOpts = append(useCaseOpts,
+		usecase.WithUnitOfWork(unitOfWork),
+		usecase.WithBatchLimits(cfg.Batch.MaxGet, cfg.Batch.MaxWrite),
+		usecase.WithMinimumAge(uint(cfg.Users.MinAge)),
+		usecase.WithAuthorizer(authz))
+	return &store{
+		db:       dbConn,
+		sqlDB:    sqlDB,
+		resolver: resolver,
+		users:    usecase.NewUserUseCase(userRepo, useCaseOpts...),
+	}, nil
+}
+
+// checkEmailRules returns an error if the stored canonical email addresses
+// were computed with other rules than those of cfg, since lookups and the
+// uniqueness of addresses would silently go wrong.
+func (s *store) checkEmailRules(ctx context.Context, cfg config.EmailConfig) error {
+	stored, err := persistence.EmailRules(ctx, s.db)
+	if err != nil {
+		return fmt.Errorf("failed to read the email rules of the database: %w", err)
+	}
+	if configured := email.RuleSet(cfg.ProviderRules); stored != configured {
+		return fmt.Errorf("the stored email addresses are canonical under the %q rules, but %q are configured; run \"userapi email recanonicalize\" with the new configuration first", stored, configured)
+	}
+	return nil
+}
+
+// Close closes the database connections.
+func (s *store) Close() error {
+	return errors.Join(s.resolver.Close(), s.sqlDB.Close())
+}
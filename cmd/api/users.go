@@ -0,0 +1,333 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+
+	"github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/auth"
+	"github.com/interimme/userapi/internal/cli"
+	"github.com/interimme/userapi/internal/config"
+	"github.com/interimme/userapi/internal/controller"
+	"github.com/interimme/userapi/internal/entity"
+)
+
+// exportBatchSize is the number of users read per query by export.
+const exportBatchSize = 500
+
+// withStore sets up logging to stderr, opens the store and runs fn until it
+// returns or the process receives SIGINT or SIGTERM. It refuses to run if the
+// stored email addresses are canonical under other rules than the configured.
+func withStore(cfg *config.Config, fn func(ctx context.Context, st *store) error) error {
+	return withUncheckedStore(cfg, func(ctx context.Context, st *store) error {
+		if err := st.checkEmailRules(ctx, cfg.Email); err != nil {
+			return err
+		}
+		return fn(ctx, st)
+	})
+}
+
+// withUncheckedStore is withStore without checking the email rules.
+func withUncheckedStore(cfg *config.Config, fn func(ctx context.Context, st *store) error) error {
+	if _, err := setupLogging(cfg, os.Stderr); err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// The commands work on the database directly, so they hold every permission
+	st, err := openStore(ctx, cfg, nil, auth.Operator{})
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	return fn(ctx, st)
+}
+
+// printJSON writes v as indented JSON to stdout.
+func printJSON(v any) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// isAlreadyExists reports whether err means that the user exists already.
+func isAlreadyExists(err error) bool {
+	return apperrors.FromError(err).Code == codes.AlreadyExists
+}
+
+// idArg parses the single <id> argument of cmd.
+func idArg(cmd *cli.Command, args []string) (uuid.UUID, error) {
+	if len(args) != 1 {
+		return uuid.Nil, cli.Usagef(cmd, "expected exactly one user ID")
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return uuid.Nil, cli.Usagef(cmd, "invalid user ID %q", args[0])
+	}
+	return id, nil
+}
+
+// userGetCommand prints a user as JSON.
+func userGetCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	cfg := loadConfig(fs, args)
+	id, err := idArg(cmd, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return withStore(cfg, func(ctx context.Context, st *store) error {
+		user, err := st.users.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		return printJSON(controller.NewUserResponse(user))
+	})
+}
+
+// userCreateCommand creates a user from flags and prints it as JSON.
+func userCreateCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	var req controller.CreateUserRequest
+	fs.StringVar(&req.Firstname, "firstname", "", "First name")
+	fs.StringVar(&req.Lastname, "lastname", "", "Last name")
+	fs.StringVar(&req.Email, "email", "", "Email address")
+	fs.StringVar(&req.DateOfBirth, "date-of-birth", "", "Date of birth, e.g. 1989-12-10")
+	age := fs.Uint("age", 0, "Age, for users without a date of birth")
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 0 {
+		return cli.Usagef(cmd, "unexpected arguments %q", fs.Args())
+	}
+	req.Age = uint32(*age)
+
+	return withStore(cfg, func(ctx context.Context, st *store) error {
+		user, err := req.ToEntity()
+		if err != nil {
+			return err
+		}
+		if err := st.users.CreateUser(ctx, user); err != nil {
+			return err
+		}
+		return printJSON(controller.NewUserResponse(user))
+	})
+}
+
+// userDeleteCommand deletes a user.
+func userDeleteCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	cfg := loadConfig(fs, args)
+	id, err := idArg(cmd, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	return withStore(cfg, func(ctx context.Context, st *store) error {
+		if err := st.users.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		return printJSON(controller.DeleteUserResponse{Message: "User deleted successfully"})
+	})
+}
+
+// seedCommand creates sample users. Their emails are deterministic, so
+// seeding twice skips the users created the first time.
+func seedCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	count := fs.Int("count", 10, "Number of users to create")
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 0 {
+		return cli.Usagef(cmd, "unexpected arguments %q", fs.Args())
+	}
+	if *count <= 0 {
+		return cli.Usagef(cmd, "-count must be positive")
+	}
+
+	return withStore(cfg, func(ctx context.Context, st *store) error {
+		var created, skipped int
+		for i := 0; i < *count; i++ {
+			err := st.users.CreateUser(ctx, seedUser(i))
+			switch {
+			case err == nil:
+				created++
+			case isAlreadyExists(err):
+				skipped++
+			default:
+				return err
+			}
+		}
+		fmt.Printf("Created %d users, skipped %d existing\n", created, skipped)
+		return nil
+	})
+}
+
+var (
+	seedFirstnames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy"}
+	seedLastnames  = []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis"}
+)
+
+// seedUser returns the i-th sample user.
+func seedUser(i int) *entity.User {
+	firstname := seedFirstnames[i%len(seedFirstnames)]
+	lastname := seedLastnames[(i/len(seedFirstnames))%len(seedLastnames)]
+	return &entity.User{
+		Firstname: firstname,
+		Lastname:  lastname,
+		Email:     fmt.Sprintf("%s.%s.%d@example.com", strings.ToLower(firstname), strings.ToLower(lastname), i+1),
+		// Born between 1950 and 2004
+		DateOfBirth: time.Date(1950+(i*7)%55, time.Month(1+i%12), 1+(i*11)%28, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// exportCommand writes every user as one JSON object per line, in the REST
+// representation, ordered by ID.
+func exportCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	output := fs.String("o", "-", "Output file; - writes to stdout")
+	filterExpr := fs.String("filter", "", `Export only users matching this filter, e.g. 'age >= 18 AND email:"*@example.com"'`)
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 0 {
+		return cli.Usagef(cmd, "unexpected arguments %q", fs.Args())
+	}
+
+	return withStore(cfg, func(ctx context.Context, st *store) (err error) {
+		w := io.Writer(os.Stdout)
+		if *output != "-" {
+			// Assigned, not declared, so that the deferred close error reaches the result
+			var f *os.File
+			f, err = os.Create(*output)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				if closeErr := f.Close(); err == nil {
+					err = closeErr
+				}
+			}()
+			w = f
+		}
+
+		enc := json.NewEncoder(w)
+		exported := 0
+		after := uuid.Nil
+		for {
+			users, err := st.users.ListUsers(ctx, *filterExpr, after, exportBatchSize)
+			if err != nil {
+				return err
+			}
+			for _, user := range users {
+				if err := enc.Encode(controller.NewUserResponse(user)); err != nil {
+					return err
+				}
+			}
+			exported += len(users)
+			if len(users) < exportBatchSize {
+				break
+			}
+			after = users[len(users)-1].ID
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d users\n", exported)
+		return nil
+	})
+}
+
+// importCommand creates users from the output of export, keeping their IDs
+// and creation times.
+func importCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	skipExisting := fs.Bool("skip-existing", false, "Skip users whose ID or email exists already instead of failing")
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 1 {
+		return cli.Usagef(cmd, "expected at most one file")
+	}
+
+	r := io.Reader(os.Stdin)
+	if path := fs.Arg(0); path != "" && path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	return withStore(cfg, func(ctx context.Context, st *store) error {
+		dec := json.NewDecoder(r)
+		dec.DisallowUnknownFields()
+
+		var imported, skipped int
+		for n := 1; ; n++ {
+			var record controller.UserResponse
+			if err := dec.Decode(&record); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return fmt.Errorf("record %d: %w", n, err)
+			}
+
+			user, err := importedUser(&record)
+			if err != nil {
+				return fmt.Errorf("record %d: %w", n, err)
+			}
+			err = st.users.ImportUser(ctx, user)
+			switch {
+			case err == nil:
+				imported++
+			case *skipExisting && isAlreadyExists(err):
+				skipped++
+			default:
+				return fmt.Errorf("record %d: %w", n, err)
+			}
+		}
+		fmt.Printf("Imported %d users, skipped %d existing\n", imported, skipped)
+		return nil
+	})
+}
+
+// importedUser converts an exported record into a user entity.
+func importedUser(record *controller.UserResponse) (*entity.User, error) {
+	dateOfBirth, err := entity.ParseDate(record.DateOfBirth)
+	if err != nil {
+		return nil, err
+	}
+	user := &entity.User{
+		Firstname:   record.Firstname,
+		Lastname:    record.Lastname,
+		Email:       record.Email,
+		Age:         uint(record.Age),
+		DateOfBirth: dateOfBirth,
+		Created:     record.Created,
+		Updated:     record.Updated,
+	}
+	if record.ID != "" {
+		id, err := uuid.Parse(record.ID)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user ID %q", record.ID)
+		}
+		user.ID = id
+	}
+	return user, nil
+}
+
+// configPrintCommand prints the effective configuration.
+func configPrintCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	format := fs.String("format", "yaml", "Output format: yaml or toml")
+	cfg := loadConfig(fs, args)
+	if fs.NArg() > 0 {
+		return cli.Usagef(cmd, "unexpected arguments %q", fs.Args())
+	}
+	return cfg.Write(os.Stdout, *format)
+}
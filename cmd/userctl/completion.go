@@ -0,0 +1,155 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/interimme/userapi/internal/cli"
+)
+
+// Shells call userctl itself for completions with the command line in these
+// variables, the protocol of bash's complete -C.
+const (
+	compLineEnv  = "COMP_LINE"
+	compPointEnv = "COMP_POINT"
+)
+
+// completionScripts register userctl as its own completer.
+var completionScripts = map[string]string{
+	"bash": "complete -o default -C userctl userctl\n",
+	"zsh":  "autoload -U +X bashcompinit && bashcompinit\ncomplete -o default -C userctl userctl\n",
+	"fish": "complete -c userctl -f -a '(env COMP_LINE=(commandline -cp) userctl)'\n",
+}
+
+// completionCommand prints the completion script of a shell.
+func completionCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	args = parseArgs(fs, args)
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		return cli.Usagef(cmd, "expected one of bash, zsh or fish")
+	}
+	_, err := fmt.Print(completionScripts[args[0]])
+	return err
+}
+
+// commandFlags registers the flags a command has besides the global ones.
+var commandFlags = map[string]func(fs *flag.FlagSet){
+	"userctl create":      func(fs *flag.FlagSet) { new(userFlags).register(fs) },
+	"userctl update":      func(fs *flag.FlagSet) { new(userFlags).register(fs) },
+	"userctl create-bulk": func(fs *flag.FlagSet) { bulkFlags(fs) },
+	"userctl watch":       func(fs *flag.FlagSet) { watchFlags(fs) },
+}
+
+// flagsOf returns the flag set cmd parses.
+func flagsOf(cmd *cli.Command) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.Path(), flag.ContinueOnError)
+	switch {
+	case cmd.Path() == "userctl completion":
+	case cmd.Path() == "userctl profile set":
+		addGlobalFlags(fs)
+	case strings.HasPrefix(cmd.Path(), "userctl profile "):
+		profileFlags(fs)
+	default:
+		addGlobalFlags(fs)
+		if register := commandFlags[cmd.Path()]; register != nil {
+			register(fs)
+		}
+	}
+	return fs
+}
+
+// printCompletions prints the candidates for the last word of line.
+func printCompletions(w io.Writer, root *cli.Command, line string) {
+	if point, err := strconv.Atoi(os.Getenv(compPointEnv)); err == nil && point >= 0 && point < len(line) {
+		line = line[:point]
+	}
+	for _, c := range completions(root, line) {
+		fmt.Fprintln(w, c)
+	}
+}
+
+// completions returns the candidates for the last, possibly empty, word of a
+// command line: commands, flags, profile names and flag values.
+func completions(root *cli.Command, line string) []string {
+	words := strings.Fields(line)
+	if len(words) == 0 {
+		return nil
+	}
+	words = words[1:]
+	current := ""
+	if !strings.HasSuffix(line, " ") && len(words) > 0 {
+		current = words[len(words)-1]
+		words = words[:len(words)-1]
+	}
+
+	cmd := root
+	var positional []string
+	for _, word := range words {
+		if strings.HasPrefix(word, "-") {
+			continue
+		}
+		if sub := cmd.Sub(word); cmd.Run == nil && sub != nil {
+			cmd = sub
+		} else {
+			positional = append(positional, word)
+		}
+	}
+
+	var candidates []string
+	switch {
+	case cmd.Run == nil:
+		for _, sub := range cmd.Subs {
+			candidates = append(candidates, sub.Name)
+		}
+	case len(words) > 0 && takesValue(cmd, words[len(words)-1]):
+		switch strings.TrimLeft(words[len(words)-1], "-") {
+		case "profile":
+			candidates = profileNames()
+		case "o":
+			candidates = []string{outputTable, outputJSON, outputYAML}
+		}
+	case strings.HasPrefix(current, "-"):
+		flagsOf(cmd).VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "-"+f.Name)
+		})
+	case cmd.Path() == "userctl completion" && len(positional) == 0:
+		candidates = []string{"bash", "fish", "zsh"}
+	case strings.HasPrefix(cmd.Path(), "userctl profile ") && cmd.Name != "list" && len(positional) == 0:
+		candidates = profileNames()
+	}
+
+	var matches []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, current) {
+			matches = append(matches, c)
+		}
+	}
+	return matches
+}
+
+// takesValue reports whether word is a flag of cmd followed by a separate value.
+func takesValue(cmd *cli.Command, word string) bool {
+	name := strings.TrimLeft(word, "-")
+	if !strings.HasPrefix(word, "-") || strings.Contains(name, "=") {
+		return false
+	}
+	f := flagsOf(cmd).Lookup(name)
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return !ok || !b.IsBoolFlag()
+}
+
+// profileNames returns the profiles in the default profiles file.
+func profileNames() []string {
+	pf, err := loadProfiles(defaultConfigPath())
+	if err != nil {
+		return nil
+	}
+	return pf.names()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/interimme/userapi/internal/cli"
+)
+
+func TestCompletions(t *testing.T) {
+	t.Setenv(configEnv, writeProfiles(t, &ProfilesFile{Profiles: map[string]Profile{"dev": {}, "prod": {}}}))
+	root := &cli.Command{Name: "userctl", Subs: commands()}
+
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"userctl ", []string{"get", "create", "update", "delete", "create-bulk", "watch", "profile", "completion"}},
+		{"userctl cr", []string{"create", "create-bulk"}},
+		{"userctl profile ", []string{"list", "show", "set", "use", "delete"}},
+		{"userctl profile use ", []string{"dev", "prod"}},
+		{"userctl profile use dev ", nil},
+		{"userctl get -profile p", []string{"prod"}},
+		{"userctl get -o ", []string{"table", "json", "yaml"}},
+		{"userctl get -server ", nil},
+		{"userctl create -first", []string{"-firstname"}},
+		{"userctl watch -inter", []string{"-interval"}},
+		{"userctl get -tls -o j", []string{"json"}},
+		{"userctl completion ", []string{"bash", "fish", "zsh"}},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, completions(root, tt.line), tt.line)
+	}
+}
@@ -0,0 +1,46 @@
+// Command userctl is a command-line client for the UserService gRPC API.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"google.golang.org/grpc/status"
+
+	"github.com/interimme/userapi/internal/cli"
+)
+
+func main() {
+	root := &cli.Command{Name: "userctl", Subs: commands()}
+	if line, ok := os.LookupEnv(compLineEnv); ok {
+		printCompletions(os.Stdout, root, line)
+		return
+	}
+	os.Exit(cli.Execute(root, os.Args[1:], func(cmd *cli.Command, err error) {
+		if st, ok := status.FromError(err); ok {
+			fmt.Fprintf(os.Stderr, "Error: %s (%s)\n", st.Message(), st.Code())
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+	}))
+}
+
+// commands returns the command tree of userctl.
+func commands() []*cli.Command {
+	return []*cli.Command{
+		{Name: "get", Args: "<id>", Summary: "Print a user", Run: getCommand},
+		{Name: "create", Summary: "Create a user", Run: createCommand},
+		{Name: "update", Args: "<id>", Summary: "Change the fields of a user given as flags", Run: updateCommand},
+		{Name: "delete", Args: "<id>", Summary: "Delete a user", Run: deleteCommand},
+		{Name: "create-bulk", Args: "<file>", Summary: "Create users from a JSON lines, JSON array or YAML file (- reads stdin)", Run: createBulkCommand},
+		{Name: "watch", Args: "<id>", Summary: "Print a user whenever it changes until it is deleted", Run: watchCommand},
+		{Name: "profile", Summary: "Manage connection profiles", Subs: []*cli.Command{
+			{Name: "list", Summary: "List the profiles; * marks the current one", Run: profileListCommand},
+			{Name: "show", Args: "[name]", Summary: "Print a profile, by default the current one", Run: profileShowCommand},
+			{Name: "set", Args: "<name>", Summary: "Create or change a profile from the connection flags given", Run: profileSetCommand},
+			{Name: "use", Args: "<name>", Summary: "Make a profile the current one", Run: profileUseCommand},
+			{Name: "delete", Args: "<name>", Summary: "Delete a profile", Run: profileDeleteCommand},
+		}},
+		{Name: "completion", Args: "bash|zsh|fish", Summary: "Print a shell completion script", Run: completionCommand},
+	}
+}
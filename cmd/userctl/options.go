@@ -0,0 +1,216 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	userapi "github.com/interimme/userapi/proto"
+)
+
+// Defaults used when neither a flag, the environment nor a profile sets a value.
+const (
+	defaultServer  = "localhost:9090"
+	defaultTimeout = 10 * time.Second
+	defaultOutput  = "table"
+)
+
+// Environment variables that override the profile.
+const (
+	serverEnv = "USERCTL_SERVER"
+	tokenEnv  = "USERCTL_TOKEN"
+)
+
+// options are the connection and output settings shared by all commands.
+type options struct {
+	configPath string
+	profile    string
+
+	Profile // Settings given as flags
+	timeout time.Duration
+}
+
+// addGlobalFlags registers the shared flags on fs.
+func addGlobalFlags(fs *flag.FlagSet) *options {
+	o := &options{}
+	fs.StringVar(&o.configPath, "config", defaultConfigPath(), "Profiles file (env "+configEnv+")")
+	fs.StringVar(&o.profile, "profile", "", "Profile to use instead of the current one")
+	fs.StringVar(&o.Server, "server", defaultServer, "host:port of the gRPC server (env "+serverEnv+")")
+	fs.BoolVar(&o.TLS, "tls", false, "Connect with TLS")
+	fs.StringVar(&o.CAFile, "ca-file", "", "CA certificate used to verify the server (implies -tls)")
+	fs.StringVar(&o.CertFile, "cert-file", "", "Client certificate for mutual TLS (implies -tls)")
+	fs.StringVar(&o.KeyFile, "key-file", "", "Private key of the client certificate")
+	fs.StringVar(&o.ServerName, "server-name", "", "Override the server name verified by TLS")
+	fs.BoolVar(&o.InsecureSkipVerify, "insecure-skip-verify", false, "Do not verify the server certificate")
+	fs.StringVar(&o.Token, "token", "", "Bearer token sent with every call (env "+tokenEnv+")")
+	fs.StringVar(&o.TokenFile, "token-file", "", "File containing the bearer token")
+	fs.DurationVar(&o.timeout, "timeout", defaultTimeout, "Deadline of each call")
+	fs.StringVar(&o.Output, "o", defaultOutput, "Output format: table, json or yaml")
+	return o
+}
+
+// resolve fills every setting not given as a flag from the environment and
+// then from the selected profile.
+func (o *options) resolve(fs *flag.FlagSet) error {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	pf, err := loadProfiles(o.configPath)
+	if err != nil {
+		return err
+	}
+	p, err := pf.profile(o.profile)
+	if err != nil {
+		return err
+	}
+
+	if env := os.Getenv(serverEnv); env != "" {
+		p.Server = env
+	}
+	if env := os.Getenv(tokenEnv); env != "" {
+		p.Token, p.TokenFile = env, ""
+	}
+
+	str := func(flagName string, dst *string, profileValue string) {
+		if !set[flagName] && profileValue != "" {
+			*dst = profileValue
+		}
+	}
+	str("server", &o.Server, p.Server)
+	str("ca-file", &o.CAFile, p.CAFile)
+	str("cert-file", &o.CertFile, p.CertFile)
+	str("key-file", &o.KeyFile, p.KeyFile)
+	str("server-name", &o.ServerName, p.ServerName)
+	str("o", &o.Output, p.Output)
+	if !set["token"] && !set["token-file"] {
+		o.Token, o.TokenFile = p.Token, p.TokenFile
+	}
+	if !set["tls"] {
+		o.TLS = o.TLS || p.TLS
+	}
+	if !set["insecure-skip-verify"] {
+		o.InsecureSkipVerify = o.InsecureSkipVerify || p.InsecureSkipVerify
+	}
+	if !set["timeout"] && p.Timeout != "" {
+		if o.timeout, err = time.ParseDuration(p.Timeout); err != nil {
+			return fmt.Errorf("profile timeout %q is not a duration", p.Timeout)
+		}
+	}
+
+	if o.CAFile != "" || o.CertFile != "" || o.InsecureSkipVerify {
+		o.TLS = true
+	}
+	if (o.CertFile == "") != (o.KeyFile == "") {
+		return errors.New("-cert-file and -key-file must be set together")
+	}
+	if o.TokenFile != "" {
+		data, err := os.ReadFile(o.TokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read token: %w", err)
+		}
+		o.Token = strings.TrimSpace(string(data))
+	}
+	if o.Token != "" && !o.TLS {
+		return errors.New("refusing to send a token over a plaintext connection; enable -tls")
+	}
+	if _, err := newPrinter(os.Stdout, o.Output); err != nil {
+		return err
+	}
+	return nil
+}
+
+// dial connects to the server described by o.
+func (o *options) dial() (*grpc.ClientConn, error) {
+	var dialOpts []grpc.DialOption
+	if o.TLS {
+		tlsConfig, err := o.tlsConfig()
+		if err != nil {
+			return nil, err
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	}
+	if o.Token != "" {
+		dialOpts = append(dialOpts, grpc.WithPerRPCCredentials(bearerToken(o.Token)))
+	}
+	return grpc.NewClient(o.Server, dialOpts...)
+}
+
+func (o *options) tlsConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         o.ServerName,
+		InsecureSkipVerify: o.InsecureSkipVerify, //nolint:gosec // Explicitly requested by the user
+	}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", o.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if o.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// bearerToken sends a token in the authorization metadata of every call.
+type bearerToken string
+
+func (t bearerToken) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+func (bearerToken) RequireTransportSecurity() bool {
+	return true
+}
+
+// session is an open connection plus the output printer of one command.
+type session struct {
+	opts   *options
+	conn   *grpc.ClientConn
+	client userapi.UserServiceClient
+	out    *printer
+}
+
+// connect resolves the options and opens a session.
+func connect(fs *flag.FlagSet, o *options) (*session, error) {
+	if err := o.resolve(fs); err != nil {
+		return nil, err
+	}
+	conn, err := o.dial()
+	if err != nil {
+		return nil, err
+	}
+	out, _ := newPrinter(os.Stdout, o.Output)
+	return &session{opts: o, conn: conn, client: userapi.NewUserServiceClient(conn), out: out}, nil
+}
+
+// call returns a context bounded by the call timeout.
+func (s *session) call(ctx context.Context) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, s.opts.timeout)
+}
+
+func (s *session) Close() error {
+	return s.conn.Close()
+}
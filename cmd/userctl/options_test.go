@@ -0,0 +1,101 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeProfiles(t *testing.T, pf *ProfilesFile) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "userctl", "config.yaml")
+	require.NoError(t, pf.save(path))
+	return path
+}
+
+func resolveArgs(t *testing.T, args ...string) (*options, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	o := addGlobalFlags(fs)
+	require.NoError(t, fs.Parse(args))
+	return o, o.resolve(fs)
+}
+
+func TestResolve_Precedence(t *testing.T) {
+	path := writeProfiles(t, &ProfilesFile{
+		CurrentProfile: "dev",
+		Profiles: map[string]Profile{
+			"dev":  {Server: "dev:9090", Timeout: "3s", Output: "json"},
+			"prod": {Server: "prod:443", TLS: true, Token: "secret"},
+		},
+	})
+	t.Setenv(serverEnv, "")
+	t.Setenv(tokenEnv, "")
+
+	o, err := resolveArgs(t, "-config", path)
+	require.NoError(t, err)
+	assert.Equal(t, "dev:9090", o.Server)
+	assert.Equal(t, 3*time.Second, o.timeout)
+	assert.Equal(t, "json", o.Output)
+
+	o, err = resolveArgs(t, "-config", path, "-profile", "prod")
+	require.NoError(t, err)
+	assert.Equal(t, "prod:443", o.Server)
+	assert.True(t, o.TLS)
+	assert.Equal(t, "secret", o.Token)
+	assert.Equal(t, defaultTimeout, o.timeout)
+
+	t.Setenv(serverEnv, "env:9090")
+	o, err = resolveArgs(t, "-config", path)
+	require.NoError(t, err)
+	assert.Equal(t, "env:9090", o.Server)
+
+	o, err = resolveArgs(t, "-config", path, "-server", "flag:9090", "-o", "yaml", "-timeout", "1s")
+	require.NoError(t, err)
+	assert.Equal(t, "flag:9090", o.Server)
+	assert.Equal(t, "yaml", o.Output)
+	assert.Equal(t, time.Second, o.timeout)
+
+	_, err = resolveArgs(t, "-config", path, "-profile", "staging")
+	assert.ErrorContains(t, err, `unknown profile "staging"`)
+}
+
+func TestResolve_Errors(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "missing.yaml")
+	t.Setenv(serverEnv, "")
+	t.Setenv(tokenEnv, "")
+
+	_, err := resolveArgs(t, "-config", path, "-token", "secret")
+	assert.ErrorContains(t, err, "plaintext")
+
+	_, err = resolveArgs(t, "-config", path, "-cert-file", "client.pem")
+	assert.ErrorContains(t, err, "must be set together")
+
+	_, err = resolveArgs(t, "-config", path, "-o", "xml")
+	assert.ErrorContains(t, err, "unknown output format")
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("from-file\n"), 0o600))
+	o, err := resolveArgs(t, "-config", path, "-ca-file", "ca.pem", "-token-file", tokenFile)
+	require.NoError(t, err)
+	assert.True(t, o.TLS, "a CA file implies TLS")
+	assert.Equal(t, "from-file", o.Token)
+}
+
+func TestProfilesFile_Save(t *testing.T) {
+	path := writeProfiles(t, &ProfilesFile{Profiles: map[string]Profile{"b": {}, "a": {Token: "secret"}}})
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	pf, err := loadProfiles(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, pf.names())
+	assert.Equal(t, "secret", pf.Profiles["a"].Token)
+}
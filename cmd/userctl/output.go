@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+
+	userapi "github.com/interimme/userapi/proto"
+)
+
+// Output formats
+const (
+	outputTable = "table"
+	outputJSON  = "json"
+	outputYAML  = "yaml"
+)
+
+// printer writes responses in the selected output format.
+type printer struct {
+	w      io.Writer
+	format string
+	docs   int // YAML documents written so far
+}
+
+func newPrinter(w io.Writer, format string) (*printer, error) {
+	switch format {
+	case outputTable, outputJSON, outputYAML:
+		return &printer{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q, want table, json or yaml", format)
+	}
+}
+
+// users prints users; tables get one header for all of them.
+func (p *printer) users(users ...*userapi.User) error {
+	if p.format != outputTable {
+		for _, u := range users {
+			if err := p.message(u); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tFIRSTNAME\tLASTNAME\tEMAIL\tAGE\tDATE OF BIRTH\tCREATED")
+	for _, u := range users {
+		created := ""
+		if u.GetCreated() != nil {
+			created = u.GetCreated().AsTime().UTC().Format(time.RFC3339)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			u.GetId(), u.GetFirstname(), u.GetLastname(), u.GetEmail(), strconv.FormatUint(uint64(u.GetAge()), 10), u.GetDateOfBirth(), created)
+	}
+	return tw.Flush()
+}
+
+// deleted prints the response of DeleteUser.
+func (p *printer) deleted(resp *userapi.DeleteUserResponse) error {
+	if p.format == outputTable {
+		_, err := fmt.Fprintln(p.w, resp.GetMessage())
+		return err
+	}
+	return p.message(resp)
+}
+
+// message prints m as JSON, or as YAML converted from its JSON mapping so that
+// both formats use the same field names.
+func (p *printer) message(m proto.Message) error {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return err
+	}
+	if p.format == outputJSON {
+		// protojson output is deliberately unstable; indent it ourselves
+		var buf bytes.Buffer
+		if err := json.Indent(&buf, data, "", "  "); err != nil {
+			return err
+		}
+		buf.WriteByte('\n')
+		_, err = buf.WriteTo(p.w)
+		return err
+	}
+
+	// JSON is valid YAML; decoding into a node keeps the field order
+	var node yaml.Node
+	if err := yaml.Unmarshal(data, &node); err != nil {
+		return err
+	}
+	blockStyle(&node)
+	if p.docs > 0 {
+		fmt.Fprintln(p.w, "---")
+	}
+	p.docs++
+	enc := yaml.NewEncoder(p.w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&node); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle switches a node decoded from JSON to YAML's block style with
+// unquoted scalars where possible.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
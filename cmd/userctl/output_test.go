@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	userapi "github.com/interimme/userapi/proto"
+)
+
+var testUser = &userapi.User{
+	Id:        "7b4e1b5e-2f37-4a39-9f4e-6a3b7f1d2c11",
+	Firstname: "Ada",
+	Lastname:  "Lovelace",
+	Email:     "ada@example.com",
+	Age:       36,
+	Created:   timestamppb.New(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)),
+}
+
+func TestPrinter_Table(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, outputTable)
+	require.NoError(t, err)
+
+	require.NoError(t, p.users(testUser, testUser))
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 3)
+	assert.Contains(t, string(lines[0]), "FIRSTNAME")
+	assert.Contains(t, string(lines[1]), "ada@example.com")
+	assert.Contains(t, string(lines[1]), "2024-01-02T03:04:05Z")
+}
+
+func TestPrinter_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, outputJSON)
+	require.NoError(t, err)
+
+	require.NoError(t, p.users(testUser))
+	assert.Contains(t, buf.String(), `"email": "ada@example.com"`)
+	assert.Contains(t, buf.String(), `"created": "2024-01-02T03:04:05Z"`)
+}
+
+func TestPrinter_YAML(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, outputYAML)
+	require.NoError(t, err)
+
+	require.NoError(t, p.users(testUser, testUser))
+	out := buf.String()
+	assert.Contains(t, out, "email: ada@example.com\n")
+	assert.Contains(t, out, "firstname: Ada\n")
+	assert.Contains(t, out, "\n---\n", "documents are separated")
+}
+
+func TestNewPrinter_UnknownFormat(t *testing.T) {
+	_, err := newPrinter(&bytes.Buffer{}, "xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/interimme/userapi/internal/cli"
+)
+
+// configEnv overrides the location of the profiles file.
+const configEnv = "USERCTL_CONFIG"
+
+// Profile holds the connection settings for one environment.
+type Profile struct {
+	Server             string `yaml:"server,omitempty"`
+	TLS                bool   `yaml:"tls,omitempty"`
+	CAFile             string `yaml:"ca_file,omitempty"`
+	CertFile           string `yaml:"cert_file,omitempty"`
+	KeyFile            string `yaml:"key_file,omitempty"`
+	ServerName         string `yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+	Token              string `yaml:"token,omitempty"`
+	TokenFile          string `yaml:"token_file,omitempty"`
+	Timeout            string `yaml:"timeout,omitempty"`
+	Output             string `yaml:"output,omitempty"`
+}
+
+// ProfilesFile is the userctl configuration file: named profiles and the one
+// used when -profile is not given.
+type ProfilesFile struct {
+	CurrentProfile string             `yaml:"current_profile,omitempty"`
+	Profiles       map[string]Profile `yaml:"profiles,omitempty"`
+}
+
+// defaultConfigPath returns $USERCTL_CONFIG or the userctl file in the user's config directory.
+func defaultConfigPath() string {
+	if path := os.Getenv(configEnv); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "userctl", "config.yaml")
+}
+
+// loadProfiles reads the profiles file at path. A missing file is empty.
+func loadProfiles(path string) (*ProfilesFile, error) {
+	pf := &ProfilesFile{}
+	if path == "" {
+		return pf, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return pf, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(data, pf); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return pf, nil
+}
+
+// save writes the profiles file to path, creating its directory if needed.
+// The file may contain tokens, so it is only readable by the user.
+func (pf *ProfilesFile) save(path string) error {
+	if path == "" {
+		return errors.New("no config file location; set " + configEnv)
+	}
+	data, err := yaml.Marshal(pf)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// profile returns the named profile, or the current one if name is empty.
+func (pf *ProfilesFile) profile(name string) (Profile, error) {
+	if name == "" {
+		name = pf.CurrentProfile
+	}
+	if name == "" {
+		return Profile{}, nil
+	}
+	p, ok := pf.Profiles[name]
+	if !ok {
+		return Profile{}, fmt.Errorf("unknown profile %q", name)
+	}
+	return p, nil
+}
+
+// names returns the profile names in alphabetical order.
+func (pf *ProfilesFile) names() []string {
+	names := make([]string, 0, len(pf.Profiles))
+	for name := range pf.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// profileFlags registers the -config flag of the profile commands.
+func profileFlags(fs *flag.FlagSet) *string {
+	return fs.String("config", defaultConfigPath(), "Profiles file (env "+configEnv+")")
+}
+
+// profileArgs parses the flags of a profile command and checks that it got
+// between min and max arguments.
+func profileArgs(cmd *cli.Command, fs *flag.FlagSet, args []string, min, max int) ([]string, error) {
+	args = parseArgs(fs, args)
+	if len(args) < min || len(args) > max {
+		return nil, cli.Usagef(cmd, "unexpected arguments %q", args)
+	}
+	return args, nil
+}
+
+func profileListCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	path := profileFlags(fs)
+	if _, err := profileArgs(cmd, fs, args, 0, 0); err != nil {
+		return err
+	}
+	pf, err := loadProfiles(*path)
+	if err != nil {
+		return err
+	}
+	for _, name := range pf.names() {
+		mark := " "
+		if name == pf.CurrentProfile {
+			mark = "*"
+		}
+		fmt.Printf("%s %s\t%s\n", mark, name, pf.Profiles[name].Server)
+	}
+	return nil
+}
+
+// profileShowCommand prints a profile as YAML with its token redacted.
+func profileShowCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	path := profileFlags(fs)
+	args, err := profileArgs(cmd, fs, args, 0, 1)
+	if err != nil {
+		return err
+	}
+	pf, err := loadProfiles(*path)
+	if err != nil {
+		return err
+	}
+	name := pf.CurrentProfile
+	if len(args) == 1 {
+		name = args[0]
+	}
+	if name == "" {
+		return errors.New("no current profile; run 'userctl profile use <name>'")
+	}
+	p, err := pf.profile(name)
+	if err != nil {
+		return err
+	}
+	if p.Token != "" {
+		p.Token = redacted
+	}
+	enc := yaml.NewEncoder(os.Stdout)
+	enc.SetIndent(2)
+	if err := enc.Encode(map[string]Profile{name: p}); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// redacted replaces tokens in printed profiles.
+const redacted = "[REDACTED]"
+
+// profileSetCommand stores the connection flags given on the command line in
+// a profile, keeping its other settings. The first profile becomes current.
+func profileSetCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	o := addGlobalFlags(fs)
+	args, err := profileArgs(cmd, fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	pf, err := loadProfiles(o.configPath)
+	if err != nil {
+		return err
+	}
+
+	name := args[0]
+	p := pf.Profiles[name]
+	fs.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "server":
+			p.Server = o.Server
+		case "tls":
+			p.TLS = o.TLS
+		case "ca-file":
+			p.CAFile = o.CAFile
+		case "cert-file":
+			p.CertFile = o.CertFile
+		case "key-file":
+			p.KeyFile = o.KeyFile
+		case "server-name":
+			p.ServerName = o.ServerName
+		case "insecure-skip-verify":
+			p.InsecureSkipVerify = o.InsecureSkipVerify
+		case "token":
+			p.Token, p.TokenFile = o.Token, ""
+		case "token-file":
+			p.Token, p.TokenFile = "", o.TokenFile
+		case "timeout":
+			p.Timeout = o.timeout.String()
+		case "o":
+			p.Output = o.Output
+		}
+	})
+	if pf.Profiles == nil {
+		pf.Profiles = make(map[string]Profile)
+	}
+	pf.Profiles[name] = p
+	if pf.CurrentProfile == "" {
+		pf.CurrentProfile = name
+	}
+	if err := pf.save(o.configPath); err != nil {
+		return err
+	}
+	fmt.Printf("Profile %q saved to %s\n", name, o.configPath)
+	return nil
+}
+
+func profileUseCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	path := profileFlags(fs)
+	args, err := profileArgs(cmd, fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	pf, err := loadProfiles(*path)
+	if err != nil {
+		return err
+	}
+	if _, err := pf.profile(args[0]); err != nil {
+		return err
+	}
+	pf.CurrentProfile = args[0]
+	if err := pf.save(*path); err != nil {
+		return err
+	}
+	fmt.Printf("Switched to profile %q\n", args[0])
+	return nil
+}
+
+func profileDeleteCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	path := profileFlags(fs)
+	args, err := profileArgs(cmd, fs, args, 1, 1)
+	if err != nil {
+		return err
+	}
+	pf, err := loadProfiles(*path)
+	if err != nil {
+		return err
+	}
+	if _, err := pf.profile(args[0]); err != nil {
+		return err
+	}
+	delete(pf.Profiles, args[0])
+	if pf.CurrentProfile == args[0] {
+		pf.CurrentProfile = ""
+	}
+	if err := pf.save(*path); err != nil {
+		return err
+	}
+	fmt.Printf("Deleted profile %q\n", args[0])
+	return nil
+}
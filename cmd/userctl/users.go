@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+
+	"github.com/interimme/userapi/internal/cli"
+	userapi "github.com/interimme/userapi/proto"
+)
+
+// parseArgs parses flags that may appear before, between or after the
+// positional arguments and returns the positional arguments. The flag package
+// has already printed the usage on errors, so it exits like loadConfig in the
+// api binary: 0 on -h and 2 on bad flags.
+func parseArgs(fs *flag.FlagSet, args []string) []string {
+	var positional []string
+	for {
+		if err := fs.Parse(args); errors.Is(err, flag.ErrHelp) {
+			os.Exit(0)
+		} else if err != nil {
+			os.Exit(2)
+		}
+		if fs.NArg() == 0 {
+			return positional
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// withSession parses args, connects and runs fn with the positional arguments.
+// nargs is the exact number of positional arguments expected.
+func withSession(cmd *cli.Command, fs *flag.FlagSet, args []string, nargs int, fn func(ctx context.Context, s *session, args []string) error) error {
+	opts := addGlobalFlags(fs)
+	args = parseArgs(fs, args)
+	switch {
+	case len(args) == nargs:
+	case nargs == 0:
+		return cli.Usagef(cmd, "unexpected arguments %q", args)
+	default:
+		return cli.Usagef(cmd, "expected arguments %s", cmd.Args)
+	}
+
+	s, err := connect(fs, opts)
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	return fn(ctx, s, args)
+}
+
+// userFlags are the user fields that can be set with flags.
+type userFlags struct {
+	firstname, lastname, email, dateOfBirth string
+	age                                     uint
+}
+
+func (f *userFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.firstname, "firstname", "", "First name")
+	fs.StringVar(&f.lastname, "lastname", "", "Last name")
+	fs.StringVar(&f.email, "email", "", "Email address")
+	fs.StringVar(&f.dateOfBirth, "date-of-birth", "", "Date of birth, e.g. 1989-12-10")
+	fs.UintVar(&f.age, "age", 0, "Age, for users without a date of birth")
+}
+
+// apply copies the flags that were set on fs into u.
+func (f *userFlags) apply(fs *flag.FlagSet, u *userapi.User) {
+	fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "firstname":
+			u.Firstname = f.firstname
+		case "lastname":
+			u.Lastname = f.lastname
+		case "email":
+			u.Email = f.email
+		case "date-of-birth":
+			u.DateOfBirth = f.dateOfBirth
+		case "age":
+			u.Age = uint32(f.age)
+		}
+	})
+}
+
+func getCommand(cmd *cli.Command, args []string) error {
+	return withSession(cmd, cmd.FlagSet(), args, 1, func(ctx context.Context, s *session, args []string) error {
+		ctx, cancel := s.call(ctx)
+		defer cancel()
+		resp, err := s.client.GetUser(ctx, &userapi.GetUserRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		return s.out.users(resp.GetUser())
+	})
+}
+
+func createCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	var f userFlags
+	f.register(fs)
+	return withSession(cmd, fs, args, 0, func(ctx context.Context, s *session, _ []string) error {
+		user := &userapi.User{}
+		f.apply(fs, user)
+
+		ctx, cancel := s.call(ctx)
+		defer cancel()
+		resp, err := s.client.CreateUser(ctx, &userapi.CreateUserRequest{User: user})
+		if err != nil {
+			return err
+		}
+		return s.out.users(resp.GetUser())
+	})
+}
+
+// updateCommand changes only the fields given as flags: the current user is
+// fetched first because UpdateUser replaces all fields.
+func updateCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	var f userFlags
+	f.register(fs)
+	return withSession(cmd, fs, args, 1, func(ctx context.Context, s *session, args []string) error {
+		ctx, cancel := s.call(ctx)
+		defer cancel()
+		current, err := s.client.GetUser(ctx, &userapi.GetUserRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		user := current.GetUser()
+		f.apply(fs, user)
+
+		resp, err := s.client.UpdateUser(ctx, &userapi.UpdateUserRequest{Id: args[0], User: user})
+		if err != nil {
+			return err
+		}
+		return s.out.users(resp.GetUser())
+	})
+}
+
+func deleteCommand(cmd *cli.Command, args []string) error {
+	return withSession(cmd, cmd.FlagSet(), args, 1, func(ctx context.Context, s *session, args []string) error {
+		ctx, cancel := s.call(ctx)
+		defer cancel()
+		resp, err := s.client.DeleteUser(ctx, &userapi.DeleteUserRequest{Id: args[0]})
+		if err != nil {
+			return err
+		}
+		return s.out.deleted(resp)
+	})
+}
+
+// bulkUser is one record of a create-bulk file.
+type bulkUser struct {
+	Firstname   string `json:"firstname" yaml:"firstname"`
+	Lastname    string `json:"lastname" yaml:"lastname"`
+	Email       string `json:"email" yaml:"email"`
+	Age         uint32 `json:"age" yaml:"age"`
+	DateOfBirth string `json:"dateOfBirth" yaml:"dateOfBirth"`
+}
+
+// readBulkUsers reads a YAML list (.yaml, .yml), a JSON array or JSON lines.
+func readBulkUsers(name string, r io.Reader) ([]bulkUser, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// Unknown keys are rejected in every format, so misspelled fields are not silently dropped
+	var users []bulkUser
+	switch ext := strings.ToLower(filepath.Ext(name)); {
+	case ext == ".yaml" || ext == ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err = dec.Decode(&users); errors.Is(err, io.EOF) {
+			err = nil
+		}
+	case bytes.HasPrefix(bytes.TrimSpace(data), []byte("[")):
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&users)
+	default:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		for {
+			var u bulkUser
+			if err := dec.Decode(&u); errors.Is(err, io.EOF) {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("record %d: %w", len(users)+1, err)
+			}
+			users = append(users, u)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+func bulkFlags(fs *flag.FlagSet) *bool {
+	return fs.Bool("continue-on-error", false, "Create the remaining users after a failure")
+}
+
+// createBulkCommand creates every user in a file and prints the created users.
+func createBulkCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	keepGoing := bulkFlags(fs)
+	return withSession(cmd, fs, args, 1, func(ctx context.Context, s *session, args []string) error {
+		r := io.Reader(os.Stdin)
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			r = f
+		}
+		records, err := readBulkUsers(args[0], r)
+		if err != nil {
+			return err
+		}
+
+		var created []*userapi.User
+		failed := 0
+		for i, rec := range records {
+			callCtx, cancel := s.call(ctx)
+			resp, err := s.client.CreateUser(callCtx, &userapi.CreateUserRequest{User: &userapi.User{
+				Firstname:   rec.Firstname,
+				Lastname:    rec.Lastname,
+				Email:       rec.Email,
+				Age:         rec.Age,
+				DateOfBirth: rec.DateOfBirth,
+			}})
+			cancel()
+			if err != nil {
+				failed++
+				fmt.Fprintf(os.Stderr, "record %d (%s): %s\n", i+1, rec.Email, status.Convert(err).Message())
+				if !*keepGoing {
+					break
+				}
+				continue
+			}
+			created = append(created, resp.GetUser())
+		}
+
+		if len(created) > 0 {
+			if err := s.out.users(created...); err != nil {
+				return err
+			}
+		}
+		if failed > 0 {
+			return fmt.Errorf("%d of %d users could not be created", failed, len(records))
+		}
+		return nil
+	})
+}
+
+func watchFlags(fs *flag.FlagSet) *time.Duration {
+	return fs.Duration("interval", 2*time.Second, "Polling interval")
+}
+
+// watchCommand polls a user and prints it whenever it changes, until the user
+// is deleted or the command is interrupted.
+func watchCommand(cmd *cli.Command, args []string) error {
+	fs := cmd.FlagSet()
+	interval := watchFlags(fs)
+	return withSession(cmd, fs, args, 1, func(ctx context.Context, s *session, args []string) error {
+		if *interval <= 0 {
+			return cli.Usagef(cmd, "-interval must be positive")
+		}
+		ticker := time.NewTicker(*interval)
+		defer ticker.Stop()
+
+		var last *userapi.User
+		for {
+			callCtx, cancel := s.call(ctx)
+			resp, err := s.client.GetUser(callCtx, &userapi.GetUserRequest{Id: args[0]})
+			cancel()
+			switch {
+			case ctx.Err() != nil:
+				return nil
+			case status.Code(err) == codes.NotFound && last != nil:
+				fmt.Fprintf(os.Stderr, "user %s was deleted\n", args[0])
+				return nil
+			case err != nil:
+				return err
+			case !proto.Equal(resp.GetUser(), last):
+				last = resp.GetUser()
+				if err := s.out.users(last); err != nil {
+					return err
+				}
+			}
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-ticker.C:
+			}
+		}
+	})
+}
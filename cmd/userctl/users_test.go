@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	userapi "github.com/interimme/userapi/proto"
+)
+
+func TestParseArgs_InterleavedFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	var f userFlags
+	f.register(fs)
+
+	args := parseArgs(fs, []string{"-firstname", "Ada", "id-1", "-age", "36", "id-2"})
+	assert.Equal(t, []string{"id-1", "id-2"}, args)
+
+	user := &userapi.User{Firstname: "Old", Lastname: "Lovelace", Age: 30}
+	f.apply(fs, user)
+	assert.Equal(t, "Ada", user.Firstname)
+	assert.Equal(t, "Lovelace", user.Lastname, "fields without flags are kept")
+	assert.Equal(t, uint32(36), user.Age)
+}
+
+func TestReadBulkUsers(t *testing.T) {
+	want := []bulkUser{
+		{Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36},
+		{Firstname: "Alan", Lastname: "Turing", Email: "alan@example.com", Age: 41},
+	}
+
+	inputs := map[string]string{
+		"users.jsonl": `{"firstname":"Ada","lastname":"Lovelace","email":"ada@example.com","age":36}
+{"firstname":"Alan","lastname":"Turing","email":"alan@example.com","age":41}
+`,
+		"users.json": `[
+  {"firstname":"Ada","lastname":"Lovelace","email":"ada@example.com","age":36},
+  {"firstname":"Alan","lastname":"Turing","email":"alan@example.com","age":41}
+]`,
+		"users.yaml": `- firstname: Ada
+  lastname: Lovelace
+  email: ada@example.com
+  age: 36
+- firstname: Alan
+  lastname: Turing
+  email: alan@example.com
+  age: 41
+`,
+	}
+	for name, input := range inputs {
+		users, err := readBulkUsers(name, strings.NewReader(input))
+		require.NoError(t, err, name)
+		assert.Equal(t, want, users, name)
+	}
+
+	_, err := readBulkUsers("-", strings.NewReader(`{"firstname":"Ada"}
+{"name":"Alan"}`))
+	assert.ErrorContains(t, err, "record 2")
+
+	_, err = readBulkUsers("users.json", strings.NewReader(`[{"firstname":"Ada","date_of_birth":"1815-12-10"}]`))
+	assert.ErrorContains(t, err, `unknown field "date_of_birth"`)
+
+	_, err = readBulkUsers("users.yaml", strings.NewReader("- firstname: Ada\n  date_of_birth: 1815-12-10\n"))
+	assert.ErrorContains(t, err, "field date_of_birth not found")
+}
@@ -0,0 +1,82 @@
+// Package apidocs serves the OpenAPI description of the REST API and a
+// Swagger UI page for it. The spec is generated from proto/userapi.proto by
+// `make generate` and embedded into the binary.
+package apidocs
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+// Paths of the spec and the docs page. The page loads the spec relative to its
+// own location, so both work below a path prefix.
+const (
+	SpecPath = "/openapi.json"
+	DocsPath = "/docs/"
+)
+
+//go:embed userapi.swagger.json
+var spec []byte
+
+//go:embed index.html
+var index []byte
+
+// Spec returns the OpenAPI 2.0 document.
+func Spec() []byte {
+	return spec
+}
+
+// SpecHandler serves the OpenAPI document. When the request reached the
+// handler below a stripped path prefix, as in single-port mode, the prefix
+// becomes the spec's basePath so that "Try it out" calls the same surface.
+func SpecHandler(w http.ResponseWriter, r *http.Request) {
+	body := spec
+	if prefix := strippedPrefix(r); prefix != "" {
+		var doc map[string]any
+		if err := json.Unmarshal(spec, &doc); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		doc["basePath"] = prefix
+		body, _ = json.Marshal(doc)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// DocsHandler serves the Swagger UI page at DocsPath and its assets below it.
+// Requests for the path without the trailing slash are redirected, keeping
+// the redirect relative so that path prefixes survive.
+func DocsHandler() http.Handler {
+	assets := http.FileServer(http.FS(swaggerfiles.FS))
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		file := strings.TrimPrefix(r.URL.Path, DocsPath)
+		switch {
+		case r.URL.Path+"/" == DocsPath:
+			w.Header().Set("Location", strings.TrimPrefix(DocsPath, "/"))
+			w.WriteHeader(http.StatusMovedPermanently)
+		case file == "" || file == "index.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = w.Write(index)
+		default:
+			r = r.Clone(r.Context())
+			r.URL.Path = "/" + file
+			assets.ServeHTTP(w, r)
+		}
+	})
+}
+
+// strippedPrefix returns the part of the original request path that a
+// handler like http.StripPrefix removed before r reached us.
+func strippedPrefix(r *http.Request) string {
+	path, _, _ := strings.Cut(r.RequestURI, "?")
+	prefix, ok := strings.CutSuffix(path, r.URL.Path)
+	if !ok {
+		return ""
+	}
+	return prefix
+}
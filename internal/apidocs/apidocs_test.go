@@ -0,0 +1,80 @@
+package apidocs
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	userapi "github.com/interimme/userapi/proto"
+)
+
+type document struct {
+	Swagger  string                               `json:"swagger"`
+	BasePath string                               `json:"basePath"`
+	Paths    map[string]map[string]map[string]any `json:"paths"`
+}
+
+func decode(t *testing.T, body []byte) document {
+	t.Helper()
+	var doc document
+	require.NoError(t, json.Unmarshal(body, &doc))
+	return doc
+}
+
+// The embedded spec must be regenerated with `make generate` whenever an RPC is added.
+func TestSpec_CoversAllRPCs(t *testing.T) {
+	doc := decode(t, Spec())
+	assert.Equal(t, "2.0", doc.Swagger)
+
+	operations := make(map[string]bool)
+	for _, methods := range doc.Paths {
+		for _, op := range methods {
+			operations[op["operationId"].(string)] = true
+		}
+	}
+
+	methods := userapi.File_userapi_proto.Services().ByName("UserService").Methods()
+	for i := 0; i < methods.Len(); i++ {
+		name := "UserService_" + string(methods.Get(i).Name())
+		assert.True(t, operations[name], "%s is missing from the spec", name)
+	}
+}
+
+func TestSpecHandler(t *testing.T) {
+	rec := httptest.NewRecorder()
+	SpecHandler(rec, httptest.NewRequest(http.MethodGet, SpecPath, nil))
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Empty(t, decode(t, rec.Body.Bytes()).BasePath)
+
+	rec = httptest.NewRecorder()
+	http.StripPrefix("/gateway", http.HandlerFunc(SpecHandler)).
+		ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/gateway"+SpecPath+"?x=1", nil))
+	assert.Equal(t, "/gateway", decode(t, rec.Body.Bytes()).BasePath)
+}
+
+func TestDocsHandler(t *testing.T) {
+	h := DocsHandler()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs", nil))
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "docs/", rec.Header().Get("Location"), "relative, so that path prefixes are kept")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "../openapi.json")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/swagger-ui-bundle.js", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Header().Get("Content-Type"), "javascript")
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/docs/missing.js", nil))
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
@@ -1,9 +1,11 @@
 package apperrors
 
 import (
-	"fmt"
+	"errors"
+	"net/http"
 
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 // AppError represents a custom application error tailored for gRPC.
@@ -14,7 +16,18 @@ type AppError struct {
 
 // Error implements the error interface for AppError.
 func (e *AppError) Error() string {
-	return fmt.Sprintf("code: %v, message: %s", e.Code, e.Message)
+	return e.Message
+}
+
+// HTTPStatus returns the HTTP status code corresponding to the error's gRPC code.
+func (e *AppError) HTTPStatus() int {
+	return HTTPStatusFromCode(e.Code)
+}
+
+// GRPCStatus allows AppError to be converted by status.FromError and returned
+// directly from gRPC handlers.
+func (e *AppError) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
 }
 
 // NewAppError creates a new AppError with the specified gRPC code and message.
@@ -34,3 +47,82 @@ var (
 	ErrConflict            = NewAppError(codes.AlreadyExists, "email already exists")
 	ErrInternalServerError = NewAppError(codes.Internal, "internal server error")
 )
+
+// FromError converts any error into an AppError. AppErrors are returned as is,
+// gRPC status errors keep their code and message, and everything else is
+// reported as an internal server error so that no internal details leak.
+func FromError(err error) *AppError {
+	var appErr *AppError
+	if errors.As(err, &appErr) {
+		return appErr
+	}
+	if s, ok := status.FromError(err); ok && s.Code() != codes.Unknown {
+		if s.Code() == codes.Internal {
+			return ErrInternalServerError
+		}
+		return NewAppError(s.Code(), s.Message())
+	}
+	return ErrInternalServerError
+}
+
+// HTTPStatusFromCode maps a gRPC code to the canonical HTTP status code.
+// The mapping follows google.rpc.Code so that the Gin API and the gRPC-Gateway agree.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// CodeFromHTTPStatus maps an HTTP status code back to the closest gRPC code.
+// It is used for errors that originate from HTTP routing rather than from a handler.
+func CodeFromHTTPStatus(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestEntityTooLarge, http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusUnsupportedMediaType:
+		return codes.InvalidArgument
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,70 @@
+package apperrors
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"unicode"
+
+	"google.golang.org/grpc/codes"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details documents.
+const ProblemContentType = "application/problem+json"
+
+// problemTypePrefix prefixes the problem type URI; the suffix is derived from the gRPC code.
+const problemTypePrefix = "urn:userapi:problem:"
+
+// Problem is an RFC 7807 problem details document.
+// Both the Gin API and the gRPC-Gateway render errors in this shape.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+}
+
+// NewProblem builds a Problem for err. The instance is usually the request path.
+func NewProblem(err error, instance string) *Problem {
+	appErr := FromError(err)
+	return newProblem(appErr.Code, appErr.HTTPStatus(), appErr.Message, instance)
+}
+
+// NewProblemFromHTTPStatus builds a Problem for errors that only carry an HTTP
+// status, such as routing failures (unknown path, method not allowed).
+func NewProblemFromHTTPStatus(httpStatus int, detail, instance string) *Problem {
+	return newProblem(CodeFromHTTPStatus(httpStatus), httpStatus, detail, instance)
+}
+
+func newProblem(code codes.Code, httpStatus int, detail, instance string) *Problem {
+	return &Problem{
+		Type:     problemTypePrefix + kebabCase(code.String()),
+		Title:    http.StatusText(httpStatus),
+		Status:   httpStatus,
+		Detail:   detail,
+		Instance: instance,
+	}
+}
+
+// WriteProblem renders p as application/problem+json.
+func WriteProblem(w http.ResponseWriter, p *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(p.Status)
+	_ = json.NewEncoder(w).Encode(p)
+}
+
+// kebabCase turns a gRPC code name such as "InvalidArgument" into "invalid-argument".
+func kebabCase(s string) string {
+	var b strings.Builder
+	for i, r := range s {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('-')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
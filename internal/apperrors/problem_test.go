@@ -0,0 +1,59 @@
+package apperrors
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewProblem_AppError(t *testing.T) {
+	p := NewProblem(ErrNotFound, "/user/123")
+
+	assert.Equal(t, "urn:userapi:problem:not-found", p.Type)
+	assert.Equal(t, "Not Found", p.Title)
+	assert.Equal(t, http.StatusNotFound, p.Status)
+	assert.Equal(t, "user not found", p.Detail)
+	assert.Equal(t, "/user/123", p.Instance)
+}
+
+func TestNewProblem_StatusError(t *testing.T) {
+	p := NewProblem(status.Error(codes.InvalidArgument, "invalid user ID format"), "/user/abc")
+
+	assert.Equal(t, "urn:userapi:problem:invalid-argument", p.Type)
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "invalid user ID format", p.Detail)
+}
+
+func TestNewProblem_UnknownErrorIsHidden(t *testing.T) {
+	p := NewProblem(errors.New("pq: connection refused"), "/users")
+
+	assert.Equal(t, http.StatusInternalServerError, p.Status)
+	assert.Equal(t, "internal server error", p.Detail)
+}
+
+func TestNewProblemFromHTTPStatus_KeepsStatus(t *testing.T) {
+	p := NewProblemFromHTTPStatus(http.StatusMethodNotAllowed, "Method Not Allowed", "/users")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, p.Status)
+	assert.Equal(t, "urn:userapi:problem:unimplemented", p.Type)
+}
+
+func TestWriteProblem(t *testing.T) {
+	w := httptest.NewRecorder()
+
+	WriteProblem(w, NewProblem(ErrConflict, "/users"))
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, ProblemContentType, w.Header().Get("Content-Type"))
+	var p Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, "email already exists", p.Detail)
+	assert.Equal(t, http.StatusConflict, p.Status)
+}
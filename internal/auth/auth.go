@@ -0,0 +1,78 @@
+// Package auth authorizes privileged operations by API key. Transports store
+// the key presented by the client in the request context with WithAPIKey;
+// the use cases ask an Authorizer such as APIKeys whether it may proceed.
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"strings"
+
+	"github.com/interimme/userapi/internal/apperrors"
+)
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a context carrying the API key presented by the client.
+func WithAPIKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKey returns the key stored by WithAPIKey, or "" if there is none.
+func APIKey(ctx context.Context) string {
+	key, _ := ctx.Value(apiKeyContextKey{}).(string)
+	return key
+}
+
+// FromHeader extracts the API key from an Authorization header value of the
+// form "Bearer <key>". It returns "" for other schemes.
+func FromHeader(value string) string {
+	scheme, key, ok := strings.Cut(strings.TrimSpace(value), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(key)
+}
+
+// APIKeys grants every permission to the holders of a set of keys.
+type APIKeys struct {
+	hashes [][sha256.Size]byte
+}
+
+// NewAPIKeys creates an APIKeys accepting keys. Without keys it refuses everyone.
+func NewAPIKeys(keys []string) *APIKeys {
+	a := &APIKeys{}
+	for _, key := range keys {
+		a.hashes = append(a.hashes, sha256.Sum256([]byte(key)))
+	}
+	return a
+}
+
+// Authorize returns nil if the context carries one of the keys. A missing
+// key is ErrUnauthorized and a wrong one ErrForbidden. Keys are compared by
+// hash in constant time, so timing does not reveal how much of a key matched.
+func (a *APIKeys) Authorize(ctx context.Context, _ string) error {
+	key := APIKey(ctx)
+	if key == "" {
+		return apperrors.ErrUnauthorized
+	}
+	hash := sha256.Sum256([]byte(key))
+	match := 0
+	for _, h := range a.hashes {
+		match |= subtle.ConstantTimeCompare(hash[:], h[:])
+	}
+	if match == 0 {
+		return apperrors.ErrForbidden
+	}
+	return nil
+}
+
+// Operator grants every permission. It authorizes the administrative
+// commands, whose users have direct access to the database anyway.
+type Operator struct{}
+
+// Authorize always returns nil.
+func (Operator) Authorize(context.Context, string) error {
+	return nil
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/interimme/userapi/internal/apperrors"
+)
+
+func TestAPIKeys_Authorize(t *testing.T) {
+	keys := NewAPIKeys([]string{"first-key", "second-key"})
+
+	assert.NoError(t, keys.Authorize(WithAPIKey(context.Background(), "second-key"), "users.lookup"))
+	assert.Equal(t, apperrors.ErrForbidden, keys.Authorize(WithAPIKey(context.Background(), "second"), "users.lookup"))
+	assert.Equal(t, apperrors.ErrUnauthorized, keys.Authorize(context.Background(), "users.lookup"))
+	assert.Equal(t, apperrors.ErrForbidden, NewAPIKeys(nil).Authorize(WithAPIKey(context.Background(), "first-key"), "users.lookup"))
+}
+
+func TestFromHeader(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer abc":   "abc",
+		"bearer  abc ": "abc",
+		"Basic abc":    "",
+		"abc":          "",
+		"":             "",
+	} {
+		assert.Equal(t, want, FromHeader(header), header)
+	}
+}
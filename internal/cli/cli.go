@@ -0,0 +1,142 @@
+// Package cli implements the subcommand dispatch shared by the userapi and
+// userctl binaries. Commands parse their own flags with the standard flag
+// package; this package only resolves which command runs and prints usage.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Command is a subcommand of a binary. Commands either run something or
+// group further subcommands, like "user get".
+type Command struct {
+	Name    string
+	Args    string // Positional arguments shown in the usage, e.g. "<id>"
+	Summary string
+	Run     func(cmd *Command, args []string) error
+	Subs    []*Command
+
+	// Default names the subcommand of a group that runs when no command is
+	// given, e.g. serve for the api binary.
+	Default string
+
+	path string
+}
+
+// Path returns the full invocation of the command, e.g. "userapi user get".
+func (c *Command) Path() string {
+	if c.path == "" {
+		return c.Name
+	}
+	return c.path
+}
+
+// UsageError reports a command invoked with wrong arguments.
+type UsageError struct {
+	Cmd *Command
+	Msg string
+}
+
+func (e *UsageError) Error() string {
+	return e.Msg
+}
+
+// Usagef returns a UsageError for cmd.
+func Usagef(cmd *Command, format string, args ...any) error {
+	return &UsageError{Cmd: cmd, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Execute runs the command of root selected by args and returns the exit code:
+// 0 on success or -h, 2 on usage errors and 1 on other errors, which are
+// passed to report.
+func Execute(root *Command, args []string, report func(cmd *Command, err error)) int {
+	cmd, args, err := root.Find(args)
+	if err == nil {
+		err = cmd.Run(cmd, args)
+	}
+
+	var usageErr *UsageError
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, flag.ErrHelp):
+		cmd.PrintUsage(os.Stdout, nil)
+		return 0
+	case errors.As(err, &usageErr):
+		fmt.Fprintf(os.Stderr, "%s: %s\n\n", usageErr.Cmd.Path(), usageErr.Msg)
+		usageErr.Cmd.PrintUsage(os.Stderr, nil)
+		return 2
+	default:
+		report(cmd, err)
+		return 1
+	}
+}
+
+// Find resolves args to the command to run and its remaining arguments.
+// Flags without a command select the default command. On errors the returned
+// command is the one whose usage should be shown.
+func (c *Command) Find(args []string) (*Command, []string, error) {
+	if c.Run != nil {
+		return c, args, nil
+	}
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help") {
+			return c, nil, flag.ErrHelp
+		}
+		if def := c.Sub(c.Default); def != nil {
+			return def.Find(args)
+		}
+		return c, nil, Usagef(c, "missing command")
+	}
+	if args[0] == "help" {
+		return c, nil, flag.ErrHelp
+	}
+	sub := c.Sub(args[0])
+	if sub == nil {
+		return c, nil, Usagef(c, "unknown command %q", args[0])
+	}
+	return sub.Find(args[1:])
+}
+
+// Sub returns the direct subcommand called name, or nil.
+func (c *Command) Sub(name string) *Command {
+	for _, s := range c.Subs {
+		if s.Name == name {
+			s.path = c.Path() + " " + s.Name
+			return s
+		}
+	}
+	return nil
+}
+
+// PrintUsage describes c and its subcommands, or its flags if fs is set.
+func (c *Command) PrintUsage(w io.Writer, fs *flag.FlagSet) {
+	if c.Run == nil {
+		fmt.Fprintf(w, "Usage: %s <command> [flags] [arguments]\n\nCommands:\n", c.Path())
+		for _, s := range c.Subs {
+			fmt.Fprintf(w, "  %-12s %s\n", s.Name, s.Summary)
+		}
+		fmt.Fprintf(w, "\nRun '%s <command> -h' for the flags of a command.\n", c.Path())
+		return
+	}
+	fmt.Fprintf(w, "Usage: %s\n\n%s.\n", strings.TrimSpace(c.Path()+" [flags] "+c.Args), c.Summary)
+	if fs == nil {
+		fmt.Fprintf(w, "\nRun '%s -h' for its flags.\n", c.Path())
+		return
+	}
+	fmt.Fprintln(w, "\nFlags:")
+	fs.SetOutput(w)
+	fs.PrintDefaults()
+}
+
+// FlagSet returns a flag set for c whose -h output is the command's usage.
+func (c *Command) FlagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(c.Path(), flag.ContinueOnError)
+	fs.Usage = func() { c.PrintUsage(os.Stderr, fs) }
+	return fs
+}
@@ -0,0 +1,57 @@
+package cli
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func noop(*Command, []string) error { return nil }
+
+func newRoot() *Command {
+	return &Command{Name: "app", Default: "serve", Subs: []*Command{
+		{Name: "serve", Run: noop},
+		{Name: "migrate", Run: noop},
+		{Name: "user", Subs: []*Command{
+			{Name: "get", Run: noop},
+		}},
+	}}
+}
+
+func TestFind(t *testing.T) {
+	tests := []struct {
+		args     []string
+		wantPath string
+		wantArgs []string
+	}{
+		{args: nil, wantPath: "app serve"},
+		{args: []string{"-config", "c.yaml"}, wantPath: "app serve", wantArgs: []string{"-config", "c.yaml"}},
+		{args: []string{"serve", "-log-level", "debug"}, wantPath: "app serve", wantArgs: []string{"-log-level", "debug"}},
+		{args: []string{"migrate", "to", "3"}, wantPath: "app migrate", wantArgs: []string{"to", "3"}},
+		{args: []string{"user", "get", "id"}, wantPath: "app user get", wantArgs: []string{"id"}},
+	}
+	for _, tt := range tests {
+		cmd, args, err := newRoot().Find(tt.args)
+
+		require.NoError(t, err, tt.args)
+		assert.Equal(t, tt.wantPath, cmd.Path(), tt.args)
+		assert.Equal(t, tt.wantArgs, args, tt.args)
+	}
+}
+
+func TestFind_Errors(t *testing.T) {
+	root := newRoot()
+
+	cmd, _, err := root.Find([]string{"user"})
+	assert.EqualError(t, err, "missing command")
+	assert.Equal(t, "app user", cmd.Path())
+
+	_, _, err = root.Find([]string{"user", "rename"})
+	assert.EqualError(t, err, `unknown command "rename"`)
+
+	cmd, _, err = root.Find([]string{"-h"})
+	assert.ErrorIs(t, err, flag.ErrHelp)
+	assert.Equal(t, "app", cmd.Path())
+}
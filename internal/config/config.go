@@ -1,64 +1,243 @@
 package config
 
 import (
-	"log"
-	"os"
-	"strconv"
+	"strings"
+	"time"
 )
 
 // Config structure holds all configuration values for the application.
+//
+// Every setting can be given in a config file, as an environment variable and
+// as a command-line flag; see Load. The struct tags describe each setting:
+// key is its name in a config file, env its environment variable, usage its
+// documentation, and secret marks values that are redacted when printed.
 type Config struct {
-	Database DatabaseConfig
-	Server   ServerConfig
+	Database DatabaseConfig `key:"database"`
+	Server   ServerConfig   `key:"server"`
+	Cache    CacheConfig    `key:"cache"`
+	Batch    BatchConfig    `key:"batch"`
+	Auth     AuthConfig     `key:"auth"`
+	Email    EmailConfig    `key:"email"`
+	Users    UsersConfig    `key:"users"`
+	Tracing  TracingConfig  `key:"tracing"`
+	Log      LogConfig      `key:"log"`
 }
 
 // DatabaseConfig holds the database-related configuration.
 type DatabaseConfig struct {
-	Host     string
-	User     string
-	Password string
-	Name     string
-	Port     int
+	// URL is a complete connection string, either a postgres:// URL or a
+	// key=value DSN. When set it replaces the connection settings below up to
+	// and including the TLS files; application name and statement timeout are
+	// only applied if the URL does not set them itself.
+	URL string `key:"url" env:"DB_URL" secret:"true" usage:"Full connection URL or DSN; overrides host, port, user, password, name and TLS settings"`
+
+	Host     string `key:"host" env:"DB_HOST" usage:"Database host"`
+	User     string `key:"user" env:"DB_USER" usage:"Database user"`
+	Password string `key:"password" env:"DB_PASSWORD" secret:"true" usage:"Database password"`
+	Name     string `key:"name" env:"DB_NAME" usage:"Database name"`
+	Port     int    `key:"port" env:"DB_PORT" usage:"Database port"`
+
+	SSLMode     string `key:"sslmode" env:"DB_SSLMODE" usage:"TLS mode: disable, allow, prefer, require, verify-ca or verify-full"`
+	SSLRootCert string `key:"ssl_root_cert" env:"DB_SSL_ROOT_CERT" usage:"Path to the CA certificate used to verify the server"`
+	SSLCert     string `key:"ssl_cert" env:"DB_SSL_CERT" usage:"Path to the client certificate"`
+	SSLKey      string `key:"ssl_key" env:"DB_SSL_KEY" usage:"Path to the client certificate's private key"`
+
+	ApplicationName  string        `key:"application_name" env:"DB_APPLICATION_NAME" usage:"application_name reported to the server"`
+	StatementTimeout time.Duration `key:"statement_timeout" env:"DB_STATEMENT_TIMEOUT" usage:"Abort statements running longer than this (0 uses the server default)"`
+	ConnectTimeout   time.Duration `key:"connect_timeout" env:"DB_CONNECT_TIMEOUT" usage:"Timeout of a single connection attempt"`
+
+	MaxOpenConns    int           `key:"max_open_conns" env:"DB_MAX_OPEN_CONNS" usage:"Maximum number of open connections (0 is unlimited)"`
+	MaxIdleConns    int           `key:"max_idle_conns" env:"DB_MAX_IDLE_CONNS" usage:"Maximum number of idle connections"`
+	ConnMaxLifetime time.Duration `key:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME" usage:"Close connections after this long (0 is unlimited)"`
+	ConnMaxIdleTime time.Duration `key:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME" usage:"Close connections idle for this long (0 is unlimited)"`
+
+	// AutoMigrate applies pending schema migrations on startup. Disable it to
+	// run migrations separately with the migrate command.
+	AutoMigrate bool `key:"auto_migrate" env:"DB_AUTO_MIGRATE" usage:"Apply pending schema migrations on startup"`
+
+	// Startup retries back off exponentially from RetryBackoff up to
+	// RetryMaxBackoff, with jitter, until ConnectAttempts is exhausted.
+	ConnectAttempts int           `key:"connect_attempts" env:"DB_CONNECT_ATTEMPTS" usage:"Number of attempts to reach the database on startup"`
+	RetryBackoff    time.Duration `key:"retry_backoff" env:"DB_RETRY_BACKOFF" usage:"Delay before the first connection retry"`
+	RetryMaxBackoff time.Duration `key:"retry_max_backoff" env:"DB_RETRY_MAX_BACKOFF" usage:"Upper bound of the delay between connection retries"`
+
+	// TxAttempts bounds how often a transaction is run when it fails because
+	// of a serialization failure or deadlock with concurrent transactions.
+	TxAttempts int `key:"tx_attempts" env:"DB_TX_ATTEMPTS" usage:"Number of attempts of a transaction that conflicts with concurrent ones"`
+
+	// ReplicaURLs lists read replicas as comma-separated connection URLs,
+	// which replace the connection settings above like URL does. Lookups and
+	// listings go to a healthy replica, except for callers that wrote within
+	// ReplicaStickiness, so that they read their own writes.
+	ReplicaURLs           string        `key:"replica_urls" env:"DB_REPLICA_URLS" secret:"true" usage:"Comma-separated connection URLs of read replicas (empty sends all queries to the primary)"`
+	ReplicaStickiness     time.Duration `key:"replica_stickiness" env:"DB_REPLICA_STICKINESS" usage:"How long a caller's reads stay on the primary after it wrote"`
+	ReplicaHealthInterval time.Duration `key:"replica_health_interval" env:"DB_REPLICA_HEALTH_INTERVAL" usage:"Interval of replica health checks; failing replicas are skipped until they recover"`
+}
+
+// Replicas returns the connection URLs of the read replicas.
+func (c DatabaseConfig) Replicas() []string {
+	var urls []string
+	for _, u := range strings.Split(c.ReplicaURLs, ",") {
+		if u = strings.TrimSpace(u); u != "" {
+			urls = append(urls, u)
+		}
+	}
+	return urls
 }
 
 // ServerConfig holds the server-related configuration.
 type ServerConfig struct {
-	HttpPort int
-	GrpcPort int
-	GinPort  int
+	HttpPort int `key:"http_port" env:"HTTP_PORT" usage:"Port of the gRPC-Gateway HTTP server"`
+	GrpcPort int `key:"grpc_port" env:"GRPC_PORT" usage:"Port of the gRPC server"`
+	GinPort  int `key:"gin_port" env:"GIN_PORT" usage:"Port of the Gin HTTP server"`
+
+	// Port enables single-port mode when non-zero: gRPC, the gRPC-Gateway and
+	// the Gin API are served on one listener and the ports above are ignored.
+	Port          int    `key:"port" env:"PORT" usage:"Serve gRPC, the gateway and Gin on this single port (0 disables single-port mode)"`
+	GatewayPrefix string `key:"gateway_prefix" env:"GATEWAY_PREFIX" usage:"Path prefix of the gRPC-Gateway in single-port mode"`
+	GinPrefix     string `key:"gin_prefix" env:"GIN_PREFIX" usage:"Path prefix of the Gin API in single-port mode"`
+
+	// TrustedProxies lists the proxies whose X-Forwarded-For entries name the
+	// client; without any, clients are identified by their own address.
+	TrustedProxies string `key:"trusted_proxies" env:"TRUSTED_PROXIES" usage:"Comma-separated IP addresses and CIDR ranges of the proxies trusted to set X-Forwarded-For (empty trusts none)"`
+
+	// DrainDelay is how long the servers keep accepting requests on shutdown
+	// after reporting not ready, for load balancers to notice; it should
+	// cover the readiness probe interval.
+	DrainDelay time.Duration `key:"drain_delay" env:"DRAIN_DELAY" usage:"How long the servers keep accepting requests on shutdown after reporting not ready"`
+
+	// ShutdownTimeout bounds how long in-flight requests may drain on shutdown
+	ShutdownTimeout time.Duration `key:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT" usage:"How long in-flight requests may drain on shutdown"`
+	// HealthCheckTimeout bounds the dependency checks behind /readyz
+	HealthCheckTimeout time.Duration `key:"health_check_timeout" env:"HEALTH_CHECK_TIMEOUT" usage:"Timeout of the dependency checks behind /readyz"`
 }
 
-// Init initializes the configuration by reading from environment variables.
-func Init() *Config {
-	dbPort, err := strconv.Atoi(os.Getenv("DB_PORT"))
-	if err != nil {
-		log.Fatalf("DB_PORT doesn't look like an integer: %s", err)
-	}
-	httpPort, err := strconv.Atoi(os.Getenv("HTTP_PORT"))
-	if err != nil {
-		log.Fatalf("HTTP_PORT doesn't look like an integer: %s", err)
-	}
-	grpcPort, err := strconv.Atoi(os.Getenv("GRPC_PORT"))
-	if err != nil {
-		log.Fatalf("GRPC_PORT doesn't look like an integer: %s", err)
+// CacheConfig holds the configuration of the in-process user cache. Every
+// instance caches on its own, so after a write other instances may serve the
+// old user until the TTL passes.
+type CacheConfig struct {
+	Enabled     bool          `key:"enabled" env:"CACHE_ENABLED" usage:"Cache users looked up by ID in memory"`
+	Size        int           `key:"size" env:"CACHE_SIZE" usage:"Maximum number of cached lookups; the least recently used are evicted"`
+	TTL         time.Duration `key:"ttl" env:"CACHE_TTL" usage:"How long a cached user is served"`
+	NegativeTTL time.Duration `key:"negative_ttl" env:"CACHE_NEGATIVE_TTL" usage:"How long a lookup of a missing user is remembered (0 disables)"`
+}
+
+// BatchConfig limits the number of users in one request of the batch RPCs.
+type BatchConfig struct {
+	MaxGet   int `key:"max_get" env:"BATCH_MAX_GET" usage:"Maximum number of IDs in a BatchGetUsers request"`
+	MaxWrite int `key:"max_write" env:"BATCH_MAX_WRITE" usage:"Maximum number of users in a BatchCreateUsers or BatchDeleteUsers request"`
+}
+
+// AuthConfig holds the credentials of privileged API calls.
+type AuthConfig struct {
+	APIKeys string `key:"api_keys" env:"AUTH_API_KEYS" secret:"true" usage:"Comma-separated API keys allowed to look up users by email, check whether users exist, list users and search users (empty refuses these calls)"`
+}
+
+// Keys returns the API keys of APIKeys.
+func (c AuthConfig) Keys() []string {
+	var keys []string
+	for _, k := range strings.Split(c.APIKeys, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keys = append(keys, k)
+		}
 	}
-	ginPort, err := strconv.Atoi(os.Getenv("GIN_PORT"))
-	if err != nil {
-		log.Fatalf("GIN_PORT doesn't look like an integer: %s", err)
+	return keys
+}
+
+// EmailConfig holds the rules for comparing email addresses.
+type EmailConfig struct {
+	ProviderRules bool `key:"provider_rules" env:"EMAIL_PROVIDER_RULES" usage:"Treat the address variants that well-known providers such as Gmail deliver to the same mailbox as one address"`
+}
+
+// UsersConfig holds the policies users must satisfy.
+type UsersConfig struct {
+	MinAge int `key:"min_age" env:"USERS_MIN_AGE" usage:"Minimum age in years of new users (0 allows all ages)"`
+}
+
+// TracingConfig holds the OpenTelemetry tracing configuration.
+type TracingConfig struct {
+	Exporter     string  `key:"exporter" env:"TRACING_EXPORTER" usage:"Trace exporter: none, stdout or otlp"`
+	OTLPEndpoint string  `key:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT" usage:"host:port of the OTLP gRPC collector"`
+	OTLPInsecure bool    `key:"otlp_insecure" env:"TRACING_OTLP_INSECURE" usage:"Disable TLS towards the collector"`
+	SampleRatio  float64 `key:"sample_ratio" env:"TRACING_SAMPLE_RATIO" usage:"Fraction of new traces to sample"`
+	ServiceName  string  `key:"service_name" env:"TRACING_SERVICE_NAME" usage:"service.name resource attribute"`
+}
+
+// LogConfig holds the logging configuration.
+type LogConfig struct {
+	Level  string `key:"level" env:"LOG_LEVEL" usage:"Log level: debug, info, warn or error"`
+	Format string `key:"format" env:"LOG_FORMAT" usage:"Log format: json or text"`
+}
+
+// SinglePort reports whether all servers share one listener.
+func (c ServerConfig) SinglePort() bool {
+	return c.Port != 0
+}
+
+// Proxies returns the addresses and ranges of TrustedProxies.
+func (c ServerConfig) Proxies() []string {
+	var proxies []string
+	for _, p := range strings.Split(c.TrustedProxies, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			proxies = append(proxies, p)
+		}
 	}
+	return proxies
+}
 
+// Default returns the configuration used for every setting that is not set
+// explicitly.
+func Default() *Config {
 	return &Config{
 		Database: DatabaseConfig{
-			Host:     os.Getenv("DB_HOST"),
-			User:     os.Getenv("DB_USER"),
-			Password: os.Getenv("DB_PASSWORD"),
-			Name:     os.Getenv("DB_NAME"),
-			Port:     dbPort,
+			Host:            "localhost",
+			User:            "postgres",
+			Name:            "usersdb",
+			Port:            5432,
+			SSLMode:         "disable",
+			ApplicationName: "userapi",
+			ConnectTimeout:  5 * time.Second,
+			MaxOpenConns:    10,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: time.Minute,
+			ConnMaxIdleTime: 5 * time.Minute,
+			ConnectAttempts: 10,
+			RetryBackoff:    500 * time.Millisecond,
+			RetryMaxBackoff: 10 * time.Second,
+			AutoMigrate:     true,
+			TxAttempts:      3,
+
+			ReplicaStickiness:     5 * time.Second,
+			ReplicaHealthInterval: 5 * time.Second,
 		},
 		Server: ServerConfig{
-			HttpPort: httpPort,
-			GrpcPort: grpcPort,
-			GinPort:  ginPort,
+			HttpPort:           8080,
+			GrpcPort:           9090,
+			GinPort:            8000,
+			GatewayPrefix:      "/gateway",
+			DrainDelay:         5 * time.Second,
+			ShutdownTimeout:    15 * time.Second,
+			HealthCheckTimeout: 2 * time.Second,
+		},
+		Cache: CacheConfig{
+			Enabled:     true,
+			Size:        10000,
+			TTL:         30 * time.Second,
+			NegativeTTL: 5 * time.Second,
+		},
+		Batch: BatchConfig{
+			MaxGet:   1000,
+			MaxWrite: 100,
+		},
+		Tracing: TracingConfig{
+			Exporter:     "none",
+			OTLPEndpoint: "localhost:4317",
+			SampleRatio:  1,
+			ServiceName:  "userapi",
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "json",
 		},
 	}
 }
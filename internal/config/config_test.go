@@ -0,0 +1,188 @@
+package config
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func load(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	return Load(fs, args)
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoad_Defaults(t *testing.T) {
+	cfg, err := load(t)
+
+	require.NoError(t, err)
+	assert.Equal(t, Default(), cfg)
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  host: file-host
+  name: file-db
+server:
+  http_port: 8081
+  shutdown_timeout: 30s
+`)
+	t.Setenv("DB_NAME", "env-db")
+	t.Setenv("HTTP_PORT", "8082")
+
+	cfg, err := load(t, "-config", path, "-server-http-port", "8083")
+
+	require.NoError(t, err)
+	assert.Equal(t, "file-host", cfg.Database.Host)
+	assert.Equal(t, "env-db", cfg.Database.Name)
+	assert.Equal(t, 8083, cfg.Server.HttpPort)
+	assert.Equal(t, 30*time.Second, cfg.Server.ShutdownTimeout)
+	assert.Equal(t, 9090, cfg.Server.GrpcPort)
+}
+
+func TestLoad_TOMLFromEnv(t *testing.T) {
+	path := writeFile(t, "config.toml", `
+[tracing]
+exporter = "otlp"
+otlp_insecure = true
+sample_ratio = 0.25
+`)
+	t.Setenv(FileEnv, path)
+
+	cfg, err := load(t)
+
+	require.NoError(t, err)
+	assert.Equal(t, "otlp", cfg.Tracing.Exporter)
+	assert.True(t, cfg.Tracing.OTLPInsecure)
+	assert.Equal(t, 0.25, cfg.Tracing.SampleRatio)
+}
+
+func TestLoad_FileSuffix(t *testing.T) {
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db_password", "s3cret\n"))
+
+	cfg, err := load(t)
+
+	require.NoError(t, err)
+	assert.Equal(t, "s3cret", cfg.Database.Password)
+}
+
+func TestLoad_FileSuffixConflict(t *testing.T) {
+	t.Setenv("DB_PASSWORD", "inline")
+	t.Setenv("DB_PASSWORD_FILE", writeFile(t, "db_password", "s3cret"))
+
+	_, err := load(t)
+
+	assert.EqualError(t, err, "DB_PASSWORD and DB_PASSWORD_FILE are both set")
+}
+
+func TestLoad_AggregatesErrors(t *testing.T) {
+	path := writeFile(t, "config.yaml", `
+database:
+  hostname: db
+`)
+	t.Setenv("DB_PORT", "postgres")
+	t.Setenv("LOG_FORMAT", "xml")
+
+	_, err := load(t, "-config", path, "-server-gin-port", "9090")
+
+	require.Error(t, err)
+	assert.Equal(t, "config file "+path+": unknown setting database.hostname\n"+
+		`DB_PORT: "postgres" is not an integer`+"\n"+
+		"server.gin_port: must differ from server.http_port and server.grpc_port\n"+
+		`log.format: must be json or text, got "xml"`, err.Error())
+}
+
+func TestLoad_Help(t *testing.T) {
+	_, err := load(t, "-h")
+
+	assert.ErrorIs(t, err, flag.ErrHelp)
+}
+
+func TestValidate_SinglePort(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Port = 8080
+	cfg.Server.HttpPort = 0
+	cfg.Server.GatewayPrefix = "/api/"
+	cfg.Server.GinPrefix = "/api/"
+
+	err := cfg.Validate()
+
+	assert.EqualError(t, err, `server.gateway_prefix: must be empty or start with / and not end with /, got "/api/"`+"\n"+
+		`server.gin_prefix: must be empty or start with / and not end with /, got "/api/"`+"\n"+
+		"server.gin_prefix: must differ from server.gateway_prefix in single-port mode")
+}
+
+func TestValidate_TrustedProxies(t *testing.T) {
+	cfg := Default()
+	cfg.Server.TrustedProxies = "10.0.0.0/8, 192.168.1.1,proxy.internal"
+
+	err := cfg.Validate()
+
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.1", "proxy.internal"}, cfg.Server.Proxies())
+	assert.EqualError(t, err, `server.trusted_proxies: must list IP addresses and CIDR ranges, got "proxy.internal"`)
+}
+
+func TestWrite_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Password = "s3cret"
+
+	for _, format := range []string{"yaml", "toml"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			require.NoError(t, cfg.Write(&buf, format))
+			assert.NotContains(t, buf.String(), "s3cret")
+			assert.Contains(t, buf.String(), Redacted)
+
+			// The output is a valid config file
+			path := writeFile(t, "config."+format, buf.String())
+			loaded, err := load(t, "-config", path)
+			require.NoError(t, err)
+			assert.Equal(t, Redacted, loaded.Database.Password)
+			assert.Equal(t, cfg.Server, loaded.Server)
+		})
+	}
+}
+
+func TestValidate_Database(t *testing.T) {
+	cfg := Default()
+	cfg.Database.Host = ""
+	cfg.Database.SSLMode = "on"
+	cfg.Database.SSLCert = "/certs/client.crt"
+	cfg.Database.RetryMaxBackoff = time.Millisecond
+
+	err := cfg.Validate()
+
+	assert.EqualError(t, err, "database.host: must not be empty\n"+
+		`database.sslmode: must be disable, allow, prefer, require, verify-ca or verify-full, got "on"`+"\n"+
+		"database.ssl_key: must be set together with database.ssl_cert\n"+
+		"database.retry_max_backoff: must not be less than database.retry_backoff")
+
+	// A URL replaces the individual connection settings
+	cfg.Database.URL = "postgres://db/usersdb"
+	err = cfg.Validate()
+
+	assert.EqualError(t, err, "database.retry_max_backoff: must not be less than database.retry_backoff")
+}
+
+func TestDatabaseConfig_Replicas(t *testing.T) {
+	cfg := DatabaseConfig{ReplicaURLs: " postgres://r1/db, ,postgres://r2/db "}
+
+	assert.Equal(t, []string{"postgres://r1/db", "postgres://r2/db"}, cfg.Replicas())
+	assert.Empty(t, DatabaseConfig{}.Replicas())
+}
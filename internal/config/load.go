@@ -0,0 +1,290 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// FileEnv names the environment variable that points at a config file.
+// The -config flag takes precedence over it.
+const FileEnv = "CONFIG_FILE"
+
+// fileSuffix marks an environment variable whose value is read from a file,
+// e.g. DB_PASSWORD_FILE=/run/secrets/db_password.
+const fileSuffix = "_FILE"
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setting describes one configuration value and where it can be set.
+type setting struct {
+	key    string // section.key as used in config files
+	env    string
+	flag   string
+	usage  string
+	secret bool
+	value  reflect.Value
+}
+
+// settings lists every configuration value of c in declaration order.
+func settings(c *Config) []setting {
+	var out []setting
+	root := reflect.ValueOf(c).Elem()
+	for i := 0; i < root.NumField(); i++ {
+		section := root.Type().Field(i).Tag.Get("key")
+		sv := root.Field(i)
+		for j := 0; j < sv.NumField(); j++ {
+			f := sv.Type().Field(j)
+			key := f.Tag.Get("key")
+			out = append(out, setting{
+				key:    section + "." + key,
+				env:    f.Tag.Get("env"),
+				flag:   section + "-" + strings.ReplaceAll(key, "_", "-"),
+				usage:  f.Tag.Get("usage"),
+				secret: f.Tag.Get("secret") == "true",
+				value:  sv.Field(j),
+			})
+		}
+	}
+	return out
+}
+
+// set parses raw into the setting's value.
+func (s setting) set(raw string) error {
+	v := s.value
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a duration", raw)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(raw)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", raw)
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return fmt.Errorf("%q is not a boolean", raw)
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not a number", raw)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// String formats the setting's value the way it is accepted by set.
+func (s setting) String() string {
+	if s.value.Type() == durationType {
+		return time.Duration(s.value.Int()).String()
+	}
+	return fmt.Sprint(s.value.Interface())
+}
+
+// rawFlag records a flag's value so that flags can be applied after the config
+// file and the environment, whatever order they are parsed in.
+type rawFlag struct {
+	values map[string]string
+	name   string
+	def    string
+	isBool bool
+}
+
+func (f *rawFlag) String() string {
+	if f == nil {
+		return ""
+	}
+	return f.def
+}
+
+func (f *rawFlag) Set(value string) error {
+	f.values[f.name] = value
+	return nil
+}
+
+func (f *rawFlag) IsBoolFlag() bool {
+	return f.isBool
+}
+
+// Load builds the configuration from, in increasing order of precedence,
+// the defaults, an optional YAML or TOML config file, environment variables
+// and command-line flags. It registers its flags on fs and parses args with it,
+// so callers may add flags of their own to fs beforehand.
+//
+// All problems are reported together: the returned error joins every invalid
+// value and every failed validation. flag.ErrHelp is returned as is.
+func Load(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	all := settings(cfg)
+
+	flagValues := make(map[string]string)
+	configFile := fs.String("config", "", "Path to a YAML or TOML config file (env "+FileEnv+")")
+	for _, s := range all {
+		usage := s.usage
+		if s.env != "" {
+			usage += " (env " + s.env + ")"
+		}
+		fs.Var(&rawFlag{
+			values: flagValues,
+			name:   s.flag,
+			def:    s.String(),
+			isBool: s.value.Kind() == reflect.Bool,
+		}, s.flag, usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	var errs []error
+
+	path := *configFile
+	if path == "" {
+		path = os.Getenv(FileEnv)
+	}
+	if path != "" {
+		if err := applyFile(all, path); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, s := range all {
+		if s.env == "" {
+			continue
+		}
+		raw, ok, err := lookupEnv(s.env)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		if err := s.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", s.env, err))
+		}
+	}
+
+	for _, s := range all {
+		raw, ok := flagValues[s.flag]
+		if !ok {
+			continue
+		}
+		if err := s.set(raw); err != nil {
+			errs = append(errs, fmt.Errorf("-%s: %w", s.flag, err))
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// lookupEnv returns the value of the environment variable key. If key_FILE is
+// set instead, the value is read from the file it names, without trailing newlines.
+func lookupEnv(key string) (string, bool, error) {
+	value, ok := os.LookupEnv(key)
+	path, fileOK := os.LookupEnv(key + fileSuffix)
+	if !fileOK {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("%s and %s%s are both set", key, key, fileSuffix)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s%s: %w", key, fileSuffix, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), true, nil
+}
+
+// applyFile sets every value found in the config file at path. The format is
+// chosen by the file extension.
+func applyFile(all []setting, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("config file %s: unsupported extension %q (want .yaml, .yml or .toml)", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	byKey := make(map[string]setting, len(all))
+	for _, s := range all {
+		byKey[s.key] = s
+	}
+
+	var errs []error
+	for _, section := range sortedKeys(doc) {
+		values, ok := doc[section].(map[string]any)
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: %s: expected a section", path, section))
+			continue
+		}
+		for _, key := range sortedKeys(values) {
+			name := section + "." + key
+			s, ok := byKey[name]
+			if !ok {
+				errs = append(errs, fmt.Errorf("config file %s: unknown setting %s", path, name))
+				continue
+			}
+			value := values[key]
+			if value == nil {
+				continue
+			}
+			switch value.(type) {
+			case map[string]any, []any:
+				errs = append(errs, fmt.Errorf("config file %s: %s: expected a single value", path, name))
+				continue
+			}
+			if err := s.set(fmt.Sprint(value)); err != nil {
+				errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func sortedKeys(m map[string]any) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/pelletier/go-toml/v2"
+	"gopkg.in/yaml.v3"
+)
+
+// Redacted replaces the value of secret settings when the configuration is printed.
+const Redacted = "[REDACTED]"
+
+// Write prints the effective configuration to w in the given format, yaml or
+// toml, with secrets redacted. The output is a valid config file.
+func (c *Config) Write(w io.Writer, format string) error {
+	doc := make(map[string]map[string]any)
+	for _, s := range settings(c) {
+		section, key, _ := strings.Cut(s.key, ".")
+		if doc[section] == nil {
+			doc[section] = make(map[string]any)
+		}
+		doc[section][key] = printable(s)
+	}
+
+	switch format {
+	case "yaml":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(doc)
+	default:
+		return fmt.Errorf("unsupported config format %q (want yaml or toml)", format)
+	}
+}
+
+// printable returns the value of s as it should appear in a config file.
+func printable(s setting) any {
+	switch {
+	case s.secret:
+		if s.value.IsZero() {
+			return ""
+		}
+		return Redacted
+	case s.value.Type() == durationType:
+		return s.String()
+	case s.value.Kind() == reflect.Int:
+		return s.value.Int()
+	default:
+		return s.value.Interface()
+	}
+}
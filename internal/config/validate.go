@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// Validate checks the whole configuration and returns every problem it finds
+// joined into one error, or nil if the configuration is valid.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, key, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf("%s: %s", key, fmt.Sprintf(format, args...)))
+		}
+	}
+
+	// Database; an explicit URL replaces the individual connection settings
+	d := c.Database
+	if d.URL == "" {
+		check(d.Host != "", "database.host", "must not be empty")
+		check(d.User != "", "database.user", "must not be empty")
+		check(d.Name != "", "database.name", "must not be empty")
+		check(validPort(d.Port), "database.port", "must be between 1 and 65535, got %d", d.Port)
+		check(oneOf(d.SSLMode, "disable", "allow", "prefer", "require", "verify-ca", "verify-full"), "database.sslmode",
+			"must be disable, allow, prefer, require, verify-ca or verify-full, got %q", d.SSLMode)
+		check((d.SSLCert == "") == (d.SSLKey == ""), "database.ssl_key", "must be set together with database.ssl_cert")
+	}
+	check(d.StatementTimeout >= 0, "database.statement_timeout", "must not be negative")
+	check(d.ConnectTimeout >= 0, "database.connect_timeout", "must not be negative")
+	check(d.MaxOpenConns >= 0, "database.max_open_conns", "must not be negative")
+	check(d.MaxIdleConns >= 0, "database.max_idle_conns", "must not be negative")
+	check(d.ConnMaxLifetime >= 0, "database.conn_max_lifetime", "must not be negative")
+	check(d.ConnMaxIdleTime >= 0, "database.conn_max_idle_time", "must not be negative")
+	check(d.ConnectAttempts > 0, "database.connect_attempts", "must be positive")
+	check(d.RetryBackoff > 0, "database.retry_backoff", "must be positive")
+	check(d.RetryMaxBackoff >= d.RetryBackoff, "database.retry_max_backoff", "must not be less than database.retry_backoff")
+	check(d.TxAttempts > 0, "database.tx_attempts", "must be positive")
+	if len(d.Replicas()) > 0 {
+		check(d.ReplicaStickiness >= 0, "database.replica_stickiness", "must not be negative")
+		check(d.ReplicaHealthInterval > 0, "database.replica_health_interval", "must be positive")
+	}
+
+	// Server
+	s := c.Server
+	if s.SinglePort() {
+		check(validPort(s.Port), "server.port", "must be between 1 and 65535 (0 disables single-port mode), got %d", s.Port)
+		check(validPrefix(s.GatewayPrefix), "server.gateway_prefix", "must be empty or start with / and not end with /, got %q", s.GatewayPrefix)
+		check(validPrefix(s.GinPrefix), "server.gin_prefix", "must be empty or start with / and not end with /, got %q", s.GinPrefix)
+		check(s.GatewayPrefix != s.GinPrefix, "server.gin_prefix", "must differ from server.gateway_prefix in single-port mode")
+	} else {
+		check(validPort(s.HttpPort), "server.http_port", "must be between 1 and 65535, got %d", s.HttpPort)
+		check(validPort(s.GrpcPort), "server.grpc_port", "must be between 1 and 65535, got %d", s.GrpcPort)
+		check(validPort(s.GinPort), "server.gin_port", "must be between 1 and 65535, got %d", s.GinPort)
+		check(s.GrpcPort != s.HttpPort, "server.grpc_port", "must differ from server.http_port")
+		check(s.GinPort != s.HttpPort && s.GinPort != s.GrpcPort, "server.gin_port", "must differ from server.http_port and server.grpc_port")
+	}
+	check(s.DrainDelay >= 0, "server.drain_delay", "must not be negative")
+	check(s.ShutdownTimeout > 0, "server.shutdown_timeout", "must be positive")
+	check(s.HealthCheckTimeout > 0, "server.health_check_timeout", "must be positive")
+	for _, p := range s.Proxies() {
+		check(validProxy(p), "server.trusted_proxies", "must list IP addresses and CIDR ranges, got %q", p)
+	}
+
+	// Cache
+	if c.Cache.Enabled {
+		check(c.Cache.Size > 0, "cache.size", "must be positive")
+		check(c.Cache.TTL > 0, "cache.ttl", "must be positive")
+		check(c.Cache.NegativeTTL >= 0, "cache.negative_ttl", "must not be negative")
+	}
+
+	// Batches
+	check(c.Batch.MaxGet > 0, "batch.max_get", "must be positive")
+	check(c.Batch.MaxWrite > 0, "batch.max_write", "must be positive")
+
+	// Users
+	check(c.Users.MinAge >= 0 && c.Users.MinAge <= 150, "users.min_age", "must be between 0 and 150, got %d", c.Users.MinAge)
+
+	// Tracing
+	t := c.Tracing
+	check(oneOf(t.Exporter, "none", "stdout", "otlp"), "tracing.exporter", "must be none, stdout or otlp, got %q", t.Exporter)
+	check(t.Exporter != "otlp" || t.OTLPEndpoint != "", "tracing.otlp_endpoint", "must not be empty with the otlp exporter")
+	check(t.SampleRatio >= 0 && t.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1, got %g", t.SampleRatio)
+	check(t.ServiceName != "", "tracing.service_name", "must not be empty")
+
+	// Logging
+	check(oneOf(strings.ToLower(c.Log.Level), "debug", "info", "warn", "error"), "log.level", "must be debug, info, warn or error, got %q", c.Log.Level)
+	check(oneOf(c.Log.Format, "json", "text"), "log.format", "must be json or text, got %q", c.Log.Format)
+
+	return errors.Join(errs...)
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+func validPrefix(prefix string) bool {
+	return prefix == "" || (strings.HasPrefix(prefix, "/") && !strings.HasSuffix(prefix, "/"))
+}
+
+func validProxy(proxy string) bool {
+	if strings.Contains(proxy, "/") {
+		_, err := netip.ParsePrefix(proxy)
+		return err == nil
+	}
+	_, err := netip.ParseAddr(proxy)
+	return err == nil
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,40 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	appErrors "github.com/interimme/userapi/internal/apperrors"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc/codes"
+)
+
+// MaxRequestBodyBytes limits the size of JSON request bodies.
+const MaxRequestBodyBytes = 1 << 20 // 1 MiB
+
+// bindJSON strictly decodes the request body into dst. Unknown fields, trailing
+// data and bodies larger than MaxRequestBodyBytes are rejected.
+func bindJSON(c *gin.Context, dst any) error {
+	body := http.MaxBytesReader(c.Writer, c.Request.Body, MaxRequestBodyBytes)
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return maxBytesErr
+		}
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return appErrors.NewAppError(codes.InvalidArgument, "invalid request: unknown field "+field)
+		}
+		return errInvalidRequest
+	}
+	if _, err := dec.Token(); !errors.Is(err, io.EOF) {
+		return errInvalidRequest
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"time"
+
+	"google.golang.org/grpc/codes"
+
+	appErrors "github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/usecase"
+
+	"github.com/google/uuid"
+)
+
+// The DTOs below define the REST contract of the Gin API. JSON names follow the
+// proto JSON mapping used by the gRPC-Gateway so both REST surfaces share one schema.
+
+// CreateUserRequest is the body of POST /users.
+// ID and created are assigned by the server and cannot be set by clients.
+type CreateUserRequest struct {
+	Firstname   string `json:"firstname"`
+	Lastname    string `json:"lastname"`
+	Email       string `json:"email"`
+	Age         uint32 `json:"age"`
+	DateOfBirth string `json:"dateOfBirth"`
+}
+
+// ToEntity converts the request into a user entity
+func (r *CreateUserRequest) ToEntity() (*entity.User, error) {
+	dateOfBirth, err := parseDate(r.DateOfBirth)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.User{
+		Firstname:   r.Firstname,
+		Lastname:    r.Lastname,
+		Email:       r.Email,
+		Age:         uint(r.Age),
+		DateOfBirth: dateOfBirth,
+	}, nil
+}
+
+// UpdateUserRequest is the body of PATCH /user/:id.
+type UpdateUserRequest struct {
+	Firstname   string `json:"firstname"`
+	Lastname    string `json:"lastname"`
+	Email       string `json:"email"`
+	Age         uint32 `json:"age"`
+	DateOfBirth string `json:"dateOfBirth"`
+}
+
+// ToEntity converts the request into a user entity with the given ID
+func (r *UpdateUserRequest) ToEntity(id uuid.UUID) (*entity.User, error) {
+	dateOfBirth, err := parseDate(r.DateOfBirth)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.User{
+		ID:          id,
+		Firstname:   r.Firstname,
+		Lastname:    r.Lastname,
+		Email:       r.Email,
+		Age:         uint(r.Age),
+		DateOfBirth: dateOfBirth,
+	}, nil
+}
+
+// parseDate parses the date of birth of a request
+func parseDate(s string) (time.Time, error) {
+	t, err := entity.ParseDate(s)
+	if err != nil {
+		return time.Time{}, appErrors.NewAppError(codes.InvalidArgument, err.Error())
+	}
+	return t, nil
+}
+
+// UserResponse is the representation of a user returned by the API.
+type UserResponse struct {
+	ID          string    `json:"id"`
+	Firstname   string    `json:"firstname"`
+	Lastname    string    `json:"lastname"`
+	Email       string    `json:"email"`
+	Age         uint32    `json:"age"`
+	DateOfBirth string    `json:"dateOfBirth,omitempty"`
+	Created     time.Time `json:"created"`
+	Updated     time.Time `json:"updated"`
+}
+
+// NewUserResponse converts a user entity into its REST representation
+func NewUserResponse(user *entity.User) *UserResponse {
+	return &UserResponse{
+		ID:          user.ID.String(),
+		Firstname:   user.Firstname,
+		Lastname:    user.Lastname,
+		Email:       user.Email,
+		Age:         uint32(user.CurrentAge()),
+		DateOfBirth: entity.FormatDate(user.DateOfBirth),
+		Created:     user.Created.UTC(),
+		Updated:     user.Updated.UTC(),
+	}
+}
+
+// ListUsersResponse is the body returned by GET /users.
+type ListUsersResponse struct {
+	Users         []*UserResponse `json:"users"`
+	NextPageToken string          `json:"nextPageToken,omitempty"`
+}
+
+// NewListUsersResponse converts a page of users listed with limit into its
+// REST representation. A full page may be followed by more users, so it
+// carries the ID of its last user as the token of the next page.
+func NewListUsersResponse(users []*entity.User, limit int) *ListUsersResponse {
+	resp := &ListUsersResponse{Users: make([]*UserResponse, len(users))}
+	for i, u := range users {
+		resp.Users[i] = NewUserResponse(u)
+	}
+	if len(users) > 0 && len(users) == usecase.ListLimit(limit) {
+		resp.NextPageToken = users[len(users)-1].ID.String()
+	}
+	return resp
+}
+
+// UserMatchResponse is a user found by GET /users/search.
+type UserMatchResponse struct {
+	User       *UserResponse     `json:"user"`
+	Score      float64           `json:"score"`
+	Highlights map[string]string `json:"highlights"`
+}
+
+// SearchUsersResponse is the body returned by GET /users/search.
+type SearchUsersResponse struct {
+	Results []*UserMatchResponse `json:"results"`
+}
+
+// NewSearchUsersResponse converts search matches into their REST representation
+func NewSearchUsersResponse(matches []*entity.UserMatch) *SearchUsersResponse {
+	results := make([]*UserMatchResponse, len(matches))
+	for i, m := range matches {
+		results[i] = &UserMatchResponse{
+			User:       NewUserResponse(m.User),
+			Score:      m.Score,
+			Highlights: m.Highlights,
+		}
+	}
+	return &SearchUsersResponse{Results: results}
+}
+
+// BatchCreateUsersRequest is the body of POST /users:batchCreate.
+type BatchCreateUsersRequest struct {
+	Users  []CreateUserRequest `json:"users"`
+	Atomic bool                `json:"atomic"`
+}
+
+// BatchDeleteUsersRequest is the body of POST /users:batchDelete.
+type BatchDeleteUsersRequest struct {
+	IDs    []string `json:"ids"`
+	Atomic bool     `json:"atomic"`
+}
+
+// StatusResponse is the error of a batch result, in the JSON form of google.rpc.Status.
+type StatusResponse struct {
+	Code    int32  `json:"code"`
+	Message string `json:"message"`
+}
+
+// UserResultResponse is the outcome of a batch operation for one user.
+// At most one of User and Error is set.
+type UserResultResponse struct {
+	ID    string          `json:"id,omitempty"`
+	User  *UserResponse   `json:"user,omitempty"`
+	Error *StatusResponse `json:"error,omitempty"`
+}
+
+// BatchUsersResponse is the body returned by the batch endpoints.
+type BatchUsersResponse struct {
+	Results []*UserResultResponse `json:"results"`
+}
+
+// NewBatchUsersResponse converts batch results into their REST representation
+func NewBatchUsersResponse(results []*entity.UserResult) *BatchUsersResponse {
+	converted := make([]*UserResultResponse, len(results))
+	for i, r := range results {
+		result := &UserResultResponse{}
+		if r.ID != uuid.Nil {
+			result.ID = r.ID.String()
+		}
+		switch {
+		case r.Err != nil:
+			appErr := appErrors.FromError(r.Err)
+			result.Error = &StatusResponse{Code: int32(appErr.Code), Message: appErr.Message}
+		case r.User != nil:
+			result.User = NewUserResponse(r.User)
+		}
+		converted[i] = result
+	}
+	return &BatchUsersResponse{Results: converted}
+}
+
+// DeleteUserResponse is the body returned by DELETE /user/:id.
+type DeleteUserResponse struct {
+	Message string `json:"message"`
+}
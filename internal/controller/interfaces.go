@@ -1,6 +1,8 @@
 package controller
 
 import (
+	"context"
+
 	"github.com/interimme/userapi/internal/entity"
 
 	"github.com/google/uuid"
@@ -8,8 +10,15 @@ import (
 
 // UserUseCase interface defines the methods used by the controller
 type UserUseCase interface {
-	CreateUser(user *entity.User) error
-	GetUser(id uuid.UUID) (*entity.User, error)
-	UpdateUser(user *entity.User) error
-	DeleteUser(id uuid.UUID) error
+	CreateUser(ctx context.Context, user *entity.User) error
+	GetUser(ctx context.Context, id uuid.UUID) (*entity.User, error)
+	LookupUser(ctx context.Context, email string) (*entity.User, error)
+	CheckUserExists(ctx context.Context, id uuid.UUID) error
+	UpdateUser(ctx context.Context, user *entity.User) error
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	ListUsers(ctx context.Context, filterExpr string, afterID uuid.UUID, limit int) ([]*entity.User, error)
+	SearchUsers(ctx context.Context, query string, limit int) ([]*entity.UserMatch, error)
+	BatchGetUsers(ctx context.Context, ids []uuid.UUID) ([]*entity.UserResult, error)
+	BatchCreateUsers(ctx context.Context, users []*entity.User, atomic bool) ([]*entity.UserResult, error)
+	BatchDeleteUsers(ctx context.Context, ids []uuid.UUID, atomic bool) ([]*entity.UserResult, error)
 }
@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/interimme/userapi/internal/entity"
 
 	"github.com/google/uuid"
@@ -12,25 +14,78 @@ type UserUseCase struct {
 	mock.Mock
 }
 
-func (m *UserUseCase) CreateUser(user *entity.User) error {
-	args := m.Called(user)
+func (m *UserUseCase) CreateUser(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
-func (m *UserUseCase) GetUser(id uuid.UUID) (*entity.User, error) {
-	args := m.Called(id)
+func (m *UserUseCase) GetUser(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	args := m.Called(ctx, id)
+	if user, ok := args.Get(0).(*entity.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserUseCase) LookupUser(ctx context.Context, email string) (*entity.User, error) {
+	args := m.Called(ctx, email)
 	if user, ok := args.Get(0).(*entity.User); ok {
 		return user, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *UserUseCase) UpdateUser(user *entity.User) error {
-	args := m.Called(user)
+func (m *UserUseCase) CheckUserExists(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
-func (m *UserUseCase) DeleteUser(id uuid.UUID) error {
-	args := m.Called(id)
+func (m *UserUseCase) UpdateUser(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
+
+func (m *UserUseCase) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *UserUseCase) ListUsers(ctx context.Context, filterExpr string, afterID uuid.UUID, limit int) ([]*entity.User, error) {
+	args := m.Called(ctx, filterExpr, afterID, limit)
+	if users, ok := args.Get(0).([]*entity.User); ok {
+		return users, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserUseCase) SearchUsers(ctx context.Context, query string, limit int) ([]*entity.UserMatch, error) {
+	args := m.Called(ctx, query, limit)
+	if matches, ok := args.Get(0).([]*entity.UserMatch); ok {
+		return matches, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserUseCase) BatchGetUsers(ctx context.Context, ids []uuid.UUID) ([]*entity.UserResult, error) {
+	args := m.Called(ctx, ids)
+	if results, ok := args.Get(0).([]*entity.UserResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserUseCase) BatchCreateUsers(ctx context.Context, users []*entity.User, atomic bool) ([]*entity.UserResult, error) {
+	args := m.Called(ctx, users, atomic)
+	if results, ok := args.Get(0).([]*entity.UserResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserUseCase) BatchDeleteUsers(ctx context.Context, ids []uuid.UUID, atomic bool) ([]*entity.UserResult, error) {
+	args := m.Called(ctx, ids, atomic)
+	if results, ok := args.Get(0).([]*entity.UserResult); ok {
+		return results, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
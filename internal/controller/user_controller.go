@@ -1,16 +1,27 @@
 package controller
 
 import (
-	"errors"
+	"fmt"
 	appErrors "github.com/interimme/userapi/internal/apperrors"
 	"github.com/interimme/userapi/internal/entity"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
 )
 
-// UserController handles HTTP requests related to users
+// Errors reported for malformed requests
+var (
+	errInvalidRequest   = appErrors.NewAppError(codes.InvalidArgument, "invalid request")
+	errInvalidUUID      = appErrors.NewAppError(codes.InvalidArgument, "invalid uuid")
+	errInvalidPageSize  = appErrors.NewAppError(codes.InvalidArgument, "invalid page size")
+	errInvalidPageToken = appErrors.NewAppError(codes.InvalidArgument, "invalid page token")
+)
+
+// UserController handles HTTP requests related to users.
+// Errors are attached to the Gin context and rendered by middleware.ErrorHandler.
 type UserController struct {
 	UserUseCase UserUseCase // Use the interface defined in this package
 }
@@ -24,26 +35,28 @@ func NewUserController(uc UserUseCase) *UserController {
 
 // CreateUser handles the creation of a new user
 func (ctrl *UserController) CreateUser(c *gin.Context) {
-	var user entity.User
+	var req CreateUserRequest
 
-	// Bind JSON input to the user entity
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+	// Bind JSON input to the request DTO
+	if err := bindJSON(c, &req); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	user, err := req.ToEntity()
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	// Call the use case to create the user
-	if err := ctrl.UserUseCase.CreateUser(&user); err != nil {
-		if appErr, ok := err.(*appErrors.AppError); ok {
-			c.JSON(int(appErr.Code), gin.H{"error": appErr.Message})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	if err := ctrl.UserUseCase.CreateUser(c.Request.Context(), user); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
 	// Respond with the created user
-	c.JSON(http.StatusCreated, user)
+	c.JSON(http.StatusCreated, NewUserResponse(user))
 }
 
 // GetUser handles fetching a user by ID
@@ -51,21 +64,45 @@ func (ctrl *UserController) GetUser(c *gin.Context) {
 	idParam := c.Param("id")
 	userID, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid uuid"})
+		_ = c.Error(errInvalidUUID)
 		return
 	}
 
-	user, err := ctrl.UserUseCase.GetUser(userID)
+	user, err := ctrl.UserUseCase.GetUser(c.Request.Context(), userID)
 	if err != nil {
-		if errors.Is(err, appErrors.ErrNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// LookupUser handles fetching a user by the email query parameter
+func (ctrl *UserController) LookupUser(c *gin.Context) {
+	user, err := ctrl.UserUseCase.LookupUser(c.Request.Context(), c.Query("email"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
+}
+
+// CheckUserExists handles HEAD requests for a user, answering 200 if it
+// exists and 404 otherwise
+func (ctrl *UserController) CheckUserExists(c *gin.Context) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		_ = c.Error(errInvalidUUID)
+		return
+	}
+
+	if err := ctrl.UserUseCase.CheckUserExists(c.Request.Context(), userID); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	c.Status(http.StatusOK)
 }
 
 // UpdateUser handles updating an existing user
@@ -73,32 +110,35 @@ func (ctrl *UserController) UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
 	userID, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid uuid"})
+		_ = c.Error(errInvalidUUID)
 		return
 	}
 
-	var user entity.User
-	if err := c.ShouldBindJSON(&user); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid request"})
+	var req UpdateUserRequest
+	if err := bindJSON(c, &req); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	user.ID = userID
+	user, err := req.ToEntity(userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
 
-	if err := ctrl.UserUseCase.UpdateUser(&user); err != nil {
-		if errors.Is(err, appErrors.ErrNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
-			return
-		}
-		if appErr, ok := err.(*appErrors.AppError); ok {
-			c.JSON(int(appErr.Code), gin.H{"error": appErr.Message})
-			return
-		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+	if err := ctrl.UserUseCase.UpdateUser(c.Request.Context(), user); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	// Retrieve the updated user to include the timestamps
+	user, err = ctrl.UserUseCase.GetUser(c.Request.Context(), userID)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewUserResponse(user))
 }
 
 // DeleteUser handles deleting a user by ID
@@ -106,18 +146,150 @@ func (ctrl *UserController) DeleteUser(c *gin.Context) {
 	idParam := c.Param("id")
 	userID, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid uuid"})
+		_ = c.Error(errInvalidUUID)
+		return
+	}
+
+	if err := ctrl.UserUseCase.DeleteUser(c.Request.Context(), userID); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, DeleteUserResponse{Message: "User deleted successfully"})
+}
+
+// ListUsers handles listing the users matching the filter query parameter.
+// Pages are limited by pageSize (or page_size) and continue after pageToken
+// (or page_token), the nextPageToken of the previous page.
+func (ctrl *UserController) ListUsers(c *gin.Context) {
+	limit, err := pageSize(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	afterID := uuid.Nil
+	if token := c.DefaultQuery("pageToken", c.Query("page_token")); token != "" {
+		if afterID, err = uuid.Parse(token); err != nil {
+			_ = c.Error(errInvalidPageToken)
+			return
+		}
+	}
+
+	users, err := ctrl.UserUseCase.ListUsers(c.Request.Context(), c.Query("filter"), afterID, limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewListUsersResponse(users, limit))
+}
+
+// SearchUsers handles searching users by the words in the q query parameter.
+// The number of results is limited by pageSize (or page_size).
+func (ctrl *UserController) SearchUsers(c *gin.Context) {
+	limit, err := pageSize(c)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	matches, err := ctrl.UserUseCase.SearchUsers(c.Request.Context(), c.Query("q"), limit)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSearchUsersResponse(matches))
+}
+
+// pageSize returns the pageSize (or page_size) query parameter, 0 if unset.
+func pageSize(c *gin.Context) (int, error) {
+	pageSize := c.DefaultQuery("pageSize", c.Query("page_size"))
+	if pageSize == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(pageSize)
+	if err != nil {
+		return 0, errInvalidPageSize
+	}
+	return n, nil
+}
+
+// BatchGetUsers handles fetching the users whose IDs are given as repeated
+// ids query parameters
+func (ctrl *UserController) BatchGetUsers(c *gin.Context) {
+	ids, err := parseIDs(c.QueryArray("ids"))
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	results, err := ctrl.UserUseCase.BatchGetUsers(c.Request.Context(), ids)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBatchUsersResponse(results))
+}
+
+// BatchCreateUsers handles creating several users at once
+func (ctrl *UserController) BatchCreateUsers(c *gin.Context) {
+	var req BatchCreateUsersRequest
+	if err := bindJSON(c, &req); err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	if err := ctrl.UserUseCase.DeleteUser(userID); err != nil {
-		if errors.Is(err, appErrors.ErrNotFound) {
-			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+	users := make([]*entity.User, len(req.Users))
+	for i := range req.Users {
+		user, err := req.Users[i].ToEntity()
+		if err != nil {
+			_ = c.Error(appErrors.NewAppError(codes.InvalidArgument, fmt.Sprintf("users[%d]: %s", i, appErrors.FromError(err).Message)))
 			return
 		}
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "internal server error"})
+		users[i] = user
+	}
+	results, err := ctrl.UserUseCase.BatchCreateUsers(c.Request.Context(), users, req.Atomic)
+	if err != nil {
+		_ = c.Error(err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
+	c.JSON(http.StatusOK, NewBatchUsersResponse(results))
+}
+
+// BatchDeleteUsers handles deleting several users at once
+func (ctrl *UserController) BatchDeleteUsers(c *gin.Context) {
+	var req BatchDeleteUsersRequest
+	if err := bindJSON(c, &req); err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	ids, err := parseIDs(req.IDs)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+	results, err := ctrl.UserUseCase.BatchDeleteUsers(c.Request.Context(), ids, req.Atomic)
+	if err != nil {
+		_ = c.Error(err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewBatchUsersResponse(results))
+}
+
+// parseIDs parses the user IDs of a batch request
+func parseIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, appErrors.NewAppError(codes.InvalidArgument, fmt.Sprintf("ids[%d]: invalid uuid", i))
+		}
+		ids[i] = id
+	}
+	return ids, nil
 }
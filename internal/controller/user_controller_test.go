@@ -3,9 +3,10 @@ package controller
 import (
 	"bytes"
 	"encoding/json"
+	appErrors "github.com/interimme/userapi/internal/apperrors"
 	"github.com/interimme/userapi/internal/controller/mocks"
 	"github.com/interimme/userapi/internal/entity"
-	appErrors "github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/infrastructure/middleware"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 )
 
 func TestMain(m *testing.M) {
@@ -30,9 +32,10 @@ func TestCreateUser_Success(t *testing.T) {
 
 	// Create a test router
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.POST("/users", userController.CreateUser)
 
-	user := entity.User{
+	user := CreateUserRequest{
 		Firstname: "Alice",
 		Lastname:  "Smith",
 		Email:     "alice@example.com",
@@ -43,7 +46,7 @@ func TestCreateUser_Success(t *testing.T) {
 	require.NoError(t, err, "Failed to marshal user to JSON")
 
 	// Mock the use case to return no error
-	mockUseCase.On("CreateUser", mock.AnythingOfType("*entity.User")).Return(nil)
+	mockUseCase.On("CreateUser", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil)
 
 	req, err := http.NewRequest("POST", "/users", bytes.NewBuffer(userJSON))
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -53,13 +56,13 @@ func TestCreateUser_Success(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusCreated, w.Code)
-	var response entity.User
+	var response UserResponse
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
 	assert.Equal(t, "Alice", response.Firstname)
 	assert.Equal(t, "Smith", response.Lastname)
 	assert.Equal(t, "alice@example.com", response.Email)
-	assert.Equal(t, uint(28), response.Age)
+	assert.Equal(t, uint32(28), response.Age)
 }
 
 func TestCreateUser_InvalidJSON(t *testing.T) {
@@ -69,6 +72,7 @@ func TestCreateUser_InvalidJSON(t *testing.T) {
 
 	// Create a test router
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.POST("/users", userController.CreateUser)
 
 	// Create an invalid JSON payload
@@ -83,10 +87,11 @@ func TestCreateUser_InvalidJSON(t *testing.T) {
 
 	// Assert the response
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "invalid request", response["error"])
+	assert.Equal(t, "invalid request", response.Detail)
 }
 
 func TestCreateUser_ValidationError(t *testing.T) {
@@ -94,9 +99,10 @@ func TestCreateUser_ValidationError(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.POST("/users", userController.CreateUser)
 
-	user := entity.User{
+	user := CreateUserRequest{
 		Firstname: "",
 		Lastname:  "Smith",
 		Email:     "alice@example.com",
@@ -107,7 +113,7 @@ func TestCreateUser_ValidationError(t *testing.T) {
 	require.NoError(t, err, "Failed to marshal user to JSON")
 
 	// Mock the use case to return a validation error
-	mockUseCase.On("CreateUser", mock.AnythingOfType("*entity.User")).Return(&appErrors.AppError{Code: http.StatusBadRequest, Message: "firstname is required"})
+	mockUseCase.On("CreateUser", mock.Anything, mock.AnythingOfType("*entity.User")).Return(appErrors.NewAppError(codes.InvalidArgument, "firstname is required"))
 
 	req, err := http.NewRequest("POST", "/users", bytes.NewBuffer(userJSON))
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -117,10 +123,11 @@ func TestCreateUser_ValidationError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "firstname is required", response["error"])
+	assert.Equal(t, "firstname is required", response.Detail)
 }
 
 func TestGetUser_Success(t *testing.T) {
@@ -128,6 +135,7 @@ func TestGetUser_Success(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.GET("/user/:id", userController.GetUser)
 
 	userID := uuid.New()
@@ -140,7 +148,7 @@ func TestGetUser_Success(t *testing.T) {
 	}
 
 	// Mock the use case to return the user
-	mockUseCase.On("GetUser", userID).Return(user, nil)
+	mockUseCase.On("GetUser", mock.Anything, userID).Return(user, nil)
 
 	req, err := http.NewRequest("GET", "/user/"+userID.String(), nil)
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -149,13 +157,13 @@ func TestGetUser_Success(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var response entity.User
+	var response UserResponse
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
 	assert.Equal(t, "Alice", response.Firstname)
 	assert.Equal(t, "Smith", response.Lastname)
 	assert.Equal(t, "alice@example.com", response.Email)
-	assert.Equal(t, uint(28), response.Age)
+	assert.Equal(t, uint32(28), response.Age)
 }
 
 func TestGetUser_InvalidUUID(t *testing.T) {
@@ -163,6 +171,7 @@ func TestGetUser_InvalidUUID(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.GET("/user/:id", userController.GetUser)
 
 	req, err := http.NewRequest("GET", "/user/invalid-uuid", nil)
@@ -172,10 +181,11 @@ func TestGetUser_InvalidUUID(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "invalid uuid", response["error"])
+	assert.Equal(t, "invalid uuid", response.Detail)
 }
 
 func TestGetUser_NotFound(t *testing.T) {
@@ -183,12 +193,13 @@ func TestGetUser_NotFound(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.GET("/user/:id", userController.GetUser)
 
 	userID := uuid.New()
 
 	// Mock the use case to return ErrNotFound
-	mockUseCase.On("GetUser", userID).Return(nil, appErrors.ErrNotFound)
+	mockUseCase.On("GetUser", mock.Anything, userID).Return(nil, appErrors.ErrNotFound)
 
 	req, err := http.NewRequest("GET", "/user/"+userID.String(), nil)
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -197,10 +208,11 @@ func TestGetUser_NotFound(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "user not found", response["error"])
+	assert.Equal(t, "user not found", response.Detail)
 }
 
 func TestUpdateUser_Success(t *testing.T) {
@@ -208,10 +220,11 @@ func TestUpdateUser_Success(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.PATCH("/user/:id", userController.UpdateUser)
 
 	userID := uuid.New()
-	user := entity.User{
+	user := UpdateUserRequest{
 		Firstname: "Alice",
 		Lastname:  "Johnson",
 		Email:     "alice.johnson@example.com",
@@ -221,8 +234,15 @@ func TestUpdateUser_Success(t *testing.T) {
 	userJSON, err := json.Marshal(user)
 	require.NoError(t, err, "Failed to marshal user to JSON")
 
-	// Mock the use case to return no error
-	mockUseCase.On("UpdateUser", mock.AnythingOfType("*entity.User")).Return(nil)
+	// Mock the use case to return no error and the updated user
+	mockUseCase.On("UpdateUser", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil)
+	mockUseCase.On("GetUser", mock.Anything, userID).Return(&entity.User{
+		ID:        userID,
+		Firstname: "Alice",
+		Lastname:  "Johnson",
+		Email:     "alice.johnson@example.com",
+		Age:       29,
+	}, nil)
 
 	req, err := http.NewRequest("PATCH", "/user/"+userID.String(), bytes.NewBuffer(userJSON))
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -232,13 +252,14 @@ func TestUpdateUser_Success(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var response entity.User
+	var response UserResponse
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
+	assert.Equal(t, userID.String(), response.ID)
 	assert.Equal(t, "Alice", response.Firstname)
 	assert.Equal(t, "Johnson", response.Lastname)
 	assert.Equal(t, "alice.johnson@example.com", response.Email)
-	assert.Equal(t, uint(29), response.Age)
+	assert.Equal(t, uint32(29), response.Age)
 }
 
 func TestUpdateUser_InvalidUUID(t *testing.T) {
@@ -246,9 +267,10 @@ func TestUpdateUser_InvalidUUID(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.PATCH("/user/:id", userController.UpdateUser)
 
-	user := entity.User{
+	user := UpdateUserRequest{
 		Firstname: "Alice",
 		Lastname:  "Johnson",
 		Email:     "alice.johnson@example.com",
@@ -266,10 +288,11 @@ func TestUpdateUser_InvalidUUID(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "invalid uuid", response["error"])
+	assert.Equal(t, "invalid uuid", response.Detail)
 }
 
 func TestUpdateUser_ValidationError(t *testing.T) {
@@ -277,10 +300,11 @@ func TestUpdateUser_ValidationError(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.PATCH("/user/:id", userController.UpdateUser)
 
 	userID := uuid.New()
-	user := entity.User{
+	user := UpdateUserRequest{
 		Firstname: "",
 		Lastname:  "Johnson",
 		Email:     "alice.johnson@example.com",
@@ -291,7 +315,7 @@ func TestUpdateUser_ValidationError(t *testing.T) {
 	require.NoError(t, err, "Failed to marshal user to JSON")
 
 	// Mock the use case to return a validation error
-	mockUseCase.On("UpdateUser", mock.AnythingOfType("*entity.User")).Return(&appErrors.AppError{Code: http.StatusBadRequest, Message: "firstname is required"})
+	mockUseCase.On("UpdateUser", mock.Anything, mock.AnythingOfType("*entity.User")).Return(appErrors.NewAppError(codes.InvalidArgument, "firstname is required"))
 
 	req, err := http.NewRequest("PATCH", "/user/"+userID.String(), bytes.NewBuffer(userJSON))
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -301,10 +325,11 @@ func TestUpdateUser_ValidationError(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "firstname is required", response["error"])
+	assert.Equal(t, "firstname is required", response.Detail)
 }
 
 func TestUpdateUser_NotFound(t *testing.T) {
@@ -312,10 +337,11 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.PATCH("/user/:id", userController.UpdateUser)
 
 	userID := uuid.New()
-	user := entity.User{
+	user := UpdateUserRequest{
 		Firstname: "Alice",
 		Lastname:  "Johnson",
 		Email:     "alice.johnson@example.com",
@@ -326,7 +352,7 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	require.NoError(t, err, "Failed to marshal user to JSON")
 
 	// Mock the use case to return ErrNotFound
-	mockUseCase.On("UpdateUser", mock.AnythingOfType("*entity.User")).Return(appErrors.ErrNotFound)
+	mockUseCase.On("UpdateUser", mock.Anything, mock.AnythingOfType("*entity.User")).Return(appErrors.ErrNotFound)
 
 	req, err := http.NewRequest("PATCH", "/user/"+userID.String(), bytes.NewBuffer(userJSON))
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -336,10 +362,11 @@ func TestUpdateUser_NotFound(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "user not found", response["error"])
+	assert.Equal(t, "user not found", response.Detail)
 }
 
 func TestDeleteUser_Success(t *testing.T) {
@@ -347,12 +374,13 @@ func TestDeleteUser_Success(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.DELETE("/user/:id", userController.DeleteUser)
 
 	userID := uuid.New()
 
 	// Mock the use case to return no error
-	mockUseCase.On("DeleteUser", userID).Return(nil)
+	mockUseCase.On("DeleteUser", mock.Anything, userID).Return(nil)
 
 	req, err := http.NewRequest("DELETE", "/user/"+userID.String(), nil)
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -361,10 +389,10 @@ func TestDeleteUser_Success(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusOK, w.Code)
-	var response map[string]string
+	var response DeleteUserResponse
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "User deleted successfully", response["message"])
+	assert.Equal(t, "User deleted successfully", response.Message)
 }
 
 func TestDeleteUser_InvalidUUID(t *testing.T) {
@@ -372,6 +400,7 @@ func TestDeleteUser_InvalidUUID(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.DELETE("/user/:id", userController.DeleteUser)
 
 	req, err := http.NewRequest("DELETE", "/user/invalid-uuid", nil)
@@ -381,10 +410,11 @@ func TestDeleteUser_InvalidUUID(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusBadRequest, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "invalid uuid", response["error"])
+	assert.Equal(t, "invalid uuid", response.Detail)
 }
 
 func TestDeleteUser_NotFound(t *testing.T) {
@@ -392,12 +422,13 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	userController := NewUserController(mockUseCase)
 
 	router := gin.New()
+	router.Use(middleware.ErrorHandler)
 	router.DELETE("/user/:id", userController.DeleteUser)
 
 	userID := uuid.New()
 
 	// Mock the use case to return ErrNotFound
-	mockUseCase.On("DeleteUser", userID).Return(appErrors.ErrNotFound)
+	mockUseCase.On("DeleteUser", mock.Anything, userID).Return(appErrors.ErrNotFound)
 
 	req, err := http.NewRequest("DELETE", "/user/"+userID.String(), nil)
 	require.NoError(t, err, "Failed to create HTTP request")
@@ -406,8 +437,290 @@ func TestDeleteUser_NotFound(t *testing.T) {
 	router.ServeHTTP(w, req)
 
 	assert.Equal(t, http.StatusNotFound, w.Code)
-	var response map[string]string
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	var response appErrors.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err, "Failed to unmarshal response JSON")
+	assert.Equal(t, "user not found", response.Detail)
+}
+
+func TestCreateUser_UnknownField(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.POST("/users", userController.CreateUser)
+
+	// Clients must not be able to set server-assigned fields
+	body := `{"id": "6f1c1c9e-8a0e-4f7e-9a8b-0c6f1b2a3d4e", "firstname": "Alice", "lastname": "Smith", "email": "alice@example.com", "age": 28}`
+	req, err := http.NewRequest("POST", "/users", strings.NewReader(body))
+	require.NoError(t, err, "Failed to create HTTP request")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response appErrors.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err, "Failed to unmarshal response JSON")
+	assert.Equal(t, `invalid request: unknown field "id"`, response.Detail)
+	mockUseCase.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestCreateUser_InvalidDateOfBirth(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.POST("/users", userController.CreateUser)
+
+	body := `{"firstname": "Alice", "lastname": "Smith", "email": "alice@example.com", "dateOfBirth": "1997-02-30"}`
+	req, err := http.NewRequest("POST", "/users", strings.NewReader(body))
+	require.NoError(t, err, "Failed to create HTTP request")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response appErrors.Problem
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err, "Failed to unmarshal response JSON")
+	assert.Equal(t, `date_of_birth must be a date like 2000-01-31, got "1997-02-30"`, response.Detail)
+	mockUseCase.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestCreateUser_BodyTooLarge(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.POST("/users", userController.CreateUser)
+
+	body := `{"firstname": "` + strings.Repeat("a", MaxRequestBodyBytes) + `"}`
+	req, err := http.NewRequest("POST", "/users", strings.NewReader(body))
+	require.NoError(t, err, "Failed to create HTTP request")
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	mockUseCase.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+}
+
+func TestListUsers_Success(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users", userController.ListUsers)
+
+	afterID := uuid.New()
+	users := []*entity.User{{ID: uuid.New(), Firstname: "Ada"}, {ID: uuid.New(), Firstname: "Alan"}}
+	mockUseCase.On("ListUsers", mock.Anything, "age >= 18", afterID, 2).Return(users, nil)
+
+	req, err := http.NewRequest("GET", "/users?filter=age+%3E%3D+18&pageSize=2&pageToken="+afterID.String(), nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response ListUsersResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response), "Failed to unmarshal response JSON")
+	require.Len(t, response.Users, 2)
+	assert.Equal(t, "Alan", response.Users[1].Firstname)
+	assert.Equal(t, users[1].ID.String(), response.NextPageToken, "a full page links to the next one")
+}
+
+func TestListUsers_InvalidFilter(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users", userController.ListUsers)
+
+	mockUseCase.On("ListUsers", mock.Anything, "nickname = x", uuid.Nil, 0).
+		Return(nil, appErrors.NewAppError(codes.InvalidArgument, `invalid filter at column 1: unknown field "nickname"`))
+
+	req, err := http.NewRequest("GET", "/users?filter=nickname+%3D+x", nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, appErrors.ProblemContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `unknown field \"nickname\"`)
+}
+
+func TestListUsers_InvalidPageToken(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users", userController.ListUsers)
+
+	req, err := http.NewRequest("GET", "/users?page_token=next", nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockUseCase.AssertNotCalled(t, "ListUsers", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestSearchUsers_Success(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users/search", userController.SearchUsers)
+
+	user := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	mockUseCase.On("SearchUsers", mock.Anything, "ada", 5).Return([]*entity.UserMatch{
+		{User: user, Score: 0.75, Highlights: map[string]string{"firstname": "<mark>Ada</mark>"}},
+	}, nil)
+
+	req, err := http.NewRequest("GET", "/users/search?q=ada&page_size=5", nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response SearchUsersResponse
 	err = json.Unmarshal(w.Body.Bytes(), &response)
 	require.NoError(t, err, "Failed to unmarshal response JSON")
-	assert.Equal(t, "user not found", response["error"])
+	require.Len(t, response.Results, 1)
+	assert.Equal(t, user.ID.String(), response.Results[0].User.ID)
+	assert.Equal(t, 0.75, response.Results[0].Score)
+	assert.Equal(t, "<mark>Ada</mark>", response.Results[0].Highlights["firstname"])
+}
+
+func TestSearchUsers_InvalidPageSize(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users/search", userController.SearchUsers)
+
+	req, err := http.NewRequest("GET", "/users/search?q=ada&pageSize=many", nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	mockUseCase.AssertNotCalled(t, "SearchUsers", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBatchGetUsers_Success(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users:batchGet", userController.BatchGetUsers)
+
+	user := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	missing := uuid.New()
+	mockUseCase.On("BatchGetUsers", mock.Anything, []uuid.UUID{user.ID, missing}).Return([]*entity.UserResult{
+		{ID: user.ID, User: user},
+		{ID: missing, Err: appErrors.ErrNotFound},
+	}, nil)
+
+	req, err := http.NewRequest("GET", "/users:batchGet?ids="+user.ID.String()+"&ids="+missing.String(), nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response BatchUsersResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err, "Failed to unmarshal response JSON")
+	require.Len(t, response.Results, 2)
+	assert.Equal(t, "Ada", response.Results[0].User.Firstname)
+	assert.Nil(t, response.Results[0].Error)
+	assert.Equal(t, &UserResultResponse{ID: missing.String(), Error: &StatusResponse{Code: 5, Message: "user not found"}}, response.Results[1])
+}
+
+func TestBatchDeleteUsers_InvalidUUID(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.POST("/users:batchDelete", userController.BatchDeleteUsers)
+
+	req, err := http.NewRequest("POST", "/users:batchDelete", strings.NewReader(`{"ids": ["`+uuid.NewString()+`", "nope"], "atomic": true}`))
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "ids[1]: invalid uuid")
+	mockUseCase.AssertNotCalled(t, "BatchDeleteUsers", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestLookupUser_Success(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.GET("/users:lookup", userController.LookupUser)
+
+	user := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	mockUseCase.On("LookupUser", mock.Anything, "Ada@example.com").Return(user, nil)
+
+	req, err := http.NewRequest("GET", "/users:lookup?email=Ada%40example.com", nil)
+	require.NoError(t, err, "Failed to create HTTP request")
+	w := httptest.NewRecorder()
+
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response UserResponse
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	require.NoError(t, err, "Failed to unmarshal response JSON")
+	assert.Equal(t, user.ID.String(), response.ID)
+}
+
+func TestCheckUserExists(t *testing.T) {
+	mockUseCase := new(mocks.UserUseCase)
+	userController := NewUserController(mockUseCase)
+
+	router := gin.New()
+	router.Use(middleware.ErrorHandler)
+	router.HEAD("/user/:id", userController.CheckUserExists)
+
+	existing, missing, unauthorized := uuid.New(), uuid.New(), uuid.New()
+	mockUseCase.On("CheckUserExists", mock.Anything, existing).Return(nil)
+	mockUseCase.On("CheckUserExists", mock.Anything, missing).Return(appErrors.ErrNotFound)
+	mockUseCase.On("CheckUserExists", mock.Anything, unauthorized).Return(appErrors.ErrUnauthorized)
+
+	for id, want := range map[uuid.UUID]int{existing: http.StatusOK, missing: http.StatusNotFound, unauthorized: http.StatusUnauthorized} {
+		req, err := http.NewRequest("HEAD", "/user/"+id.String(), nil)
+		require.NoError(t, err, "Failed to create HTTP request")
+		w := httptest.NewRecorder()
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, want, w.Code)
+	}
 }
@@ -0,0 +1,169 @@
+// Package email provides the email address value type of the users.
+//
+// Addresses are validated as RFC 5322 addr-specs: dot-atom or quoted local
+// parts, including UTF-8 (RFC 6532) and subaddresses such as
+// alice+news@example.com, at a domain name, which may be internationalized.
+// Each Address keeps the text it was parsed from and a canonical form that
+// identifies the mailbox, so that addresses differing only in case or in
+// the encoding of the domain compare equal.
+package email
+
+import (
+	"errors"
+	"net/mail"
+	"strings"
+
+	"golang.org/x/net/idna"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Length limits of RFC 5321.
+const (
+	maxLength      = 254
+	maxLocalLength = 64
+	maxLabelLength = 63
+)
+
+// Names of the rule sets that canonical forms are computed with. Stored
+// canonical forms are only comparable with those of the same rule set, so a
+// name must change whenever its rules do, e.g. when a provider is added.
+const (
+	StandardRules = "standard-v1"
+	ProviderRules = "provider-v1"
+)
+
+// RuleSet returns the name of the rules of Canonical, with those of
+// WithProviderRules if providerRules is set.
+func RuleSet(providerRules bool) string {
+	if providerRules {
+		return ProviderRules
+	}
+	return StandardRules
+}
+
+// ErrInvalid is returned by Parse for text that is not an email address.
+var ErrInvalid = errors.New("invalid email format")
+
+// Address is a parsed email address. The zero Address is empty.
+type Address struct {
+	original  string
+	canonical string
+}
+
+// Parse parses a single address without display name or comments, such as
+// "Alice@Example.com", ignoring surrounding spaces.
+func Parse(s string) (Address, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || strings.ContainsAny(s, "<>") {
+		return Address{}, ErrInvalid
+	}
+	parsed, err := mail.ParseAddress(s)
+	if err != nil || parsed.Name != "" {
+		return Address{}, ErrInvalid
+	}
+
+	// The domain cannot contain @, so the local part is everything before the last one
+	at := strings.LastIndexByte(parsed.Address, '@')
+	local, domain := parsed.Address[:at], parsed.Address[at+1:]
+	asciiDomain, ok := domainToASCII(domain)
+	if !ok || local == "" || len(local) > maxLocalLength {
+		return Address{}, ErrInvalid
+	}
+
+	canonical := strings.ToLower(norm.NFC.String(local)) + "@" + asciiDomain
+	if len(canonical) > maxLength {
+		return Address{}, ErrInvalid
+	}
+	return Address{original: s, canonical: canonical}, nil
+}
+
+// domainToASCII validates a domain name and returns its lowercase ASCII
+// form, with internationalized labels in Punycode. Domain literals such as
+// [192.0.2.1] and names without a dot are not accepted for users.
+func domainToASCII(domain string) (string, bool) {
+	ascii, err := idna.Lookup.ToASCII(domain)
+	if err != nil || len(ascii) > maxLength {
+		return "", false
+	}
+	labels := strings.Split(ascii, ".")
+	if len(labels) < 2 {
+		return "", false
+	}
+	for _, label := range labels {
+		if label == "" || len(label) > maxLabelLength {
+			return "", false
+		}
+	}
+	if strings.Trim(labels[len(labels)-1], "0123456789") == "" {
+		return "", false
+	}
+	return strings.ToLower(ascii), true
+}
+
+// String returns the address as it was given.
+func (a Address) String() string {
+	return a.original
+}
+
+// Canonical returns the canonical form of the address: the local part in
+// lowercase and Unicode NFC, and the domain in lowercase ASCII. It
+// identifies the mailbox for comparisons and is not meant for display or
+// for sending mail, since quotes are removed from the local part.
+func (a Address) Canonical() string {
+	return a.canonical
+}
+
+// provider describes how a mail provider delivers variants of an address
+// to the same mailbox.
+type provider struct {
+	domain     string // the domain of the canonical form, if it has aliases
+	ignoreDots bool   // dots in the local part are ignored
+	subaddress byte   // the local part ends at this separator
+}
+
+// providers are the well-known providers by domain.
+var providers = map[string]provider{
+	"gmail.com":      {domain: "gmail.com", ignoreDots: true, subaddress: '+'},
+	"googlemail.com": {domain: "gmail.com", ignoreDots: true, subaddress: '+'},
+	"outlook.com":    {subaddress: '+'},
+	"hotmail.com":    {subaddress: '+'},
+	"live.com":       {subaddress: '+'},
+	"icloud.com":     {domain: "icloud.com", subaddress: '+'},
+	"me.com":         {domain: "icloud.com", subaddress: '+'},
+	"mac.com":        {domain: "icloud.com", subaddress: '+'},
+	"fastmail.com":   {subaddress: '+'},
+	"proton.me":      {domain: "proton.me", subaddress: '+'},
+	"protonmail.com": {domain: "proton.me", subaddress: '+'},
+}
+
+// WithProviderRules returns a with a canonical form that also merges the
+// variants that well-known mail providers deliver to the same mailbox, e.g.
+// a.lice+news@googlemail.com becomes alice@gmail.com. Addresses at other
+// domains are returned unchanged.
+func (a Address) WithProviderRules() Address {
+	at := strings.LastIndexByte(a.canonical, '@')
+	if at < 0 {
+		return a
+	}
+	local, domain := a.canonical[:at], a.canonical[at+1:]
+	p, ok := providers[domain]
+	if !ok {
+		return a
+	}
+	if p.subaddress != 0 {
+		if i := strings.IndexByte(local, p.subaddress); i > 0 {
+			local = local[:i]
+		}
+	}
+	if p.ignoreDots {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+	if p.domain != "" {
+		domain = p.domain
+	}
+	if local == "" {
+		return a
+	}
+	a.canonical = local + "@" + domain
+	return a
+}
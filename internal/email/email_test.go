@@ -0,0 +1,74 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for input, canonical := range map[string]string{
+		"alice@example.com":          "alice@example.com",
+		" Alice@Example.COM ":        "alice@example.com",
+		"alice+news@example.com":     "alice+news@example.com",
+		"o'brien@example.co.uk":      "o'brien@example.co.uk",
+		`"john doe"@example.com`:     "john doe@example.com",
+		"jürgen@bücher.de":           "jürgen@xn--bcher-kva.de",
+		"JÜRGEN@BÜCHER.DE":           "jürgen@xn--bcher-kva.de",
+		"info@xn--bcher-kva.de":      "info@xn--bcher-kva.de",
+		"user@mail.example.museum":   "user@mail.example.museum",
+		"first.last@sub.example.org": "first.last@sub.example.org",
+	} {
+		addr, err := Parse(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, strings.TrimSpace(input), addr.String(), input)
+		assert.Equal(t, canonical, addr.Canonical(), input)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	for _, input := range []string{
+		"",
+		"not-an-email",
+		"alice@",
+		"@example.com",
+		"alice@example",
+		"alice@localhost",
+		"alice@[192.0.2.1]",
+		"alice@-example.com",
+		"alice@example..com",
+		"alice@example.com.",
+		"alice@exa_mple.com",
+		"alice@192.0.2.1",
+		"a..b@example.com",
+		"Alice <alice@example.com>",
+		"alice@example.com, bob@example.com",
+		strings.Repeat("a", 65) + "@example.com",
+		"alice@" + strings.Repeat("a", 64) + ".com",
+		"alice@" + strings.Repeat(strings.Repeat("a", 60)+".", 5) + "com",
+	} {
+		_, err := Parse(input)
+		assert.ErrorIs(t, err, ErrInvalid, input)
+	}
+}
+
+func TestWithProviderRules(t *testing.T) {
+	for input, canonical := range map[string]string{
+		"A.Lice+news@googlemail.com": "alice@gmail.com",
+		"alice.smith@gmail.com":      "alicesmith@gmail.com",
+		"alice+news@outlook.com":     "alice@outlook.com",
+		"alice.smith+x@hotmail.com":  "alice.smith@hotmail.com",
+		"alice+x@me.com":             "alice@icloud.com",
+		"alice+x@protonmail.com":     "alice@proton.me",
+		"alice+news@example.com":     "alice+news@example.com",
+		"+news@gmail.com":            "+news@gmail.com",
+	} {
+		addr, err := Parse(input)
+		require.NoError(t, err, input)
+		addr = addr.WithProviderRules()
+		assert.Equal(t, input, addr.String(), input)
+		assert.Equal(t, canonical, addr.Canonical(), input)
+	}
+}
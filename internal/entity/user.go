@@ -2,20 +2,109 @@ package entity
 
 import (
 	"errors"
-	"regexp"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
+
+	"github.com/interimme/userapi/internal/email"
 )
 
 // User represents the user entity in the application
 type User struct {
-	ID        uuid.UUID // Unique identifier
-	Firstname string    // User's first name
-	Lastname  string    // User's last name
-	Email     string    // User's email address
-	Age       uint      // User's age
-	Created   time.Time // Timestamp of user creation
+	ID             uuid.UUID // Unique identifier
+	Firstname      string    // User's first name
+	Lastname       string    // User's last name
+	Email          string    // User's email address, as given
+	CanonicalEmail string    // Email in canonical form, unique across users; set by the use cases
+	DateOfBirth    time.Time // Date of birth at midnight UTC; zero for users written with an age only
+	Age            uint      // Age in years as given, or as of the last write for users with a date of birth; see AgeOn
+	Created        time.Time // Timestamp of user creation; set by the repository unless imported
+	Updated        time.Time // Timestamp of the last write; set by the repository
+}
+
+// MaxAge is the highest plausible age in years
+const MaxAge = 150
+
+// DateLayout is the format of dates of birth in the API, e.g. 2000-01-31
+const DateLayout = time.DateOnly
+
+// ParseDate parses a date of birth in DateLayout. The empty string yields
+// the zero time.
+func ParseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(DateLayout, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date_of_birth must be a date like 2000-01-31, got %q", s)
+	}
+	return t, nil
+}
+
+// FormatDate formats a date of birth in DateLayout, or returns "" for the
+// zero time.
+func FormatDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(DateLayout)
+}
+
+// AgeOn returns the age in whole years on the day of t, in UTC, of someone
+// born on dateOfBirth. People born on February 29 turn a year older on
+// March 1 in common years.
+func AgeOn(dateOfBirth, t time.Time) uint {
+	t = t.UTC()
+	years := t.Year() - dateOfBirth.Year()
+	if t.Month() < dateOfBirth.Month() || t.Month() == dateOfBirth.Month() && t.Day() < dateOfBirth.Day() {
+		years--
+	}
+	return uint(max(years, 0))
+}
+
+// BornBy returns the latest date of birth of people who are at least age
+// years old on the day of t, in UTC, consistent with AgeOn.
+func BornBy(age uint, t time.Time) time.Time {
+	t = t.UTC()
+	year, month, day := t.Year()-int(age), t.Month(), t.Day()
+	if month == time.February && day == 29 && !isLeap(year) {
+		day = 28
+	}
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+// AgeOn returns the user's age on the day of t: computed from the date of
+// birth if there is one, and the age as given otherwise.
+func (u *User) AgeOn(t time.Time) uint {
+	if u.DateOfBirth.IsZero() {
+		return u.Age
+	}
+	return AgeOn(u.DateOfBirth, t)
+}
+
+// CurrentAge returns the user's age today.
+func (u *User) CurrentAge() uint {
+	return u.AgeOn(time.Now())
+}
+
+// UserMatch is a user found by a search
+type UserMatch struct {
+	User       *User
+	Score      float64           // Relevance, higher is better
+	Highlights map[string]string // Matched fields by JSON name, as HTML with the matches in <mark> elements
+}
+
+// UserResult is the outcome for one user of a batch operation: the user,
+// if any, or the error that occurred for it
+type UserResult struct {
+	ID   uuid.UUID
+	User *User
+	Err  error
 }
 
 // Validate checks the fields of the User entity for correctness
@@ -29,17 +118,25 @@ func (u *User) Validate() error {
 	if u.Email == "" {
 		return errors.New("email is required")
 	}
-	if !isValidEmail(u.Email) {
-		return errors.New("invalid email format")
+	if _, err := email.Parse(u.Email); err != nil {
+		return err
 	}
-	if u.Age == 0 || u.Age > 150 {
-		return errors.New("age must be between 1 and 150")
+	if u.DateOfBirth.IsZero() {
+		// Users of clients that predate dates of birth
+		switch {
+		case u.Age == 0:
+			return errors.New("date_of_birth is required")
+		case u.Age > MaxAge:
+			return fmt.Errorf("age must be between 1 and %d", MaxAge)
+		}
+		return nil
+	}
+	now := time.Now()
+	switch {
+	case u.DateOfBirth.After(now):
+		return errors.New("date_of_birth must not be in the future")
+	case !u.DateOfBirth.After(BornBy(MaxAge+1, now)):
+		return fmt.Errorf("date_of_birth must not be more than %d years ago", MaxAge)
 	}
 	return nil
 }
-
-// Helper function to validate email format using a regex
-func isValidEmail(email string) bool {
-	re := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}$`)
-	return re.MatchString(email)
-}
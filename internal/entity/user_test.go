@@ -0,0 +1,55 @@
+package entity
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestAgeOn(t *testing.T) {
+	tests := []struct {
+		dateOfBirth, on time.Time
+		want            uint
+	}{
+		{date(1990, 5, 17), date(2025, 5, 16), 34},
+		{date(1990, 5, 17), date(2025, 5, 17), 35},
+		// Leap day births have their birthday on March 1 in common years
+		{date(2000, 2, 29), date(2025, 2, 28), 24},
+		{date(2000, 2, 29), date(2025, 3, 1), 25},
+		{date(2000, 2, 29), date(2024, 2, 29), 24},
+		{date(2025, 1, 1), date(2024, 12, 31), 0},
+	}
+	for _, tc := range tests {
+		assert.Equal(t, tc.want, AgeOn(tc.dateOfBirth, tc.on), "%s on %s", tc.dateOfBirth, tc.on)
+	}
+}
+
+func TestBornBy(t *testing.T) {
+	for _, on := range []time.Time{date(2024, 2, 29), date(2025, 2, 28), date(2025, 3, 1), date(2025, 12, 31)} {
+		for _, age := range []uint{1, 4, 18} {
+			bornBy := BornBy(age, on)
+			assert.Equal(t, age, AgeOn(bornBy, on), "latest birth for %d on %s", age, on)
+			assert.Equal(t, age-1, AgeOn(bornBy.AddDate(0, 0, 1), on), "day after the latest birth for %d on %s", age, on)
+		}
+	}
+}
+
+func TestValidate_AgeOrDateOfBirth(t *testing.T) {
+	user := User{Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com"}
+	assert.EqualError(t, user.Validate(), "date_of_birth is required")
+
+	user.Age = 151
+	assert.EqualError(t, user.Validate(), "age must be between 1 and 150")
+
+	user.Age = 36
+	assert.NoError(t, user.Validate())
+
+	user.Age = 0
+	user.DateOfBirth = date(1989, 12, 10)
+	assert.NoError(t, user.Validate())
+}
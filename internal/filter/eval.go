@@ -0,0 +1,215 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Column maps a field to a database column. Convert, if set, converts the
+// field's values (string, int64, time.Time or uuid.UUID) to the column's
+// representation. Condition, if set, replaces the comparison with the
+// column for fields that do not map to one column: it returns the condition
+// comparing the field with value by op (= != < <= > >=), with ? placeholders
+// and their arguments.
+type Column struct {
+	Name      string
+	Convert   func(value any) any
+	Condition func(op string, value any) (string, []any)
+}
+
+// SQL returns the filter as a SQL condition with ? placeholders and their
+// arguments; values never become part of the SQL text. It returns "" for
+// the nil Filter. Every field of the filter must be in columns.
+func (f *Filter) SQL(columns map[string]Column) (string, []any, error) {
+	if f == nil {
+		return "", nil, nil
+	}
+	var b strings.Builder
+	var args []any
+	if err := writeSQL(&b, &args, f.root, columns); err != nil {
+		return "", nil, err
+	}
+	return b.String(), args, nil
+}
+
+func writeSQL(b *strings.Builder, args *[]any, n node, columns map[string]Column) error {
+	binary := func(op string, left, right node) error {
+		b.WriteString("(")
+		if err := writeSQL(b, args, left, columns); err != nil {
+			return err
+		}
+		b.WriteString(" " + op + " ")
+		if err := writeSQL(b, args, right, columns); err != nil {
+			return err
+		}
+		b.WriteString(")")
+		return nil
+	}
+
+	switch n := n.(type) {
+	case *and:
+		return binary("AND", n.left, n.right)
+	case *or:
+		return binary("OR", n.left, n.right)
+	case *not:
+		b.WriteString("NOT ")
+		if _, ok := n.operand.(*restriction); ok {
+			b.WriteString("(")
+			defer b.WriteString(")")
+		}
+		return writeSQL(b, args, n.operand, columns)
+	case *restriction:
+		col, ok := columns[n.field]
+		if !ok {
+			return fmt.Errorf("filter: no column for field %q", n.field)
+		}
+		if n.pattern != nil {
+			op := "LIKE"
+			if n.fold {
+				op = "ILIKE"
+			}
+			if n.op == "!=" {
+				op = "NOT " + op
+			}
+			fmt.Fprintf(b, `%s %s ? ESCAPE '\'`, col.Name, op)
+			*args = append(*args, likePattern(n.value.(string)))
+			return nil
+		}
+		if col.Condition != nil {
+			condition, condArgs := col.Condition(n.op, n.value)
+			b.WriteString("(" + condition + ")")
+			*args = append(*args, condArgs...)
+			return nil
+		}
+		value := n.value
+		if col.Convert != nil {
+			value = col.Convert(value)
+		}
+		fmt.Fprintf(b, "%s %s ?", col.Name, n.op)
+		*args = append(*args, value)
+		return nil
+	}
+	return fmt.Errorf("filter: unexpected node %T", n)
+}
+
+// likePattern turns a value with * wildcards into a LIKE pattern.
+func likePattern(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return strings.ReplaceAll(value, "*", "%")
+}
+
+// Match reports whether the values returned by get satisfy the filter. get
+// returns the value of a field in the Go type of its Type: a string, an
+// int64, a time.Time or a uuid.UUID, or nil if the field has no value. As
+// with SQL NULL, restrictions on a missing value are neither true nor false,
+// and so are their negations, so that Match agrees with SQL. The nil Filter
+// matches everything.
+func (f *Filter) Match(get func(field string) any) bool {
+	return f == nil || match(f.root, get) == yes
+}
+
+// truth is a value of SQL's three-valued logic.
+type truth int8
+
+const (
+	no truth = iota
+	yes
+	unknown
+)
+
+func truthOf(b bool) truth {
+	if b {
+		return yes
+	}
+	return no
+}
+
+func match(n node, get func(string) any) truth {
+	switch n := n.(type) {
+	case *and:
+		left, right := match(n.left, get), match(n.right, get)
+		switch {
+		case left == no || right == no:
+			return no
+		case left == unknown || right == unknown:
+			return unknown
+		}
+		return yes
+	case *or:
+		left, right := match(n.left, get), match(n.right, get)
+		switch {
+		case left == yes || right == yes:
+			return yes
+		case left == unknown || right == unknown:
+			return unknown
+		}
+		return no
+	case *not:
+		switch match(n.operand, get) {
+		case yes:
+			return no
+		case no:
+			return yes
+		}
+		return unknown
+	case *restriction:
+		actual := get(n.field)
+		if actual == nil {
+			return unknown
+		}
+		if n.pattern != nil {
+			s, _ := actual.(string)
+			return truthOf(n.pattern.MatchString(s) == (n.op != "!="))
+		}
+		c, ok := compare(actual, n.value)
+		if !ok {
+			return no
+		}
+		switch n.op {
+		case "=":
+			return truthOf(c == 0)
+		case "!=":
+			return truthOf(c != 0)
+		case "<":
+			return truthOf(c < 0)
+		case "<=":
+			return truthOf(c <= 0)
+		case ">":
+			return truthOf(c > 0)
+		case ">=":
+			return truthOf(c >= 0)
+		}
+	}
+	return no
+}
+
+// compare orders two values of the same field type; ok is false if actual
+// has a different type.
+func compare(actual, value any) (int, bool) {
+	switch v := value.(type) {
+	case string:
+		a, ok := actual.(string)
+		return strings.Compare(a, v), ok
+	case int64:
+		a, ok := actual.(int64)
+		switch {
+		case !ok:
+			return 0, false
+		case a < v:
+			return -1, true
+		case a > v:
+			return 1, true
+		}
+		return 0, true
+	case time.Time:
+		a, ok := actual.(time.Time)
+		return a.Compare(v), ok
+	case uuid.UUID:
+		a, ok := actual.(uuid.UUID)
+		return strings.Compare(a.String(), v.String()), ok
+	}
+	return 0, false
+}
@@ -0,0 +1,320 @@
+// Package filter parses filter expressions in the syntax of AIP-160
+// (https://google.aip.dev/160), such as
+//
+//	age >= 18 AND email:"*@example.com" AND created > "2025-01-01T00:00:00Z"
+//
+// and evaluates them as parameterized SQL or against values in memory.
+//
+// Restrictions compare a field with a value using =, !=, <, <=, >, >= or :.
+// Restrictions are combined with AND, OR (which binds tighter than AND, as
+// in AIP-160), NOT or - and parentheses; restrictions separated only by
+// whitespace are ANDed. In text values * matches any sequence of characters
+// when comparing with =, != or :, and : also ignores case. Timestamps are
+// RFC 3339 strings and dates are strings like "2000-01-31".
+package filter
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits of Parse, which keep evaluation cheap for untrusted input.
+const (
+	MaxLength = 2000
+	maxDepth  = 32
+)
+
+// Type is the type of a field.
+type Type int
+
+const (
+	String    Type = iota // compared as text, with wildcards
+	Int                   // an integer
+	Timestamp             // an RFC 3339 time
+	UUID                  // a UUID; only = and != apply
+	Date                  // a calendar date like "2000-01-31", as midnight UTC
+)
+
+// Fields declares the fields a filter may refer to and their types.
+type Fields map[string]Type
+
+// Error is a syntax or type error in a filter, located at the 1-based
+// column of the offending token.
+type Error struct {
+	Pos int
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("invalid filter at column %d: %s", e.Pos, e.Msg)
+}
+
+func errorf(pos int, format string, args ...any) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// Filter is a parsed filter expression. The nil Filter matches everything.
+type Filter struct {
+	root node
+}
+
+// node is an element of the expression tree: *and, *or, *not or *restriction.
+type node interface{}
+
+type and struct{ left, right node }
+
+type or struct{ left, right node }
+
+type not struct{ operand node }
+
+// restriction compares a field with a value of the field's type: a string,
+// an int64, a time.Time (for timestamps and dates) or a uuid.UUID.
+type restriction struct {
+	field string
+	typ   Type
+	op    string // one of = != < <= > >=; : is mapped to = or, with fold set, kept
+	value any
+	// For text values with wildcards or compared with :, pattern matches the
+	// whole value and fold ignores case
+	pattern *regexp.Regexp
+	fold    bool
+}
+
+// Parse parses src, allowing only the given fields. An empty src yields
+// the nil Filter. Errors are of type *Error.
+func Parse(src string, fields Fields) (*Filter, error) {
+	if len(src) > MaxLength {
+		return nil, errorf(MaxLength+1, "filter must not be longer than %d characters", MaxLength)
+	}
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	if tokens[0].kind == tokEOF {
+		return nil, nil
+	}
+	p := &parser{tokens: tokens, fields: fields}
+	root, err := p.expression(0)
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		if t.kind == tokRParen {
+			return nil, errorf(t.pos, "unbalanced \")\"")
+		}
+		return nil, errorf(t.pos, "unexpected %s", t.describe())
+	}
+	return &Filter{root: root}, nil
+}
+
+// parser is a recursive descent parser over the tokens of a filter.
+type parser struct {
+	tokens []token
+	next   int
+	fields Fields
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) take() token {
+	t := p.tokens[p.next]
+	if t.kind != tokEOF {
+		p.next++
+	}
+	return t
+}
+
+// expression := sequence { "AND" sequence }
+func (p *parser) expression(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, errorf(p.peek().pos, "filter is nested too deeply")
+	}
+	left, err := p.sequence(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.take()
+		right, err := p.sequence(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &and{left, right}
+	}
+	return left, nil
+}
+
+// sequence := factor { factor }, implicitly ANDed
+func (p *parser) sequence(depth int) (node, error) {
+	left, err := p.factor(depth)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokText, tokString, tokNot, tokMinus, tokLParen:
+			right, err := p.factor(depth)
+			if err != nil {
+				return nil, err
+			}
+			left = &and{left, right}
+		default:
+			return left, nil
+		}
+	}
+}
+
+// factor := term { "OR" term }
+func (p *parser) factor(depth int) (node, error) {
+	left, err := p.term(depth)
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.take()
+		right, err := p.term(depth)
+		if err != nil {
+			return nil, err
+		}
+		left = &or{left, right}
+	}
+	return left, nil
+}
+
+// term := [ "NOT" | "-" ] simple
+// simple := "(" expression ")" | restriction
+func (p *parser) term(depth int) (node, error) {
+	if depth > maxDepth {
+		return nil, errorf(p.peek().pos, "filter is nested too deeply")
+	}
+	if k := p.peek().kind; k == tokNot || k == tokMinus {
+		p.take()
+		operand, err := p.term(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		return &not{operand}, nil
+	}
+
+	if t := p.peek(); t.kind == tokLParen {
+		p.take()
+		inner, err := p.expression(depth + 1)
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.take(); closing.kind != tokRParen {
+			return nil, errorf(closing.pos, "expected \")\" to close the \"(\" at column %d, got %s", t.pos, closing.describe())
+		}
+		return inner, nil
+	}
+	return p.restriction()
+}
+
+// restriction := field comparator value
+func (p *parser) restriction() (node, error) {
+	field := p.take()
+	if field.kind != tokText {
+		return nil, errorf(field.pos, "expected a field name, got %s", field.describe())
+	}
+	typ, ok := p.fields[field.text]
+	if !ok {
+		return nil, errorf(field.pos, "unknown field %q, expected one of %s", field.text, p.fieldNames())
+	}
+
+	op := p.take()
+	if op.kind != tokComparator {
+		return nil, errorf(op.pos, "expected a comparison operator after %q, got %s", field.text, op.describe())
+	}
+
+	value := p.take()
+	text := value.text
+	switch value.kind {
+	case tokString, tokText:
+	case tokMinus:
+		// A negative number
+		number := p.take()
+		if number.kind != tokText {
+			return nil, errorf(number.pos, "expected a number after \"-\", got %s", number.describe())
+		}
+		text = "-" + number.text
+	default:
+		return nil, errorf(value.pos, "expected a value after %q, got %s", op.text, value.describe())
+	}
+
+	r := &restriction{field: field.text, typ: typ, op: op.text}
+	if err := r.setValue(text, value.pos); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// setValue converts text to the field's type and validates the operator.
+func (r *restriction) setValue(text string, pos int) error {
+	switch r.typ {
+	case String:
+		wildcard := strings.Contains(text, "*")
+		if r.op == ":" {
+			r.fold = true
+		}
+		if wildcard || r.fold {
+			if r.op != "=" && r.op != "!=" && r.op != ":" {
+				return errorf(pos, "wildcards are only supported with =, != and :")
+			}
+			prefix := ""
+			if r.fold {
+				prefix = "(?i)"
+			}
+			r.pattern = regexp.MustCompile(prefix + "^" + strings.ReplaceAll(regexp.QuoteMeta(text), `\*`, ".*") + "$")
+		}
+		r.value = text
+	case Int:
+		n, err := strconv.ParseInt(text, 10, 64)
+		if err != nil {
+			return errorf(pos, "%s expects an integer, got %q", r.field, text)
+		}
+		r.value = n
+	case Timestamp:
+		t, err := time.Parse(time.RFC3339, text)
+		if err != nil {
+			return errorf(pos, "%s expects an RFC 3339 timestamp like \"2025-01-01T00:00:00Z\", got %q", r.field, text)
+		}
+		r.value = t
+	case Date:
+		t, err := time.Parse(time.DateOnly, text)
+		if err != nil {
+			return errorf(pos, "%s expects a date like \"2000-01-31\", got %q", r.field, text)
+		}
+		r.value = t
+	case UUID:
+		id, err := uuid.Parse(text)
+		if err != nil {
+			return errorf(pos, "%s expects a UUID, got %q", r.field, text)
+		}
+		if r.op != "=" && r.op != "!=" && r.op != ":" {
+			return errorf(pos, "%s only supports = and !=", r.field)
+		}
+		r.value = id
+	}
+	if r.op == ":" && !r.fold {
+		r.op = "="
+	}
+	return nil
+}
+
+// fieldNames lists the allowed fields for error messages.
+func (p *parser) fieldNames() string {
+	names := make([]string, 0, len(p.fields))
+	for name := range p.fields {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,195 @@
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFields = Fields{
+	"id":      UUID,
+	"name":    String,
+	"email":   String,
+	"age":     Int,
+	"created": Timestamp,
+	"born":    Date,
+}
+
+var testColumns = map[string]Column{
+	"id":      {Name: "id"},
+	"name":    {Name: "name"},
+	"email":   {Name: "email"},
+	"age":     {Name: "age"},
+	"created": {Name: "created", Convert: func(v any) any { return v.(time.Time).Unix() }},
+	"born":    {Name: "born"},
+}
+
+func TestParse_SQL(t *testing.T) {
+	tests := []struct {
+		filter string
+		sql    string
+		args   []any
+	}{
+		{`age >= 18`, `age >= ?`, []any{int64(18)}},
+		{`age >= 18 AND email:"*@example.com"`, `(age >= ? AND email ILIKE ? ESCAPE '\')`, []any{int64(18), "%@example.com"}},
+		{`created > "2025-01-01T00:00:00Z"`, `created > ?`, []any{int64(1735689600)}},
+		{`born <= "2000-01-31"`, `born <= ?`, []any{time.Date(2000, 1, 31, 0, 0, 0, 0, time.UTC)}},
+		// OR binds tighter than AND, and whitespace means AND
+		{`age = 1 AND age = 2 OR age = 3`, `(age = ? AND (age = ? OR age = ?))`, []any{int64(1), int64(2), int64(3)}},
+		{`(age = 1 AND age = 2) OR age = 3`, `((age = ? AND age = ?) OR age = ?)`, []any{int64(1), int64(2), int64(3)}},
+		{`name = Ada age < 40`, `(name = ? AND age < ?)`, []any{"Ada", int64(40)}},
+		{`NOT name = "x" -age > -1`, `(NOT (name = ?) AND NOT (age > ?))`, []any{"x", int64(-1)}},
+		{`name != "100%_*"`, `name NOT LIKE ? ESCAPE '\'`, []any{`100\%\_%`}},
+		{`name = "O'Brien; DROP TABLE users"`, `name = ?`, []any{"O'Brien; DROP TABLE users"}},
+		{`id:"7b4e1b5e-2f37-4a39-9f4e-6a3b7f1d2c11"`, `id = ?`, []any{uuid.MustParse("7b4e1b5e-2f37-4a39-9f4e-6a3b7f1d2c11")}},
+	}
+	for _, tc := range tests {
+		f, err := Parse(tc.filter, testFields)
+		require.NoError(t, err, tc.filter)
+
+		sql, args, err := f.SQL(testColumns)
+
+		require.NoError(t, err, tc.filter)
+		assert.Equal(t, tc.sql, sql, tc.filter)
+		assert.Equal(t, tc.args, args, tc.filter)
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	f, err := Parse("  ", testFields)
+
+	require.NoError(t, err)
+	assert.Nil(t, f)
+	sql, args, err := f.SQL(testColumns)
+	require.NoError(t, err)
+	assert.Empty(t, sql)
+	assert.Empty(t, args)
+	assert.True(t, f.Match(func(string) any { return nil }))
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		filter string
+		pos    int
+		msg    string
+	}{
+		{`agee >= 18`, 1, `unknown field "agee", expected one of age, born, created, email, id, name`},
+		{`age >= eighteen`, 8, `age expects an integer, got "eighteen"`},
+		{`age >= 18 AND`, 14, `expected a field name, got end of filter`},
+		{`age 18`, 5, `expected a comparison operator after "age", got "18"`},
+		{`(age = 1`, 9, `expected ")" to close the "(" at column 1, got end of filter`},
+		{`age = 1)`, 8, `unbalanced ")"`},
+		{`name = "Ada`, 8, `unterminated string`},
+		{`created > "yesterday"`, 11, `created expects an RFC 3339 timestamp like "2025-01-01T00:00:00Z", got "yesterday"`},
+		{`born = "31.01.2000"`, 8, `born expects a date like "2000-01-31", got "31.01.2000"`},
+		{`name < "A*"`, 8, `wildcards are only supported with =, != and :`},
+		{`id > "7b4e1b5e-2f37-4a39-9f4e-6a3b7f1d2c11"`, 6, `id only supports = and !=`},
+		{`age ! 1`, 5, `unexpected "!", did you mean "!="?`},
+		{`age =`, 6, `expected a value after "=", got end of filter`},
+	}
+	for _, tc := range tests {
+		_, err := Parse(tc.filter, testFields)
+
+		var filterErr *Error
+		require.ErrorAs(t, err, &filterErr, tc.filter)
+		assert.Equal(t, tc.pos, filterErr.Pos, tc.filter)
+		assert.Equal(t, tc.msg, filterErr.Msg, tc.filter)
+	}
+}
+
+func TestParse_Limits(t *testing.T) {
+	deep := ""
+	for i := 0; i < 100; i++ {
+		deep += "NOT "
+	}
+	_, err := Parse(deep+"age = 1", testFields)
+	assert.ErrorContains(t, err, "nested too deeply")
+
+	long := make([]byte, MaxLength+1)
+	for i := range long {
+		long[i] = ' '
+	}
+	_, err = Parse(string(long), testFields)
+	assert.ErrorContains(t, err, "must not be longer")
+}
+
+func TestMatch(t *testing.T) {
+	id := uuid.New()
+	values := map[string]any{
+		"id":      id,
+		"name":    "Ada",
+		"email":   "Ada@Example.com",
+		"age":     int64(36),
+		"created": time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC),
+		"born":    time.Date(1989, 12, 10, 0, 0, 0, 0, time.UTC),
+	}
+	get := func(field string) any { return values[field] }
+
+	for filter, want := range map[string]bool{
+		`age >= 18 AND email:"*@example.com"`:   true,
+		`email = "*@example.com"`:               false,
+		`email = "*@Example.com"`:               true,
+		`created > "2025-01-01T00:00:00Z"`:      true,
+		`created < "2025-01-01T00:00:00+01:00"`: false,
+		`born = "1989-12-10"`:                   true,
+		`born > "1990-01-01"`:                   false,
+		`name = Bob OR name = Ada`:              true,
+		`age < 30 OR name = Bob`:                false,
+		`-name = Ada`:                           false,
+		`NOT (age < 30 OR name = Bob)`:          true,
+		`name != "A*"`:                          false,
+		`id = "` + id.String() + `"`:            true,
+		`id != "` + id.String() + `"`:           false,
+	} {
+		f, err := Parse(filter, testFields)
+		require.NoError(t, err, filter)
+		assert.Equal(t, want, f.Match(get), filter)
+	}
+}
+
+// A missing value behaves like SQL NULL: restrictions on it and their
+// negations are unknown and don't match, while OR and AND decide as in SQL.
+func TestMatch_MissingValue(t *testing.T) {
+	get := func(field string) any {
+		if field == "age" {
+			return int64(36)
+		}
+		return nil
+	}
+
+	for filter, want := range map[string]bool{
+		`born < "2000-01-01"`:                    false,
+		`NOT born < "2000-01-01"`:                false,
+		`born != "2000-01-01"`:                   false,
+		`name = "*"`:                             false,
+		`NOT name = "A*"`:                        false,
+		`born < "2000-01-01" OR age = 36`:        true,
+		`NOT (born < "2000-01-01" OR age = 1)`:   false,
+		`NOT (born < "2000-01-01" AND age = 1)`:  true,
+		`NOT (born < "2000-01-01" AND age = 36)`: false,
+	} {
+		f, err := Parse(filter, testFields)
+		require.NoError(t, err, filter)
+		assert.Equal(t, want, f.Match(get), filter)
+	}
+}
+
+func TestSQL_Condition(t *testing.T) {
+	columns := map[string]Column{
+		"age": {Condition: func(op string, value any) (string, []any) {
+			return "COALESCE(born " + op + " ?, age " + op + " ?)", []any{"born", value}
+		}},
+		"name": {Name: "name"},
+	}
+	f, err := Parse(`NOT age >= 18 OR name = Ada`, testFields)
+	require.NoError(t, err)
+
+	sql, args, err := f.SQL(columns)
+
+	require.NoError(t, err)
+	assert.Equal(t, `(NOT ((COALESCE(born >= ?, age >= ?))) OR name = ?)`, sql)
+	assert.Equal(t, []any{"born", int64(18), "Ada"}, args)
+}
@@ -0,0 +1,141 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// kind is the kind of a token.
+type kind int
+
+const (
+	tokEOF kind = iota
+	tokText
+	tokString
+	tokComparator
+	tokAnd
+	tokOr
+	tokNot
+	tokMinus
+	tokLParen
+	tokRParen
+)
+
+// token is a lexeme of a filter together with its position, the 1-based
+// column of its first character.
+type token struct {
+	kind kind
+	text string // for strings the unquoted value
+	pos  int
+}
+
+// describe names the token in error messages.
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of filter"
+	case tokString:
+		return strconv.Quote(t.text)
+	default:
+		return "\"" + t.text + "\""
+	}
+}
+
+// lex splits src into tokens, ending with tokEOF.
+func lex(src string) ([]token, error) {
+	var tokens []token
+	col := 1
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		start, startCol := i, col
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			col++
+			continue
+		case r == '(' || r == ')':
+			k := tokLParen
+			if r == ')' {
+				k = tokRParen
+			}
+			tokens = append(tokens, token{kind: k, text: string(r), pos: startCol})
+			i++
+			col++
+			continue
+		case r == '-':
+			tokens = append(tokens, token{kind: tokMinus, text: "-", pos: startCol})
+			i++
+			col++
+			continue
+		case strings.ContainsRune("<>=!:", r):
+			op := string(r)
+			if next := i + 1; next < len(src) && src[next] == '=' && r != '=' && r != ':' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errorf(startCol, "unexpected \"!\", did you mean \"!=\"?")
+			}
+			tokens = append(tokens, token{kind: tokComparator, text: op, pos: startCol})
+			i += len(op)
+			col += len(op)
+			continue
+		case r == '"' || r == '\'':
+			value, n, runes, err := lexString(src[i:], startCol)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: value, pos: startCol})
+			i += n
+			col += runes
+			continue
+		}
+
+		// Anything else runs up to the next space, parenthesis, comparator or quote
+		for i < len(src) {
+			r, size = utf8.DecodeRuneInString(src[i:])
+			if unicode.IsSpace(r) || strings.ContainsRune("()<>=!:\"'", r) {
+				break
+			}
+			i += size
+			col++
+		}
+		text := src[start:i]
+		k := tokText
+		switch text {
+		case "AND":
+			k = tokAnd
+		case "OR":
+			k = tokOr
+		case "NOT":
+			k = tokNot
+		}
+		tokens = append(tokens, token{kind: k, text: text, pos: startCol})
+	}
+	return append(tokens, token{kind: tokEOF, pos: col}), nil
+}
+
+// lexString reads the quoted string at the start of src. It returns the
+// unquoted value and the length of the literal in bytes and in runes.
+// Backslash escapes the next character.
+func lexString(src string, col int) (value string, n, runes int, err error) {
+	quote := src[0]
+	var b strings.Builder
+	n, runes = 1, 1
+	for n < len(src) {
+		r, size := utf8.DecodeRuneInString(src[n:])
+		n += size
+		runes++
+		switch {
+		case r == rune(quote):
+			return b.String(), n, runes, nil
+		case r == '\\' && n < len(src):
+			r, size = utf8.DecodeRuneInString(src[n:])
+			n += size
+			runes++
+		}
+		b.WriteRune(r)
+	}
+	return "", 0, 0, errorf(col, "unterminated string")
+}
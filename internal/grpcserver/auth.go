@@ -0,0 +1,35 @@
+package grpcserver
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/interimme/userapi/internal/auth"
+)
+
+// UnaryAuthInterceptor stores the API key of the "authorization: Bearer <key>"
+// metadata in the context for the use cases to authorize; see auth.WithAPIKey.
+// Calls proxied by the gRPC-Gateway carry the HTTP Authorization header there.
+func UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withAPIKey(ctx), req)
+	}
+}
+
+// StreamAuthInterceptor is the streaming variant of UnaryAuthInterceptor.
+func StreamAuthInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: withAPIKey(ss.Context())})
+	}
+}
+
+func withAPIKey(ctx context.Context) context.Context {
+	if values := metadata.ValueFromIncomingContext(ctx, "authorization"); len(values) > 0 {
+		if key := auth.FromHeader(values[0]); key != "" {
+			return auth.WithAPIKey(ctx, key)
+		}
+	}
+	return ctx
+}
@@ -0,0 +1,157 @@
+package grpcserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/interimme/userapi/internal/infrastructure/db"
+)
+
+// gatewayKeyMetadata is the metadata key carrying the key of the gateway
+// connection; see Callers.GatewayDialOption.
+const gatewayKeyMetadata = "x-userapi-gateway-key"
+
+// Callers identifies the client of every call by its IP address. Only calls
+// of the in-process gRPC-Gateway may name another client in X-Forwarded-For:
+// they carry a random key generated at startup that other clients don't know.
+type Callers struct {
+	gatewayKey     string
+	trustedProxies []netip.Prefix
+}
+
+// NewCallers creates Callers with a new gateway key. trustedProxies lists the
+// IP addresses and CIDR ranges of the proxies in front of the gateway, whose
+// X-Forwarded-For entries are trusted in turn, as the Gin API does.
+func NewCallers(trustedProxies []string) (*Callers, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate gateway key: %w", err)
+	}
+	c := &Callers{gatewayKey: hex.EncodeToString(key)}
+	for _, p := range trustedProxies {
+		prefix, err := parsePrefix(p)
+		if err != nil {
+			return nil, err
+		}
+		c.trustedProxies = append(c.trustedProxies, prefix)
+	}
+	return c, nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+		}
+		return prefix.Masked(), nil
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, fmt.Errorf("invalid trusted proxy %q: %w", s, err)
+	}
+	return netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()), nil
+}
+
+// GatewayDialOption sends the gateway key with every call of the connection;
+// use it for the gRPC-Gateway's connection only.
+func (c *Callers) GatewayDialOption() grpc.DialOption {
+	return grpc.WithPerRPCCredentials(gatewayCredentials(c.gatewayKey))
+}
+
+type gatewayCredentials string
+
+func (k gatewayCredentials) GetRequestMetadata(context.Context, ...string) (map[string]string, error) {
+	return map[string]string{gatewayKeyMetadata: string(k)}, nil
+}
+
+func (gatewayCredentials) RequireTransportSecurity() bool {
+	return false
+}
+
+// UnaryCallerInterceptor identifies the client for the database, so that it
+// reads its own writes when reads go to replicas; see db.WithCaller.
+func UnaryCallerInterceptor(c *Callers) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(c.withCaller(ctx), req)
+	}
+}
+
+// StreamCallerInterceptor is the streaming variant of UnaryCallerInterceptor.
+func StreamCallerInterceptor(c *Callers) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &contextStream{ServerStream: ss, ctx: c.withCaller(ss.Context())})
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// withCaller identifies the client by its IP address. Calls proxied by the
+// gRPC-Gateway carry the HTTP client's address in X-Forwarded-For.
+func (c *Callers) withCaller(ctx context.Context) context.Context {
+	if c.fromGateway(ctx) {
+		if client := c.forwardedFor(metadata.ValueFromIncomingContext(ctx, "x-forwarded-for")); client != "" {
+			return db.WithCaller(ctx, client)
+		}
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		return db.WithCaller(ctx, host)
+	}
+	return ctx
+}
+
+func (c *Callers) fromGateway(ctx context.Context) bool {
+	values := metadata.ValueFromIncomingContext(ctx, gatewayKeyMetadata)
+	return len(values) == 1 && subtle.ConstantTimeCompare([]byte(values[0]), []byte(c.gatewayKey)) == 1
+}
+
+// forwardedFor returns the client named in X-Forwarded-For. The gateway
+// appends the address it was called from, so entries are read from the right
+// and skipped while they belong to trusted proxies.
+func (c *Callers) forwardedFor(values []string) string {
+	var hops []string
+	for _, v := range values {
+		hops = append(hops, strings.Split(v, ",")...)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+		if err != nil {
+			return ""
+		}
+		if i == 0 || !c.trusted(addr) {
+			return addr.String()
+		}
+	}
+	return ""
+}
+
+func (c *Callers) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, p := range c.trustedProxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
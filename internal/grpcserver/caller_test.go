@@ -0,0 +1,49 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+
+	"github.com/interimme/userapi/internal/infrastructure/db"
+)
+
+func TestCallers_WithCaller(t *testing.T) {
+	callers, err := NewCallers([]string{"10.0.0.0/8"})
+	require.NoError(t, err)
+	gatewayKey := callers.gatewayKey
+
+	incoming := func(pairs ...string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 50000}})
+		return metadata.NewIncomingContext(ctx, metadata.Pairs(pairs...))
+	}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		want string
+	}{
+		{"direct call", incoming(), "127.0.0.1"},
+		{"forged X-Forwarded-For", incoming("x-forwarded-for", "203.0.113.7"), "127.0.0.1"},
+		{"wrong gateway key", incoming("x-forwarded-for", "203.0.113.7", gatewayKeyMetadata, "guess"), "127.0.0.1"},
+		{"gateway", incoming("x-forwarded-for", "203.0.113.7", gatewayKeyMetadata, gatewayKey), "203.0.113.7"},
+		{"gateway behind trusted proxy", incoming("x-forwarded-for", "198.51.100.1, 203.0.113.7, 10.1.2.3", gatewayKeyMetadata, gatewayKey), "203.0.113.7"},
+		{"gateway with invalid entry", incoming("x-forwarded-for", "unknown", gatewayKeyMetadata, gatewayKey), "127.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, db.Caller(callers.withCaller(tt.ctx)))
+		})
+	}
+}
+
+func TestNewCallers_InvalidProxy(t *testing.T) {
+	_, err := NewCallers([]string{"proxy.internal"})
+
+	assert.ErrorContains(t, err, `invalid trusted proxy "proxy.internal"`)
+}
@@ -34,37 +34,28 @@ func (s *Server) CreateUser(ctx context.Context, req *userapi.CreateUserRequest)
 		return nil, status.Error(codes.InvalidArgument, "user data is required")
 	}
 
+	// Parse the date of birth.
+	dateOfBirth, err := entity.ParseDate(req.GetUser().GetDateOfBirth())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// Convert Proto User to Entity User.
 	user := &entity.User{
-		Firstname: req.GetUser().GetFirstname(),
-		Lastname:  req.GetUser().GetLastname(),
-		Email:     req.GetUser().GetEmail(),
-		Age:       uint(req.GetUser().GetAge()), // Safe conversion as age is small.
-		// ID and Created are set by the usecase.
+		Firstname:   req.GetUser().GetFirstname(),
+		Lastname:    req.GetUser().GetLastname(),
+		Email:       req.GetUser().GetEmail(),
+		Age:         uint(req.GetUser().GetAge()), // Safe conversion as age is small.
+		DateOfBirth: dateOfBirth,
+		// ID is set by the usecase, Created and Updated by the repository.
 	}
 
 	// Call the usecase to create the user.
-	if err := s.UserUseCase.CreateUser(user); err != nil {
-		// Map internal errors to gRPC status codes using a type switch.
-		switch e := err.(type) {
-		case *apperrors.AppError:
-			return nil, status.Error(e.Code, e.Message)
-		default:
-			return nil, status.Error(codes.Internal, "internal server error")
-		}
-	}
-
-	// Convert Entity User back to Proto User.
-	respUser := &userapi.User{
-		Id:        user.ID.String(),
-		Firstname: user.Firstname,
-		Lastname:  user.Lastname,
-		Email:     user.Email,
-		Age:       uint32(user.Age),              // Convert back to uint32 for Proto.
-		Created:   timestamppb.New(user.Created), // Proper Timestamp handling.
+	if err := s.UserUseCase.CreateUser(ctx, user); err != nil {
+		return nil, toStatus(err)
 	}
 
-	return &userapi.CreateUserResponse{User: respUser}, nil
+	return &userapi.CreateUserResponse{User: toProtoUser(user)}, nil
 }
 
 // GetUser implements the GetUser RPC method.
@@ -81,28 +72,12 @@ func (s *Server) GetUser(ctx context.Context, req *userapi.GetUserRequest) (*use
 	}
 
 	// Call the usecase to retrieve the user.
-	user, err := s.UserUseCase.GetUser(userID)
-	if err != nil {
-		// Map internal errors to gRPC status codes using a type switch.
-		switch e := err.(type) {
-		case *apperrors.AppError:
-			return nil, status.Error(e.Code, e.Message)
-		default:
-			return nil, status.Error(codes.Internal, "internal server error")
-		}
-	}
-
-	// Convert Entity User back to Proto User.
-	respUser := &userapi.User{
-		Id:        user.ID.String(),
-		Firstname: user.Firstname,
-		Lastname:  user.Lastname,
-		Email:     user.Email,
-		Age:       uint32(user.Age),              // Convert back to uint32 for Proto.
-		Created:   timestamppb.New(user.Created), // Proper Timestamp handling.
+	user, err := s.UserUseCase.GetUser(ctx, userID)
+	if err != nil {
+		return nil, toStatus(err)
 	}
 
-	return &userapi.GetUserResponse{User: respUser}, nil
+	return &userapi.GetUserResponse{User: toProtoUser(user)}, nil
 }
 
 // UpdateUser implements the UpdateUser RPC method.
@@ -121,49 +96,35 @@ func (s *Server) UpdateUser(ctx context.Context, req *userapi.UpdateUserRequest)
 		return nil, status.Errorf(codes.InvalidArgument, "invalid user ID format: %v", err)
 	}
 
+	// Parse the date of birth.
+	dateOfBirth, err := entity.ParseDate(req.GetUser().GetDateOfBirth())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
 	// Convert Proto User to Entity User.
 	user := &entity.User{
-		ID:        userID, // Ensure the ID is set from the request.
-		Firstname: req.GetUser().GetFirstname(),
-		Lastname:  req.GetUser().GetLastname(),
-		Email:     req.GetUser().GetEmail(),
-		Age:       uint(req.GetUser().GetAge()), // Safe conversion as age is small.
-		// Created remains unchanged.
+		ID:          userID, // Ensure the ID is set from the request.
+		Firstname:   req.GetUser().GetFirstname(),
+		Lastname:    req.GetUser().GetLastname(),
+		Email:       req.GetUser().GetEmail(),
+		Age:         uint(req.GetUser().GetAge()), // Safe conversion as age is small.
+		DateOfBirth: dateOfBirth,
+		// Created remains unchanged; Updated is set by the repository.
 	}
 
 	// Call the usecase to update the user.
-	if err := s.UserUseCase.UpdateUser(user); err != nil {
-		// Map internal errors to gRPC status codes using a type switch.
-		switch e := err.(type) {
-		case *apperrors.AppError:
-			return nil, status.Error(e.Code, e.Message)
-		default:
-			return nil, status.Error(codes.Internal, "internal server error")
-		}
+	if err := s.UserUseCase.UpdateUser(ctx, user); err != nil {
+		return nil, toStatus(err)
 	}
 
-	// Retrieve the updated user to include the 'created' timestamp.
-	updatedUser, err := s.UserUseCase.GetUser(userID)
+	// Retrieve the updated user to include the timestamps.
+	updatedUser, err := s.UserUseCase.GetUser(ctx, userID)
 	if err != nil {
-		switch e := err.(type) {
-		case *apperrors.AppError:
-			return nil, status.Error(e.Code, e.Message)
-		default:
-			return nil, status.Error(codes.Internal, "internal server error")
-		}
+		return nil, toStatus(err)
 	}
 
-	// Convert Entity User back to Proto User.
-	respUser := &userapi.User{
-		Id:        updatedUser.ID.String(),
-		Firstname: updatedUser.Firstname,
-		Lastname:  updatedUser.Lastname,
-		Email:     updatedUser.Email,
-		Age:       uint32(updatedUser.Age),              // Convert back to uint32 for Proto.
-		Created:   timestamppb.New(updatedUser.Created), // Proper Timestamp handling.
-	}
-
-	return &userapi.UpdateUserResponse{User: respUser}, nil
+	return &userapi.UpdateUserResponse{User: toProtoUser(updatedUser)}, nil
 }
 
 // DeleteUser implements the DeleteUser RPC method.
@@ -180,15 +141,200 @@ func (s *Server) DeleteUser(ctx context.Context, req *userapi.DeleteUserRequest)
 	}
 
 	// Call the usecase to delete the user.
-	if err := s.UserUseCase.DeleteUser(userID); err != nil {
-		// Map internal errors to gRPC status codes using a type switch.
-		switch e := err.(type) {
-		case *apperrors.AppError:
-			return nil, status.Error(e.Code, e.Message)
-		default:
-			return nil, status.Error(codes.Internal, "internal server error")
-		}
+	if err := s.UserUseCase.DeleteUser(ctx, userID); err != nil {
+		return nil, toStatus(err)
 	}
 
 	return &userapi.DeleteUserResponse{Message: "User deleted successfully"}, nil
 }
+
+// ListUsers implements the ListUsers RPC method.
+func (s *Server) ListUsers(ctx context.Context, req *userapi.ListUsersRequest) (*userapi.ListUsersResponse, error) {
+	// Parse the page token, the ID of the last user of the previous page.
+	afterID := uuid.Nil
+	if req.GetPageToken() != "" {
+		id, err := uuid.Parse(req.GetPageToken())
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid page token")
+		}
+		afterID = id
+	}
+
+	// Call the usecase to list the users; it validates the filter.
+	limit := int(req.GetPageSize())
+	users, err := s.UserUseCase.ListUsers(ctx, req.GetFilter(), afterID, limit)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// Convert the users to Proto.
+	resp := &userapi.ListUsersResponse{Users: make([]*userapi.User, len(users))}
+	for i, u := range users {
+		resp.Users[i] = toProtoUser(u)
+	}
+	// A full page may be followed by more users.
+	if len(users) > 0 && len(users) == usecase.ListLimit(limit) {
+		resp.NextPageToken = users[len(users)-1].ID.String()
+	}
+	return resp, nil
+}
+
+// SearchUsers implements the SearchUsers RPC method.
+func (s *Server) SearchUsers(ctx context.Context, req *userapi.SearchUsersRequest) (*userapi.SearchUsersResponse, error) {
+	// Call the usecase to search; it validates the query.
+	matches, err := s.UserUseCase.SearchUsers(ctx, req.GetQuery(), int(req.GetPageSize()))
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	// Convert the matches to Proto.
+	results := make([]*userapi.UserMatch, len(matches))
+	for i, m := range matches {
+		results[i] = &userapi.UserMatch{
+			User:       toProtoUser(m.User),
+			Score:      m.Score,
+			Highlights: m.Highlights,
+		}
+	}
+
+	return &userapi.SearchUsersResponse{Results: results}, nil
+}
+
+// BatchGetUsers implements the BatchGetUsers RPC method.
+func (s *Server) BatchGetUsers(ctx context.Context, req *userapi.BatchGetUsersRequest) (*userapi.BatchGetUsersResponse, error) {
+	// Parse the UUIDs.
+	ids, err := parseIDs(req.GetIds())
+	if err != nil {
+		return nil, err
+	}
+
+	// Call the usecase to retrieve the users; it checks the batch size.
+	results, err := s.UserUseCase.BatchGetUsers(ctx, ids)
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userapi.BatchGetUsersResponse{Results: protoResults(results)}, nil
+}
+
+// BatchCreateUsers implements the BatchCreateUsers RPC method.
+func (s *Server) BatchCreateUsers(ctx context.Context, req *userapi.BatchCreateUsersRequest) (*userapi.BatchCreateUsersResponse, error) {
+	// Convert Proto Users to Entity Users.
+	users := make([]*entity.User, len(req.GetUsers()))
+	for i, u := range req.GetUsers() {
+		dateOfBirth, err := entity.ParseDate(u.GetDateOfBirth())
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "users[%d]: %v", i, err)
+		}
+		users[i] = &entity.User{
+			Firstname:   u.GetFirstname(),
+			Lastname:    u.GetLastname(),
+			Email:       u.GetEmail(),
+			Age:         uint(u.GetAge()),
+			DateOfBirth: dateOfBirth,
+		}
+	}
+
+	// Call the usecase to create the users.
+	results, err := s.UserUseCase.BatchCreateUsers(ctx, users, req.GetAtomic())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userapi.BatchCreateUsersResponse{Results: protoResults(results)}, nil
+}
+
+// BatchDeleteUsers implements the BatchDeleteUsers RPC method.
+func (s *Server) BatchDeleteUsers(ctx context.Context, req *userapi.BatchDeleteUsersRequest) (*userapi.BatchDeleteUsersResponse, error) {
+	// Parse the UUIDs.
+	ids, err := parseIDs(req.GetIds())
+	if err != nil {
+		return nil, err
+	}
+
+	// Call the usecase to delete the users.
+	results, err := s.UserUseCase.BatchDeleteUsers(ctx, ids, req.GetAtomic())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userapi.BatchDeleteUsersResponse{Results: protoResults(results)}, nil
+}
+
+// toStatus maps an error of the use case to a gRPC status error. Errors
+// other than AppErrors become Internal without revealing their message.
+func toStatus(err error) error {
+	return status.Convert(apperrors.FromError(err)).Err()
+}
+
+// parseIDs parses the user IDs of a batch request.
+func parseIDs(values []string) ([]uuid.UUID, error) {
+	ids := make([]uuid.UUID, len(values))
+	for i, v := range values {
+		id, err := uuid.Parse(v)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "ids[%d]: invalid user ID format: %v", i, err)
+		}
+		ids[i] = id
+	}
+	return ids, nil
+}
+
+// protoResults converts the results of a batch use case to Proto.
+func protoResults(results []*entity.UserResult) []*userapi.UserResult {
+	converted := make([]*userapi.UserResult, len(results))
+	for i, r := range results {
+		result := &userapi.UserResult{}
+		if r.ID != uuid.Nil {
+			result.Id = r.ID.String()
+		}
+		switch {
+		case r.Err != nil:
+			result.Result = &userapi.UserResult_Error{Error: status.Convert(apperrors.FromError(r.Err)).Proto()}
+		case r.User != nil:
+			result.Result = &userapi.UserResult_User{User: toProtoUser(r.User)}
+		}
+		converted[i] = result
+	}
+	return converted
+}
+
+// toProtoUser converts an entity user to Proto.
+func toProtoUser(user *entity.User) *userapi.User {
+	return &userapi.User{
+		Id:          user.ID.String(),
+		Firstname:   user.Firstname,
+		Lastname:    user.Lastname,
+		Email:       user.Email,
+		Age:         uint32(user.CurrentAge()), // Convert back to uint32 for Proto.
+		DateOfBirth: entity.FormatDate(user.DateOfBirth),
+		Created:     timestamppb.New(user.Created),
+		Updated:     timestamppb.New(user.Updated),
+	}
+}
+
+// LookupUser implements the LookupUser RPC method.
+func (s *Server) LookupUser(ctx context.Context, req *userapi.LookupUserRequest) (*userapi.LookupUserResponse, error) {
+	// Call the usecase to look the user up; it authorizes the caller first.
+	user, err := s.UserUseCase.LookupUser(ctx, req.GetEmail())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userapi.LookupUserResponse{User: toProtoUser(user)}, nil
+}
+
+// CheckUserExists implements the CheckUserExists RPC method.
+func (s *Server) CheckUserExists(ctx context.Context, req *userapi.CheckUserExistsRequest) (*userapi.CheckUserExistsResponse, error) {
+	// Parse the UUID.
+	userID, err := uuid.Parse(req.GetId())
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid user ID format: %v", err)
+	}
+
+	if err := s.UserUseCase.CheckUserExists(ctx, userID); err != nil {
+		return nil, toStatus(err)
+	}
+
+	return &userapi.CheckUserExistsResponse{}, nil
+}
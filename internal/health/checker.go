@@ -0,0 +1,131 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status values reported by the health endpoints.
+const (
+	StatusOK          = "ok"
+	StatusUnavailable = "unavailable"
+)
+
+// CheckFunc verifies that a dependency is usable.
+type CheckFunc func(ctx context.Context) error
+
+// CheckResult is the outcome of a single dependency check. Errors of
+// dependencies are logged rather than reported, since they can reveal hosts
+// and users to unauthenticated clients.
+type CheckResult struct {
+	Status string `json:"status"`
+}
+
+// Report is the body of the /readyz response.
+type Report struct {
+	Status string                 `json:"status"`
+	Checks map[string]CheckResult `json:"checks"`
+}
+
+// check is a named dependency check.
+type check struct {
+	name string
+	fn   CheckFunc
+}
+
+// Checker serves liveness and readiness endpoints. Readiness requires the
+// server to be accepting traffic and every registered dependency to pass
+// its check within the timeout.
+type Checker struct {
+	timeout time.Duration
+	checks  []check
+
+	mu    sync.RWMutex
+	ready func() bool
+}
+
+// NewChecker creates a Checker whose dependency checks time out after timeout.
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// AddCheck registers a dependency check reported under name.
+func (c *Checker) AddCheck(name string, fn CheckFunc) {
+	c.checks = append(c.checks, check{name: name, fn: fn})
+}
+
+// SetReadyFunc sets the function reporting whether the server accepts traffic,
+// typically lifecycle.Manager.Ready. Until it is set the server counts as not ready.
+func (c *Checker) SetReadyFunc(ready func() bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ready = ready
+}
+
+// Check runs all dependency checks concurrently and returns the combined report.
+func (c *Checker) Check(ctx context.Context) *Report {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	report := &Report{Status: StatusOK, Checks: make(map[string]CheckResult, len(c.checks)+1)}
+
+	c.mu.RLock()
+	ready := c.ready != nil && c.ready()
+	c.mu.RUnlock()
+	if ready {
+		report.Checks["server"] = CheckResult{Status: StatusOK}
+	} else {
+		report.Checks["server"] = CheckResult{Status: StatusUnavailable}
+	}
+
+	results := make([]CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, chk := range c.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = CheckResult{Status: StatusOK}
+			if err := chk.fn(ctx); err != nil {
+				slog.WarnContext(ctx, "Readiness check failed", slog.String("check", chk.name), slog.String("error", err.Error()))
+				results[i] = CheckResult{Status: StatusUnavailable}
+			}
+		}()
+	}
+	wg.Wait()
+
+	for i, chk := range c.checks {
+		report.Checks[chk.name] = results[i]
+	}
+	for _, result := range report.Checks {
+		if result.Status != StatusOK {
+			report.Status = StatusUnavailable
+		}
+	}
+	return report
+}
+
+// Liveness handles /healthz. It only reports that the process is able to serve requests.
+func (c *Checker) Liveness(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// Readiness handles /readyz, returning 503 when any check fails.
+func (c *Checker) Readiness(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	code := http.StatusOK
+	if report.Status != StatusOK {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, report)
+}
+
+func writeJSON(w http.ResponseWriter, code int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,82 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func readyz(t *testing.T, c *Checker) (int, Report) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	c.Readiness(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+	var report Report
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+	return w.Code, report
+}
+
+func TestReadiness_OK(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.SetReadyFunc(func() bool { return true })
+	c.AddCheck("database", func(context.Context) error { return nil })
+
+	code, report := readyz(t, c)
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, StatusOK, report.Status)
+	assert.Equal(t, StatusOK, report.Checks["database"].Status)
+}
+
+func TestReadiness_DependencyDown(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.SetReadyFunc(func() bool { return true })
+	c.AddCheck("database", func(context.Context) error { return errors.New("connection refused") })
+
+	code, report := readyz(t, c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, report.Status)
+	assert.Equal(t, StatusUnavailable, report.Checks["database"].Status)
+	assert.Equal(t, StatusOK, report.Checks["server"].Status)
+}
+
+func TestReadiness_Timeout(t *testing.T) {
+	c := NewChecker(10 * time.Millisecond)
+	c.SetReadyFunc(func() bool { return true })
+	c.AddCheck("database", func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+
+	code, report := readyz(t, c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, report.Checks["database"].Status)
+}
+
+func TestReadiness_ShuttingDown(t *testing.T) {
+	c := NewChecker(time.Second)
+	c.SetReadyFunc(func() bool { return false })
+
+	code, report := readyz(t, c)
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, StatusUnavailable, report.Checks["server"].Status)
+}
+
+func TestLiveness(t *testing.T) {
+	c := NewChecker(time.Second)
+	w := httptest.NewRecorder()
+
+	c.Liveness(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+	assert.Equal(t, http.StatusOK, w.Code)
+}
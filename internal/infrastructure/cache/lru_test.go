@@ -0,0 +1,48 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newLRU[string, int](2)
+	assert.False(t, c.add("a", 1, time.Minute))
+	assert.False(t, c.add("b", 2, time.Minute))
+
+	_, ok := c.get("a") // b is now the least recently used
+	assert.True(t, ok)
+	assert.True(t, c.add("c", 3, time.Minute))
+
+	_, ok = c.get("b")
+	assert.False(t, ok)
+	v, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, v)
+	assert.Equal(t, 2, c.len())
+
+	assert.False(t, c.add("a", 10, time.Minute), "replacing a key does not evict")
+	v, _ = c.get("a")
+	assert.Equal(t, 10, v)
+}
+
+func TestLRU_Expiry(t *testing.T) {
+	now := time.Now()
+	c := newLRU[string, int](10)
+	c.now = func() time.Time { return now }
+
+	c.add("a", 1, time.Second)
+	c.add("b", 2, time.Minute)
+
+	now = now.Add(time.Second)
+	_, ok := c.get("a")
+	assert.False(t, ok)
+	_, ok = c.get("b")
+	assert.True(t, ok)
+	assert.Equal(t, 1, c.len(), "expired entries are dropped when read")
+
+	c.remove("b")
+	assert.Equal(t, 0, c.len())
+}
@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+// unitOfWork invalidates the cache entries of users written in transactions.
+type unitOfWork struct {
+	next  usecase.UnitOfWork
+	cache *userRepository
+}
+
+// NewUnitOfWork wraps next so that users written in its transactions are
+// dropped from users, a repository created by NewUserRepository, once the
+// transaction has ended. Reads within transactions bypass the cache. If users
+// is not a caching repository, next is returned.
+func NewUnitOfWork(next usecase.UnitOfWork, users usecase.UserRepository) usecase.UnitOfWork {
+	c, ok := users.(*userRepository)
+	if !ok {
+		return next
+	}
+	return &unitOfWork{next: next, cache: c}
+}
+
+func (u *unitOfWork) WithinTx(ctx context.Context, fn func(ctx context.Context, repos usecase.Repositories) error) error {
+	// Attempts that were rolled back are invalidated too, which is harmless
+	var written []uuid.UUID
+	defer func() {
+		for _, id := range written {
+			u.cache.invalidate(id)
+		}
+	}()
+	return u.next.WithinTx(ctx, func(ctx context.Context, repos usecase.Repositories) error {
+		repos.Users = &writeRecorder{UserRepository: repos.Users, written: &written}
+		return fn(ctx, repos)
+	})
+}
+
+// writeRecorder records the IDs of the users written through it.
+type writeRecorder struct {
+	usecase.UserRepository
+	written *[]uuid.UUID
+}
+
+func (w *writeRecorder) Create(ctx context.Context, user *entity.User) error {
+	*w.written = append(*w.written, user.ID)
+	return w.UserRepository.Create(ctx, user)
+}
+
+func (w *writeRecorder) Update(ctx context.Context, user *entity.User) error {
+	*w.written = append(*w.written, user.ID)
+	return w.UserRepository.Update(ctx, user)
+}
+
+func (w *writeRecorder) Delete(ctx context.Context, user *entity.User) error {
+	*w.written = append(*w.written, user.ID)
+	return w.UserRepository.Delete(ctx, user)
+}
+
+func (w *writeRecorder) DeleteByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	*w.written = append(*w.written, ids...)
+	return w.UserRepository.DeleteByIDs(ctx, ids)
+}
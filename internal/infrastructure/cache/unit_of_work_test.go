@@ -0,0 +1,51 @@
+package cache
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/usecase"
+	"github.com/interimme/userapi/internal/usecase/mocks"
+)
+
+// txUnitOfWork runs transactions on a separate repository.
+type txUnitOfWork struct {
+	repo usecase.UserRepository
+}
+
+func (u txUnitOfWork) WithinTx(ctx context.Context, fn func(ctx context.Context, repos usecase.Repositories) error) error {
+	return fn(ctx, usecase.Repositories{Users: u.repo})
+}
+
+func TestUnitOfWork_InvalidatesWrittenUsers(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	user := testUser()
+	next.On("GetByID", mock.Anything, user.ID).Return(user, nil).Twice()
+	_, err := repo.GetByID(context.Background(), user.ID)
+	require.NoError(t, err)
+
+	txRepo := new(mocks.UserRepository)
+	txRepo.On("Update", mock.Anything, user).Return(nil)
+	uow := NewUnitOfWork(txUnitOfWork{repo: txRepo}, repo)
+
+	err = uow.WithinTx(context.Background(), func(ctx context.Context, repos usecase.Repositories) error {
+		return repos.Users.Update(ctx, user)
+	})
+	require.NoError(t, err)
+
+	// The next lookup reads the written user again
+	_, err = repo.GetByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	next.AssertExpectations(t)
+	txRepo.AssertExpectations(t)
+}
+
+func TestNewUnitOfWork_WithoutCache(t *testing.T) {
+	next := txUnitOfWork{}
+
+	assert.Equal(t, next, NewUnitOfWork(next, new(mocks.UserRepository)))
+}
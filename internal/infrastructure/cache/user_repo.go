@@ -0,0 +1,252 @@
+// Package cache provides a caching decorator for usecase.UserRepository.
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/sync/singleflight"
+	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/config"
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+// name labels the metrics of the user cache.
+const name = "users"
+
+// Metrics records cache lookups. Implementations must be safe for concurrent use.
+type Metrics interface {
+	CacheHit(cache string)
+	CacheMiss(cache string)
+	CacheEvicted(cache string)
+}
+
+// noopMetrics is used when no Metrics implementation is configured
+type noopMetrics struct{}
+
+func (noopMetrics) CacheHit(string)     {}
+func (noopMetrics) CacheMiss(string)    {}
+func (noopMetrics) CacheEvicted(string) {}
+
+// Option configures the caching repository.
+type Option func(*userRepository)
+
+// WithMetrics records hits, misses and evictions.
+func WithMetrics(m Metrics) Option {
+	return func(r *userRepository) {
+		r.metrics = m
+	}
+}
+
+// userRepository caches GetByID in front of another UserRepository. Misses
+// for the same ID share one lookup, and missing users are remembered for a
+// shorter time. Writes through this repository invalidate the user's entry;
+// other lookups pass through uncached.
+type userRepository struct {
+	next        usecase.UserRepository
+	entries     *lru[uuid.UUID, *entity.User] // nil marks a missing user
+	ttl         time.Duration
+	negativeTTL time.Duration
+	loads       singleflight.Group
+	metrics     Metrics
+
+	// generation counts invalidations. A lookup only stores its result if no
+	// invalidation happened while it ran, since it may have read the old row.
+	mu         sync.Mutex
+	generation uint64
+}
+
+// NewUserRepository wraps next with a cache configured by cfg.
+func NewUserRepository(next usecase.UserRepository, cfg config.CacheConfig, opts ...Option) usecase.UserRepository {
+	r := &userRepository{
+		next:        next,
+		entries:     newLRU[uuid.UUID, *entity.User](cfg.Size),
+		ttl:         cfg.TTL,
+		negativeTTL: cfg.NegativeTTL,
+		metrics:     noopMetrics{},
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	if user, ok := r.entries.get(id); ok {
+		r.metrics.CacheHit(name)
+		if user == nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+		return clone(user), nil
+	}
+	r.metrics.CacheMiss(name)
+
+	// The shared lookup must not fail because the caller that started it went
+	// away, so it keeps the context's values and deadline but not its cancellation
+	results := r.loads.DoChan(id.String(), func() (any, error) {
+		loadCtx, cancel := context.WithoutCancel(ctx), context.CancelFunc(func() {})
+		if deadline, ok := ctx.Deadline(); ok {
+			loadCtx, cancel = context.WithDeadline(loadCtx, deadline)
+		}
+		defer cancel()
+		return r.load(loadCtx, id)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-results:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return clone(res.Val.(*entity.User)), nil
+	}
+}
+
+// load reads a user from the next repository and caches the outcome.
+func (r *userRepository) load(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	user, err := r.next.GetByID(ctx, id)
+	switch {
+	case err == nil:
+		r.store(id, clone(user), r.ttl, generation)
+	case errors.Is(err, gorm.ErrRecordNotFound) && r.negativeTTL > 0:
+		r.store(id, nil, r.negativeTTL, generation)
+	}
+	return user, err
+}
+
+func (r *userRepository) store(id uuid.UUID, user *entity.User, ttl time.Duration, generation uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if generation != r.generation {
+		return
+	}
+	if r.entries.add(id, user, ttl) {
+		r.metrics.CacheEvicted(name)
+	}
+}
+
+// invalidate drops the entry of id and detaches running lookups of it, so
+// that later callers read the new row.
+func (r *userRepository) invalidate(id uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.generation++
+	r.entries.remove(id)
+	r.loads.Forget(id.String())
+}
+
+// Create invalidates the ID as well, which may be cached as missing.
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	defer r.invalidate(user.ID)
+	return r.next.Create(ctx, user)
+}
+
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	defer r.invalidate(user.ID)
+	return r.next.Update(ctx, user)
+}
+
+func (r *userRepository) Delete(ctx context.Context, user *entity.User) error {
+	defer r.invalidate(user.ID)
+	return r.next.Delete(ctx, user)
+}
+
+// Exists answers from the cache if the user's entry is there; otherwise it
+// asks the next repository without filling the cache.
+func (r *userRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	if user, ok := r.entries.get(id); ok {
+		r.metrics.CacheHit(name)
+		return user != nil, nil
+	}
+	r.metrics.CacheMiss(name)
+	return r.next.Exists(ctx, id)
+}
+
+// GetByIDs serves cached users and fetches the others with a single lookup,
+// caching its outcome like GetByID.
+func (r *userRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entity.User, error) {
+	var users []*entity.User
+	var misses []uuid.UUID
+	for _, id := range ids {
+		user, ok := r.entries.get(id)
+		switch {
+		case !ok:
+			r.metrics.CacheMiss(name)
+			misses = append(misses, id)
+		case user != nil:
+			r.metrics.CacheHit(name)
+			users = append(users, clone(user))
+		default:
+			r.metrics.CacheHit(name)
+		}
+	}
+	if len(misses) == 0 {
+		return users, nil
+	}
+
+	r.mu.Lock()
+	generation := r.generation
+	r.mu.Unlock()
+
+	found, err := r.next.GetByIDs(ctx, misses)
+	if err != nil {
+		return nil, err
+	}
+	loaded := make(map[uuid.UUID]bool, len(found))
+	for _, user := range found {
+		loaded[user.ID] = true
+		r.store(user.ID, clone(user), r.ttl, generation)
+	}
+	if r.negativeTTL > 0 {
+		for _, id := range misses {
+			if !loaded[id] {
+				r.store(id, nil, r.negativeTTL, generation)
+			}
+		}
+	}
+	return append(users, found...), nil
+}
+
+func (r *userRepository) DeleteByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	defer func() {
+		for _, id := range ids {
+			r.invalidate(id)
+		}
+	}()
+	return r.next.DeleteByIDs(ctx, ids)
+}
+
+// GetByIDForUpdate bypasses the cache, since the caller needs the locked row.
+func (r *userRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	return r.next.GetByIDForUpdate(ctx, id)
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, canonicalEmail string) (*entity.User, error) {
+	return r.next.GetByEmail(ctx, canonicalEmail)
+}
+
+func (r *userRepository) List(ctx context.Context, f *filter.Filter, afterID uuid.UUID, limit int) ([]*entity.User, error) {
+	return r.next.List(ctx, f, afterID, limit)
+}
+
+func (r *userRepository) Search(ctx context.Context, query string, limit int) ([]*entity.UserMatch, error) {
+	return r.next.Search(ctx, query, limit)
+}
+
+// clone copies a user so that callers cannot modify cached entries; the use
+// cases update users in place.
+func clone(user *entity.User) *entity.User {
+	c := *user
+	return &c
+}
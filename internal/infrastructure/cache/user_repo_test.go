@@ -0,0 +1,249 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/config"
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/usecase/mocks"
+)
+
+type countingMetrics struct {
+	mu                    sync.Mutex
+	hits, misses, evicted int
+}
+
+func (m *countingMetrics) CacheHit(string)     { m.mu.Lock(); m.hits++; m.mu.Unlock() }
+func (m *countingMetrics) CacheMiss(string)    { m.mu.Lock(); m.misses++; m.mu.Unlock() }
+func (m *countingMetrics) CacheEvicted(string) { m.mu.Lock(); m.evicted++; m.mu.Unlock() }
+
+var testConfig = config.CacheConfig{Enabled: true, Size: 100, TTL: time.Minute, NegativeTTL: time.Minute}
+
+func newTestRepo(cfg config.CacheConfig) (*userRepository, *mocks.UserRepository, *countingMetrics) {
+	next := new(mocks.UserRepository)
+	m := &countingMetrics{}
+	return NewUserRepository(next, cfg, WithMetrics(m)).(*userRepository), next, m
+}
+
+func testUser() *entity.User {
+	return &entity.User{ID: uuid.New(), Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28}
+}
+
+func TestGetByID_CachesUsers(t *testing.T) {
+	repo, next, m := newTestRepo(testConfig)
+	user := testUser()
+	next.On("GetByID", mock.Anything, user.ID).Return(user, nil).Once()
+
+	for i := 0; i < 3; i++ {
+		got, err := repo.GetByID(context.Background(), user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, user, got)
+	}
+	next.AssertExpectations(t)
+	assert.Equal(t, 2, m.hits)
+	assert.Equal(t, 1, m.misses)
+
+	// Callers modify users in place; the cached copy must not change
+	got, _ := repo.GetByID(context.Background(), user.ID)
+	got.Firstname = "Changed"
+	got, _ = repo.GetByID(context.Background(), user.ID)
+	assert.Equal(t, "Alice", got.Firstname)
+}
+
+func TestGetByID_NegativeCaching(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	id := uuid.New()
+	next.On("GetByID", mock.Anything, id).Return(nil, gorm.ErrRecordNotFound).Once()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.GetByID(context.Background(), id)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+	next.AssertExpectations(t)
+
+	// Creating the user forgets that it was missing
+	user := testUser()
+	user.ID = id
+	next.On("Create", mock.Anything, user).Return(nil).Once()
+	next.On("GetByID", mock.Anything, id).Return(user, nil).Once()
+	require.NoError(t, repo.Create(context.Background(), user))
+	got, err := repo.GetByID(context.Background(), id)
+	require.NoError(t, err)
+	assert.Equal(t, id, got.ID)
+}
+
+func TestGetByID_NegativeCachingDisabled(t *testing.T) {
+	cfg := testConfig
+	cfg.NegativeTTL = 0
+	repo, next, _ := newTestRepo(cfg)
+	id := uuid.New()
+	next.On("GetByID", mock.Anything, id).Return(nil, gorm.ErrRecordNotFound).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.GetByID(context.Background(), id)
+		assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	}
+	next.AssertExpectations(t)
+}
+
+func TestGetByID_DoesNotCacheErrors(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	id := uuid.New()
+	dbErr := errors.New("connection reset")
+	next.On("GetByID", mock.Anything, id).Return(nil, dbErr).Twice()
+
+	for i := 0; i < 2; i++ {
+		_, err := repo.GetByID(context.Background(), id)
+		assert.ErrorIs(t, err, dbErr)
+	}
+	next.AssertExpectations(t)
+}
+
+func TestGetByID_Expiry(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	now := time.Now()
+	repo.entries.now = func() time.Time { return now }
+	user := testUser()
+	next.On("GetByID", mock.Anything, user.ID).Return(user, nil).Twice()
+
+	_, _ = repo.GetByID(context.Background(), user.ID)
+	now = now.Add(testConfig.TTL)
+	_, _ = repo.GetByID(context.Background(), user.ID)
+	next.AssertExpectations(t)
+}
+
+func TestGetByID_SizeBound(t *testing.T) {
+	cfg := testConfig
+	cfg.Size = 2
+	repo, next, m := newTestRepo(cfg)
+	next.On("GetByID", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+
+	for i := 0; i < 5; i++ {
+		_, _ = repo.GetByID(context.Background(), uuid.New())
+	}
+	assert.Equal(t, 2, repo.entries.len())
+	assert.Equal(t, 3, m.evicted)
+}
+
+func TestWritesInvalidate(t *testing.T) {
+	for _, write := range []string{"Update", "Delete"} {
+		t.Run(write, func(t *testing.T) {
+			repo, next, _ := newTestRepo(testConfig)
+			user := testUser()
+			next.On("GetByID", mock.Anything, user.ID).Return(user, nil).Twice()
+			next.On(write, mock.Anything, user).Return(nil).Once()
+
+			_, _ = repo.GetByID(context.Background(), user.ID)
+			if write == "Update" {
+				require.NoError(t, repo.Update(context.Background(), user))
+			} else {
+				require.NoError(t, repo.Delete(context.Background(), user))
+			}
+			_, _ = repo.GetByID(context.Background(), user.ID)
+			next.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetByID_ConcurrentMissesShareOneLookup(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	user := testUser()
+	release := make(chan time.Time)
+	next.On("GetByID", mock.Anything, user.ID).Return(user, nil).WaitUntil(release).Once()
+
+	const callers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := repo.GetByID(context.Background(), user.ID)
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID, got.ID)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond) // let the callers pile up on the lookup
+	close(release)
+	wg.Wait()
+	next.AssertExpectations(t)
+}
+
+func TestGetByID_CallerCancellation(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	user := testUser()
+	release := make(chan time.Time)
+	next.On("GetByID", mock.Anything, user.ID).Return(user, nil).WaitUntil(release).Once()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := repo.GetByID(ctx, user.ID)
+		done <- err
+	}()
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+
+	// The lookup finishes and is cached for the next caller
+	close(release)
+	require.Eventually(t, func() bool { return repo.entries.len() == 1 }, time.Second, time.Millisecond)
+	got, err := repo.GetByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	next.AssertExpectations(t)
+}
+
+func TestGetByID_StaleLookupIsNotCached(t *testing.T) {
+	repo, next, _ := newTestRepo(testConfig)
+	old := testUser()
+	updated := *old
+	updated.Firstname = "Alicia"
+
+	release := make(chan time.Time)
+	next.On("GetByID", mock.Anything, old.ID).Return(old, nil).WaitUntil(release).Once()
+	next.On("Update", mock.Anything, &updated).Return(nil).Once()
+
+	done := make(chan *entity.User)
+	go func() {
+		got, _ := repo.GetByID(context.Background(), old.ID)
+		done <- got
+	}()
+	time.Sleep(20 * time.Millisecond) // the lookup has read the old row
+	require.NoError(t, repo.Update(context.Background(), &updated))
+	close(release)
+	<-done
+
+	next.On("GetByID", mock.Anything, old.ID).Return(&updated, nil).Once()
+	got, err := repo.GetByID(context.Background(), old.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alicia", got.Firstname)
+	next.AssertExpectations(t)
+}
+
+func TestGetByIDs_FetchesOnlyMisses(t *testing.T) {
+	repo, next, m := newTestRepo(testConfig)
+	cached, uncached, missing := testUser(), testUser(), uuid.New()
+	next.On("GetByID", mock.Anything, cached.ID).Return(cached, nil).Once()
+	_, err := repo.GetByID(context.Background(), cached.ID)
+	require.NoError(t, err)
+
+	next.On("GetByIDs", mock.Anything, []uuid.UUID{uncached.ID, missing}).Return([]*entity.User{uncached}, nil).Once()
+	for i := 0; i < 2; i++ {
+		users, err := repo.GetByIDs(context.Background(), []uuid.UUID{cached.ID, uncached.ID, missing})
+		require.NoError(t, err)
+		assert.ElementsMatch(t, []*entity.User{cached, uncached}, users)
+	}
+	next.AssertExpectations(t)
+	// The first batch finds one user cached, the second all three, the missing one negatively
+	assert.Equal(t, 4, m.hits)
+	assert.Equal(t, 3, m.misses)
+}
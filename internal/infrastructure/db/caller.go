@@ -0,0 +1,18 @@
+package db
+
+import "context"
+
+type callerContextKey struct{}
+
+// WithCaller returns a context identifying the client on whose behalf
+// queries run, such as its IP address. The Resolver uses it to send a
+// client's reads to the primary right after its writes.
+func WithCaller(ctx context.Context, caller string) context.Context {
+	return context.WithValue(ctx, callerContextKey{}, caller)
+}
+
+// Caller returns the client stored by WithCaller, or "" if there is none.
+func Caller(ctx context.Context) string {
+	caller, _ := ctx.Value(callerContextKey{}).(string)
+	return caller
+}
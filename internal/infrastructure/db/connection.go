@@ -1,46 +1,175 @@
 package db
 
 import (
-	"errors"
+	"context"
+	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"math/rand/v2"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/config"
 )
 
 // Connect establishes a connection to the database using GORM.
-// It returns a pointer to the GORM DB instance and any error encountered.
-func Connect(dsn string) (*gorm.DB, error) {
-	if dsn == "" {
-		return nil, errors.New("no Postgres DSN provided")
+// It retries with exponential backoff until the database answers, the
+// configured number of attempts is exhausted or ctx is cancelled.
+func Connect(ctx context.Context, cfg config.DatabaseConfig) (*gorm.DB, error) {
+	sqlDB, err := openPool(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := waitForDatabase(ctx, sqlDB, cfg); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return openGorm(sqlDB)
+}
+
+// openPool creates the connection pool described by cfg without connecting.
+func openPool(cfg config.DatabaseConfig) (*sql.DB, error) {
+	connConfig, err := ConnConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlDB := stdlib.OpenDB(*connConfig)
+	sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	sqlDB.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return sqlDB, nil
+}
+
+// openGorm opens GORM on sqlDB, closing sqlDB if that fails.
+func openGorm(sqlDB *sql.DB) (*gorm.DB, error) {
+	db, err := gorm.Open(postgres.New(postgres.Config{Conn: sqlDB}), &gorm.Config{
+		Logger: newGormLogger(slog.Default()),
+		// Report constraint violations as gorm.ErrDuplicatedKey and gorm.ErrForeignKeyViolated
+		TranslateError: true,
+	})
+	if err != nil {
+		_ = sqlDB.Close()
+		return nil, fmt.Errorf("failed to open GORM: %w", err)
 	}
+	return db, nil
+}
 
-	var db *gorm.DB
+// waitForDatabase pings the database until it answers.
+func waitForDatabase(ctx context.Context, sqlDB *sql.DB, cfg config.DatabaseConfig) error {
 	var err error
-	maxAttempts := 10
-
-	for attempts := 1; attempts <= maxAttempts; attempts++ {
-		db, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
-		if err == nil {
-			sqlDB, err := db.DB()
-			if err != nil {
-				return nil, fmt.Errorf("failed to get sqlDB from GORM: %w", err)
-			}
-			// Optionally set up database connection pool
-			sqlDB.SetMaxOpenConns(10)
-			sqlDB.SetMaxIdleConns(10)
-			sqlDB.SetConnMaxLifetime(time.Minute)
-			// Ping to test the connection
-			if err := sqlDB.Ping(); err != nil {
-				return nil, fmt.Errorf("db.Ping failed: %w", err)
-			}
-			return db, nil
+	for attempt := 1; attempt <= cfg.ConnectAttempts; attempt++ {
+		if err = sqlDB.PingContext(ctx); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if attempt == cfg.ConnectAttempts {
+			break
+		}
+
+		delay := backoff(attempt, cfg.RetryBackoff, cfg.RetryMaxBackoff)
+		slog.WarnContext(ctx, "Unable to connect to database, retrying",
+			slog.Int("attempt", attempt), slog.Duration("retry_in", delay), slog.String("error", err.Error()))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+	return fmt.Errorf("gave up after %d attempts: %w", cfg.ConnectAttempts, err)
+}
+
+// backoff returns the delay before retry number attempt (starting at 1): the
+// base delay doubles with every attempt up to maxDelay, and half of it is randomized
+// so that replicas started together do not retry in lockstep.
+func backoff(attempt int, initial, maxDelay time.Duration) time.Duration {
+	d := initial
+	for i := 1; i < attempt && d < maxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, maxDelay)
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+// ConnConfig builds the pgx connection configuration from cfg. Without a URL
+// the connection string is assembled from the individual settings.
+func ConnConfig(cfg config.DatabaseConfig) (*pgx.ConnConfig, error) {
+	dsn := cfg.URL
+	if dsn == "" {
+		dsn = keywordDSN(cfg)
+	}
+	connConfig, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return nil, fmt.Errorf("invalid database connection string: %w", err)
+	}
+
+	if cfg.ConnectTimeout > 0 && connConfig.ConnectTimeout == 0 {
+		connConfig.ConnectTimeout = cfg.ConnectTimeout
+	}
+	// Settings given in the URL win over the configured defaults
+	setRuntimeParam(connConfig, "application_name", cfg.ApplicationName)
+	if cfg.StatementTimeout > 0 {
+		setRuntimeParam(connConfig, "statement_timeout", strconv.FormatInt(cfg.StatementTimeout.Milliseconds(), 10))
+	}
+	setRuntimeParam(connConfig, "timezone", "UTC")
+	return connConfig, nil
+}
+
+// keywordDSN assembles a key=value connection string from cfg.
+func keywordDSN(cfg config.DatabaseConfig) string {
+	params := []struct{ key, value string }{
+		{"host", cfg.Host},
+		{"port", strconv.Itoa(cfg.Port)},
+		{"user", cfg.User},
+		{"password", cfg.Password},
+		{"dbname", cfg.Name},
+		{"sslmode", cfg.SSLMode},
+		{"sslrootcert", cfg.SSLRootCert},
+		{"sslcert", cfg.SSLCert},
+		{"sslkey", cfg.SSLKey},
+	}
+	var parts []string
+	for _, p := range params {
+		if p.value != "" {
+			parts = append(parts, p.key+"="+quoteDSNValue(p.value))
 		}
-		log.Printf("Attempt %d: Unable to connect to database. Retrying in 2 seconds...", attempts)
-		time.Sleep(2 * time.Second)
 	}
+	return strings.Join(parts, " ")
+}
+
+// quoteDSNValue quotes a value of a key=value connection string if needed.
+func quoteDSNValue(value string) string {
+	if !strings.ContainsAny(value, ` '\`) {
+		return value
+	}
+	value = strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value)
+	return "'" + value + "'"
+}
 
-	return nil, fmt.Errorf("failed to connect to database after %d attempts: %w", maxAttempts, err)
+// setRuntimeParam sets a server parameter unless the connection string
+// already did. Parameter names are case-insensitive.
+func setRuntimeParam(connConfig *pgx.ConnConfig, name, value string) {
+	if value == "" {
+		return
+	}
+	for key := range connConfig.RuntimeParams {
+		if strings.EqualFold(key, name) {
+			return
+		}
+	}
+	connConfig.RuntimeParams[name] = value
 }
@@ -0,0 +1,99 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/config"
+)
+
+func TestConnConfig_FromSettings(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Host = "db.internal"
+	cfg.Port = 6432
+	cfg.Password = "it's secret"
+	cfg.StatementTimeout = 5 * time.Second
+
+	connConfig, err := ConnConfig(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "db.internal", connConfig.Host)
+	assert.Equal(t, uint16(6432), connConfig.Port)
+	assert.Equal(t, "postgres", connConfig.User)
+	assert.Equal(t, "it's secret", connConfig.Password)
+	assert.Equal(t, "usersdb", connConfig.Database)
+	assert.Nil(t, connConfig.TLSConfig)
+	assert.Equal(t, 5*time.Second, connConfig.ConnectTimeout)
+	assert.Equal(t, "userapi", connConfig.RuntimeParams["application_name"])
+	assert.Equal(t, "5000", connConfig.RuntimeParams["statement_timeout"])
+	assert.Equal(t, "UTC", connConfig.RuntimeParams["timezone"])
+}
+
+func TestConnConfig_URLWins(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.URL = "postgres://app:pw@replica:5433/other?sslmode=require&application_name=custom&connect_timeout=3"
+	cfg.StatementTimeout = time.Second
+
+	connConfig, err := ConnConfig(cfg)
+
+	require.NoError(t, err)
+	assert.Equal(t, "replica", connConfig.Host)
+	assert.Equal(t, "other", connConfig.Database)
+	assert.NotNil(t, connConfig.TLSConfig)
+	assert.Equal(t, 3*time.Second, connConfig.ConnectTimeout)
+	assert.Equal(t, "custom", connConfig.RuntimeParams["application_name"])
+	assert.Equal(t, "1000", connConfig.RuntimeParams["statement_timeout"])
+}
+
+func TestConnConfig_InvalidURL(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.URL = "postgres://%zz"
+
+	_, err := ConnConfig(cfg)
+
+	assert.Error(t, err)
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt, want := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 10: time.Second} {
+		for i := 0; i < 20; i++ {
+			d := backoff(attempt, 100*time.Millisecond, time.Second)
+			assert.GreaterOrEqual(t, d, want/2)
+			assert.LessOrEqual(t, d, want)
+		}
+	}
+}
+
+func TestConnect_Cancelled(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 1 // Nothing listens here
+	cfg.RetryBackoff = time.Hour
+	cfg.RetryMaxBackoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := Connect(ctx, cfg)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+func TestConnect_GivesUp(t *testing.T) {
+	cfg := config.Default().Database
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 1
+	cfg.ConnectAttempts = 2
+	cfg.RetryBackoff = time.Millisecond
+	cfg.RetryMaxBackoff = time.Millisecond
+
+	_, err := Connect(context.Background(), cfg)
+
+	assert.ErrorContains(t, err, "gave up after 2 attempts")
+}
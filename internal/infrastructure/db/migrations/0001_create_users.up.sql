@@ -0,0 +1,12 @@
+-- Baseline schema. IF NOT EXISTS keeps it compatible with databases created
+-- by GORM's AutoMigrate before versioned migrations were introduced.
+CREATE TABLE IF NOT EXISTS user_gorms (
+    id        uuid PRIMARY KEY,
+    firstname text,
+    lastname  text,
+    email     text,
+    age       bigint,
+    created   bigint
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_user_gorms_email ON user_gorms (email);
@@ -0,0 +1,6 @@
+-- migrate:no-transaction
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_email_trgm;
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_lastname_trgm;
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_firstname_trgm;
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_search_vector;
+ALTER TABLE user_gorms DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,15 @@
+-- migrate:no-transaction
+-- Full-text and fuzzy search over names and email addresses. The 'simple'
+-- configuration neither stems nor drops stop words, which suits names. The
+-- indexes are built concurrently so that writes continue meanwhile.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE user_gorms
+    ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+        to_tsvector('simple', coalesce(firstname, '') || ' ' || coalesce(lastname, '') || ' ' || coalesce(email, ''))
+    ) STORED;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_search_vector ON user_gorms USING gin (search_vector);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_firstname_trgm ON user_gorms USING gin (firstname gin_trgm_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_lastname_trgm ON user_gorms USING gin (lastname gin_trgm_ops);
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_email_trgm ON user_gorms USING gin (email gin_trgm_ops);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS user_gorms_default_canonical_email ON user_gorms;
+DROP FUNCTION IF EXISTS user_gorms_default_canonical_email();
+ALTER TABLE user_gorms DROP COLUMN IF EXISTS canonical_email;
@@ -0,0 +1,19 @@
+-- Email addresses are unique in their canonical form (see the email
+-- package) instead of as given, once 0004 has indexed it. Only lowercase
+-- addresses were accepted before, so the canonical form of the existing rows
+-- is the lowercase address.
+-- The column stays nullable, and a trigger fills it for the rows inserted by
+-- instances of the previous release, until 0008 makes it NOT NULL.
+ALTER TABLE user_gorms ADD COLUMN canonical_email text;
+
+CREATE FUNCTION user_gorms_default_canonical_email() RETURNS trigger AS $$
+BEGIN
+    NEW.canonical_email := coalesce(NEW.canonical_email, lower(NEW.email));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_gorms_default_canonical_email BEFORE INSERT ON user_gorms
+    FOR EACH ROW EXECUTE FUNCTION user_gorms_default_canonical_email();
+
+UPDATE user_gorms SET canonical_email = lower(email);
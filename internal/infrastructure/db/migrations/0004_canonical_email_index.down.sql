@@ -0,0 +1,4 @@
+-- migrate:no-transaction
+-- Fails if addresses now differ only in case or provider variants.
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_email ON user_gorms (email);
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_canonical_email;
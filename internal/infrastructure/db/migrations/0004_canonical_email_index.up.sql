@@ -0,0 +1,6 @@
+-- migrate:no-transaction
+-- The unique index on the canonical form replaces the one on the address as
+-- given. It is built concurrently so that writes continue meanwhile, and the
+-- old index is dropped only once the new one enforces uniqueness.
+CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_canonical_email ON user_gorms (canonical_email);
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_email;
@@ -0,0 +1,6 @@
+-- migrate:no-transaction
+-- Keep the current age of users with a date of birth.
+UPDATE user_gorms SET age = extract(year FROM age(current_date, date_of_birth)) WHERE date_of_birth IS NOT NULL;
+
+DROP INDEX CONCURRENTLY IF EXISTS idx_user_gorms_date_of_birth;
+ALTER TABLE user_gorms DROP COLUMN IF EXISTS date_of_birth;
@@ -0,0 +1,6 @@
+-- migrate:no-transaction
+-- Users have a date of birth, from which the age is computed on read. The
+-- date of birth of existing users is unknown, so they keep the age as given.
+ALTER TABLE user_gorms ADD COLUMN IF NOT EXISTS date_of_birth date;
+
+CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_user_gorms_date_of_birth ON user_gorms (date_of_birth);
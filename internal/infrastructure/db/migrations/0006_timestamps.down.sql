@@ -0,0 +1,6 @@
+-- Loses the fractions of seconds and the update times. The trigger has kept
+-- created in sync, so it still holds the creation times.
+DROP TRIGGER IF EXISTS user_gorms_sync_created ON user_gorms;
+DROP FUNCTION IF EXISTS user_gorms_sync_created();
+
+ALTER TABLE user_gorms DROP COLUMN created_at, DROP COLUMN updated_at;
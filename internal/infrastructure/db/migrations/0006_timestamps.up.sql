@@ -0,0 +1,27 @@
+-- Creation and update times are timestamptz, with microsecond precision,
+-- instead of Unix seconds. The last update of existing users is unknown, so
+-- it starts out as their creation time.
+-- Instances of the previous release still read and write created, so a
+-- trigger keeps it in sync with created_at until 0009 drops it. Their
+-- updates do not advance updated_at.
+ALTER TABLE user_gorms ADD COLUMN created_at timestamptz, ADD COLUMN updated_at timestamptz;
+
+CREATE FUNCTION user_gorms_sync_created() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at
+        AND NEW.created IS DISTINCT FROM OLD.created THEN
+        NEW.created_at := to_timestamp(NEW.created);
+    END IF;
+    NEW.created_at := coalesce(NEW.created_at, to_timestamp(NEW.created), now());
+    NEW.updated_at := coalesce(NEW.updated_at, NEW.created_at);
+    NEW.created := floor(extract(epoch FROM NEW.created_at));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_gorms_sync_created BEFORE INSERT OR UPDATE ON user_gorms
+    FOR EACH ROW EXECUTE FUNCTION user_gorms_sync_created();
+
+UPDATE user_gorms SET created_at = coalesce(to_timestamp(created), now());
+UPDATE user_gorms SET updated_at = created_at;
+ALTER TABLE user_gorms ALTER COLUMN created_at SET NOT NULL, ALTER COLUMN updated_at SET NOT NULL;
@@ -0,0 +1,9 @@
+-- Settings the stored data depends on. email_rules names the rules the
+-- canonical email addresses were computed with (see email.RuleSet); the
+-- existing addresses were lowercased, the standard rules for them.
+CREATE TABLE settings (
+    key   text PRIMARY KEY,
+    value text NOT NULL
+);
+
+INSERT INTO settings (key, value) VALUES ('email_rules', 'standard-v1');
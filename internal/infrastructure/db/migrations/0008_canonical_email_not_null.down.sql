@@ -0,0 +1,11 @@
+ALTER TABLE user_gorms ALTER COLUMN canonical_email DROP NOT NULL;
+
+CREATE FUNCTION user_gorms_default_canonical_email() RETURNS trigger AS $$
+BEGIN
+    NEW.canonical_email := coalesce(NEW.canonical_email, lower(NEW.email));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_gorms_default_canonical_email BEFORE INSERT ON user_gorms
+    FOR EACH ROW EXECUTE FUNCTION user_gorms_default_canonical_email();
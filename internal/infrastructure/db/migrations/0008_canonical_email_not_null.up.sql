@@ -0,0 +1,8 @@
+-- migrate:contract
+-- Every instance writes canonical_email now, so the trigger of 0003 is no
+-- longer needed and the column becomes NOT NULL.
+DROP TRIGGER IF EXISTS user_gorms_default_canonical_email ON user_gorms;
+DROP FUNCTION IF EXISTS user_gorms_default_canonical_email();
+
+UPDATE user_gorms SET canonical_email = lower(email) WHERE canonical_email IS NULL;
+ALTER TABLE user_gorms ALTER COLUMN canonical_email SET NOT NULL;
@@ -0,0 +1,19 @@
+ALTER TABLE user_gorms ADD COLUMN created bigint;
+
+CREATE FUNCTION user_gorms_sync_created() RETURNS trigger AS $$
+BEGIN
+    IF TG_OP = 'UPDATE' AND NEW.created_at IS NOT DISTINCT FROM OLD.created_at
+        AND NEW.created IS DISTINCT FROM OLD.created THEN
+        NEW.created_at := to_timestamp(NEW.created);
+    END IF;
+    NEW.created_at := coalesce(NEW.created_at, to_timestamp(NEW.created), now());
+    NEW.updated_at := coalesce(NEW.updated_at, NEW.created_at);
+    NEW.created := floor(extract(epoch FROM NEW.created_at));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER user_gorms_sync_created BEFORE INSERT OR UPDATE ON user_gorms
+    FOR EACH ROW EXECUTE FUNCTION user_gorms_sync_created();
+
+UPDATE user_gorms SET created = floor(extract(epoch FROM created_at));
@@ -0,0 +1,7 @@
+-- migrate:contract
+-- Every instance reads and writes created_at now, so the Unix-second created
+-- column of 0006 and the trigger keeping it in sync are no longer needed.
+DROP TRIGGER IF EXISTS user_gorms_sync_created ON user_gorms;
+DROP FUNCTION IF EXISTS user_gorms_sync_created();
+
+ALTER TABLE user_gorms DROP COLUMN created;
@@ -0,0 +1,187 @@
+// Package migrations applies the versioned SQL migrations that define the
+// database schema.
+//
+// Migrations are embedded pairs of files named <version>_<name>.up.sql and
+// <version>_<name>.down.sql. Applied versions are recorded in the
+// schema_migrations table; every migration runs in its own transaction
+// together with that bookkeeping, so a failed migration leaves no trace.
+//
+// A file starting with a "-- migrate:no-transaction" line runs outside a
+// transaction instead, one statement at a time, for statements such as
+// CREATE INDEX CONCURRENTLY that PostgreSQL refuses inside one. Statements
+// end with a semicolon at the end of a line. Such a migration may stop
+// halfway, so its statements must be safe to run again (IF NOT EXISTS).
+//
+// An up file with a "-- migrate:contract" line removes what instances of an
+// earlier release still use, such as a column they write. Startup applies
+// pending migrations only up to the first contract migration (see
+// Migrator.Expand), so that old and new instances can run side by side
+// during a rolling deploy; contract migrations are applied by Up once the
+// old instances are gone.
+package migrations
+
+import (
+	"embed"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed *.sql
+var embedded embed.FS
+
+// Migration is one schema change with the SQL to apply and to revert it.
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+	// UpNoTransaction and DownNoTransaction are set for the files marked
+	// with "-- migrate:no-transaction".
+	UpNoTransaction   bool
+	DownNoTransaction bool
+	// Contract is set for migrations whose up file is marked with
+	// "-- migrate:contract".
+	Contract bool
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// markerPrefix starts the lines at the top of a file that mark how it runs.
+const markerPrefix = "-- migrate:"
+
+// All returns the embedded migrations ordered by version.
+func All() ([]Migration, error) {
+	return Load(embedded)
+}
+
+// Load reads the migrations in the root of fsys, ordered by version. Every
+// version must have exactly one up and one down file.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration %s: file name must look like <version>_<name>.up.sql or <version>_<name>.down.sql", entry.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: version must be a positive integer", entry.Name())
+		}
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		} else if m.Name != match[2] {
+			return nil, fmt.Errorf("migration %d: conflicting names %q and %q", version, m.Name, match[2])
+		}
+		script := string(data)
+		var noTx, contract bool
+		for _, line := range strings.Split(script, "\n") {
+			marker, ok := strings.CutPrefix(strings.TrimSpace(line), markerPrefix)
+			if !ok {
+				break
+			}
+			switch {
+			case marker == "no-transaction":
+				noTx = true
+			case marker == "contract" && match[3] == "up":
+				contract = true
+			default:
+				return nil, fmt.Errorf("migration %s: unknown marker %q", entry.Name(), markerPrefix+marker)
+			}
+		}
+		if match[3] == "up" {
+			m.Up, m.UpNoTransaction, m.Contract = script, noTx, contract
+		} else {
+			m.Down, m.DownNoTransaction = script, noTx
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s: both an up and a down file are required", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// statements splits a script into its statements, dropping those that
+// consist of comments only.
+func statements(script string) []string {
+	var stmts []string
+	var current strings.Builder
+	code := false
+	flush := func() {
+		if code {
+			stmts = append(stmts, strings.TrimSpace(current.String()))
+		}
+		current.Reset()
+		code = false
+	}
+	for _, line := range strings.Split(script, "\n") {
+		trimmed := strings.TrimSpace(line)
+		current.WriteString(line)
+		current.WriteString("\n")
+		if trimmed != "" && !strings.HasPrefix(trimmed, "--") {
+			code = true
+		}
+		if strings.HasSuffix(trimmed, ";") {
+			flush()
+		}
+	}
+	flush()
+	return stmts
+}
+
+// plan returns the migrations to apply, in order, to move from the applied
+// versions to target: migrations up to target that are not applied yet, or,
+// in reverse order, applied migrations above target that must be reverted.
+// Reverting a version without a known migration is an error.
+func plan(migrations []Migration, applied map[int64]bool, target int64) (up, down []Migration, err error) {
+	known := make(map[int64]bool, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = true
+		if m.Version <= target && !applied[m.Version] {
+			up = append(up, m)
+		}
+	}
+
+	var revert []int64
+	for version := range applied {
+		if version > target {
+			if !known[version] {
+				return nil, nil, fmt.Errorf("migration %d is applied but unknown to this build", version)
+			}
+			revert = append(revert, version)
+		}
+	}
+	sort.Slice(revert, func(i, j int) bool { return revert[i] > revert[j] })
+	for _, version := range revert {
+		for _, m := range migrations {
+			if m.Version == version {
+				down = append(down, m)
+			}
+		}
+	}
+	return up, down, nil
+}
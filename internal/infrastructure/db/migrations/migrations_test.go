@@ -0,0 +1,173 @@
+package migrations
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func versions(migrations []Migration) []int64 {
+	var out []int64
+	for _, m := range migrations {
+		out = append(out, m.Version)
+	}
+	return out
+}
+
+func TestAll(t *testing.T) {
+	migrations, err := All()
+
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	for i := 1; i < len(migrations); i++ {
+		assert.Greater(t, migrations[i].Version, migrations[i-1].Version)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0010_add_index.up.sql":      {Data: []byte("CREATE INDEX i ON t (c);")},
+		"0010_add_index.down.sql":    {Data: []byte("DROP INDEX i;")},
+		"0002_create_table.up.sql":   {Data: []byte("CREATE TABLE t (c int);")},
+		"0002_create_table.down.sql": {Data: []byte("DROP TABLE t;")},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []Migration{
+		{Version: 2, Name: "create_table", Up: "CREATE TABLE t (c int);", Down: "DROP TABLE t;"},
+		{Version: 10, Name: "add_index", Up: "CREATE INDEX i ON t (c);", Down: "DROP INDEX i;"},
+	}, migrations)
+}
+
+func TestLoad_NoTransaction(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0001_add_index.up.sql":   {Data: []byte("-- migrate:no-transaction\nCREATE INDEX CONCURRENTLY i ON t (c);")},
+		"0001_add_index.down.sql": {Data: []byte("DROP INDEX i;")},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.True(t, migrations[0].UpNoTransaction)
+	assert.False(t, migrations[0].DownNoTransaction)
+}
+
+func TestLoad_Contract(t *testing.T) {
+	migrations, err := Load(fstest.MapFS{
+		"0001_drop_column.up.sql":   {Data: []byte("-- migrate:contract\n-- migrate:no-transaction\nALTER TABLE t DROP COLUMN c;")},
+		"0001_drop_column.down.sql": {Data: []byte("ALTER TABLE t ADD COLUMN c int;")},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, migrations, 1)
+	assert.True(t, migrations[0].Contract)
+	assert.True(t, migrations[0].UpNoTransaction)
+
+	_, err = Load(fstest.MapFS{
+		"0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+		"0001_a.down.sql": {Data: []byte("-- migrate:contract\nSELECT 1;")},
+	})
+	assert.EqualError(t, err, `migration 0001_a.down.sql: unknown marker "-- migrate:contract"`)
+}
+
+func TestStatements(t *testing.T) {
+	script := `-- migrate:no-transaction
+-- Comments before a statement belong to it.
+CREATE INDEX CONCURRENTLY IF NOT EXISTS i ON t (c);
+
+ALTER TABLE t
+    ADD COLUMN d int;
+-- A trailing comment is dropped.
+`
+
+	assert.Equal(t, []string{
+		"-- migrate:no-transaction\n-- Comments before a statement belong to it.\nCREATE INDEX CONCURRENTLY IF NOT EXISTS i ON t (c);",
+		"ALTER TABLE t\n    ADD COLUMN d int;",
+	}, statements(script))
+}
+
+func TestAll_SearchIndexesAreBuiltConcurrently(t *testing.T) {
+	migrations, err := All()
+	require.NoError(t, err)
+
+	for _, m := range migrations {
+		if m.Name == "user_search" {
+			assert.True(t, m.UpNoTransaction)
+			assert.Len(t, statements(m.Up), 6)
+			return
+		}
+	}
+	t.Fatal("user_search migration not found")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]struct {
+		fs      fstest.MapFS
+		wantErr string
+	}{
+		"missing down": {
+			fs:      fstest.MapFS{"0001_a.up.sql": {}},
+			wantErr: "migration 1_a: both an up and a down file are required",
+		},
+		"bad name": {
+			fs:      fstest.MapFS{"create.sql": {}},
+			wantErr: "migration create.sql: file name must look like <version>_<name>.up.sql or <version>_<name>.down.sql",
+		},
+		"zero version": {
+			fs:      fstest.MapFS{"0_a.up.sql": {}},
+			wantErr: "migration 0_a.up.sql: version must be a positive integer",
+		},
+		"conflicting names": {
+			fs:      fstest.MapFS{"1_a.up.sql": {}, "1_b.down.sql": {}},
+			wantErr: `migration 1: conflicting names "a" and "b"`,
+		},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := Load(tt.fs)
+			assert.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestPlan(t *testing.T) {
+	all := []Migration{{Version: 1}, {Version: 2}, {Version: 3}, {Version: 4}}
+
+	tests := map[string]struct {
+		applied  map[int64]bool
+		target   int64
+		wantUp   []int64
+		wantDown []int64
+	}{
+		"fresh database":    {applied: map[int64]bool{}, target: 4, wantUp: []int64{1, 2, 3, 4}},
+		"up to date":        {applied: map[int64]bool{1: true, 2: true, 3: true, 4: true}, target: 4},
+		"fills gaps":        {applied: map[int64]bool{1: true, 3: true}, target: 4, wantUp: []int64{2, 4}},
+		"down to version":   {applied: map[int64]bool{1: true, 2: true, 3: true, 4: true}, target: 2, wantDown: []int64{4, 3}},
+		"down to zero":      {applied: map[int64]bool{1: true, 2: true}, target: 0, wantDown: []int64{2, 1}},
+		"both directions":   {applied: map[int64]bool{1: true, 4: true}, target: 3, wantUp: []int64{2, 3}, wantDown: []int64{4}},
+		"ignores unapplied": {applied: map[int64]bool{1: true}, target: 1},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			up, down, err := plan(all, tt.applied, tt.target)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantUp, versions(up))
+			assert.Equal(t, tt.wantDown, versions(down))
+		})
+	}
+}
+
+func TestPlan_UnknownAppliedVersion(t *testing.T) {
+	all := []Migration{{Version: 1}}
+
+	up, down, err := plan(all, map[int64]bool{1: true, 7: true}, 7)
+	require.NoError(t, err)
+	assert.Empty(t, up)
+	assert.Empty(t, down)
+
+	_, _, err = plan(all, map[int64]bool{1: true, 7: true}, 1)
+	assert.EqualError(t, err, "migration 7 is applied but unknown to this build")
+}
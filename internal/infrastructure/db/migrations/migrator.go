@@ -0,0 +1,303 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"log/slog"
+	"sort"
+	"time"
+)
+
+// lockID identifies the advisory lock that serializes migrations across
+// replicas starting at the same time.
+var lockID = func() int64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte("userapi.schema_migrations"))
+	return int64(h.Sum64())
+}()
+
+// Status describes one migration known to this build or recorded in the database.
+type Status struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Unknown is set for applied versions that this build has no migration for,
+	// e.g. after a rollback to an older release.
+	Unknown bool
+	// Contract is set for contract migrations, which startup does not apply.
+	Contract bool
+}
+
+// Migrator applies migrations to a PostgreSQL database.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator creates a new Migrator for the embedded migrations.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+// Latest returns the highest version known to this build, or 0 if there are no migrations.
+func (m *Migrator) Latest() int64 {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies all pending migrations. Applied versions unknown to this build
+// are left alone.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		target := m.Latest()
+		for version := range applied {
+			target = max(target, version)
+		}
+		return m.migrate(ctx, conn, applied, target, false)
+	})
+}
+
+// Expand applies the pending migrations that come before the first pending
+// contract migration, which are safe to apply while instances of the
+// previous release are running.
+func (m *Migrator) Expand(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		target := m.Latest()
+		for version := range applied {
+			target = max(target, version)
+		}
+		return m.migrate(ctx, conn, applied, target, true)
+	})
+}
+
+// Down reverts the most recently applied migration.
+func (m *Migrator) Down(ctx context.Context) error {
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		var highest, target int64
+		for version := range applied {
+			if version > highest {
+				highest, target = version, highest
+			} else if version > target {
+				target = version
+			}
+		}
+		if highest == 0 {
+			slog.InfoContext(ctx, "No migration to revert")
+			return nil
+		}
+		return m.migrate(ctx, conn, applied, target, false)
+	})
+}
+
+// To migrates up or down so that exactly the migrations up to and including
+// version are applied. Version 0 reverts all migrations.
+func (m *Migrator) To(ctx context.Context, version int64) error {
+	if version < 0 {
+		return fmt.Errorf("invalid target version %d", version)
+	}
+	if version > m.Latest() {
+		return fmt.Errorf("target version %d is newer than the latest migration %d", version, m.Latest())
+	}
+	return m.withLock(ctx, func(conn *sql.Conn, applied map[int64]time.Time) error {
+		return m.migrate(ctx, conn, applied, version, false)
+	})
+}
+
+// Status reports every known migration and every applied version, ordered by version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(_ *sql.Conn, applied map[int64]time.Time) error {
+		known := make(map[int64]bool, len(m.migrations))
+		for _, mig := range m.migrations {
+			known[mig.Version] = true
+			at, ok := applied[mig.Version]
+			statuses = append(statuses, Status{Version: mig.Version, Name: mig.Name, Applied: ok, AppliedAt: at, Contract: mig.Contract})
+		}
+		for version, at := range applied {
+			if !known[version] {
+				statuses = append(statuses, Status{Version: version, Applied: true, AppliedAt: at, Unknown: true})
+			}
+		}
+		return nil
+	})
+	sortStatuses(statuses)
+	return statuses, err
+}
+
+// withLock runs fn on a dedicated connection holding the migration lock,
+// after making sure the schema_migrations table exists.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn, applied map[int64]time.Time) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Unlock even if ctx has been cancelled; the lock is bound to the connection
+		unlockCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(unlockCtx, "SELECT pg_advisory_unlock($1)", lockID); err != nil {
+			slog.Warn("Failed to release migration lock", slog.String("error", err.Error()))
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	applied, err := appliedVersions(ctx, conn)
+	if err != nil {
+		return err
+	}
+	return fn(conn, applied)
+}
+
+// migrate moves the schema to target, reverting before applying. With
+// expandOnly, it stops before the first contract migration to apply.
+func (m *Migrator) migrate(ctx context.Context, conn *sql.Conn, applied map[int64]time.Time, target int64, expandOnly bool) error {
+	isApplied := make(map[int64]bool, len(applied))
+	for version := range applied {
+		isApplied[version] = true
+	}
+	up, down, err := plan(m.migrations, isApplied, target)
+	if err != nil {
+		return err
+	}
+	if expandOnly {
+		for i, mig := range up {
+			if mig.Contract {
+				slog.WarnContext(ctx, "Contract migrations are pending; apply them with the migrate command once no instance of the previous release is running",
+					slog.Int64("version", mig.Version), slog.String("name", mig.Name))
+				up = up[:i]
+				break
+			}
+		}
+	}
+	if len(up) == 0 && len(down) == 0 {
+		slog.InfoContext(ctx, "Database schema is up to date", slog.Int64("version", target))
+		return nil
+	}
+
+	for _, mig := range down {
+		if err := run(ctx, conn, mig, false); err != nil {
+			return err
+		}
+	}
+	for _, mig := range up {
+		if err := run(ctx, conn, mig, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// run applies or reverts one migration in a transaction that also updates
+// schema_migrations, or statement by statement if it must not run in one.
+func run(ctx context.Context, conn *sql.Conn, mig Migration, up bool) error {
+	direction, script, noTx := "down", mig.Down, mig.DownNoTransaction
+	record := func(ctx context.Context, db execer) error {
+		_, err := db.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", mig.Version)
+		return err
+	}
+	if up {
+		direction, script, noTx = "up", mig.Up, mig.UpNoTransaction
+		record = func(ctx context.Context, db execer) error {
+			_, err := db.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", mig.Version, mig.Name)
+			return err
+		}
+	}
+
+	exec := inTransaction
+	if noTx {
+		exec = byStatement
+	}
+	start := time.Now()
+	if err := exec(ctx, conn, script, record); err != nil {
+		return fmt.Errorf("migration %d_%s (%s) failed: %w", mig.Version, mig.Name, direction, err)
+	}
+
+	slog.InfoContext(ctx, "Applied migration",
+		slog.Int64("version", mig.Version), slog.String("name", mig.Name),
+		slog.String("direction", direction), slog.Duration("duration", time.Since(start)))
+	return nil
+}
+
+// execer is implemented by *sql.Conn and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// inTransaction runs script and then record in one transaction.
+func inTransaction(ctx context.Context, conn *sql.Conn, script string, record func(context.Context, execer) error) (err error) {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if _, err = tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if err = record(ctx, tx); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return tx.Commit()
+}
+
+// byStatement runs the statements of script one by one outside a
+// transaction, then record.
+func byStatement(ctx context.Context, conn *sql.Conn, script string, record func(context.Context, execer) error) error {
+	for _, stmt := range statements(script) {
+		if _, err := conn.ExecContext(ctx, stmt); err != nil {
+			return err
+		}
+	}
+	if err := record(ctx, conn); err != nil {
+		return fmt.Errorf("failed to record migration: %w", err)
+	}
+	return nil
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int64]time.Time, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]time.Time)
+	for rows.Next() {
+		var version int64
+		var at time.Time
+		if err := rows.Scan(&version, &at); err != nil {
+			return nil, err
+		}
+		applied[version] = at
+	}
+	return applied, rows.Err()
+}
+
+func sortStatuses(statuses []Status) {
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+}
@@ -0,0 +1,244 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/config"
+)
+
+// Replica is a read replica of the primary database.
+type Replica struct {
+	// Name identifies the replica in logs as host:port.
+	Name string
+	DB   *gorm.DB
+
+	ping    func(ctx context.Context) error
+	close   func() error
+	healthy atomic.Bool
+}
+
+// NewReplica wraps an open replica connection. It starts out healthy; ping
+// is used by the health checks.
+func NewReplica(name string, db *gorm.DB, ping func(ctx context.Context) error) *Replica {
+	r := &Replica{Name: name, DB: db, ping: ping, close: func() error { return nil }}
+	r.healthy.Store(true)
+	return r
+}
+
+// Healthy reports whether the replica passed its last health check.
+func (r *Replica) Healthy() bool {
+	return r.healthy.Load()
+}
+
+// OpenReplicas opens a connection pool for every replica URL of cfg. Unlike
+// Connect it does not wait for the replicas: a replica that does not answer
+// its first ping within the connect timeout starts out unhealthy and is
+// admitted once a health check succeeds.
+func OpenReplicas(ctx context.Context, cfg config.DatabaseConfig) ([]*Replica, error) {
+	var replicas []*Replica
+	for i, url := range cfg.Replicas() {
+		replicaCfg := cfg
+		replicaCfg.URL = url
+		connConfig, err := ConnConfig(replicaCfg)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
+		}
+		sqlDB, err := openPool(replicaCfg)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
+		}
+		db, err := openGorm(sqlDB)
+		if err != nil {
+			closeReplicas(replicas)
+			return nil, fmt.Errorf("replica %d: %w", i+1, err)
+		}
+
+		replica := NewReplica(net.JoinHostPort(connConfig.Host, strconv.Itoa(int(connConfig.Port))), db, sqlDB.PingContext)
+		replica.close = sqlDB.Close
+		pingCtx, cancel := context.WithTimeout(ctx, max(cfg.ConnectTimeout, time.Second))
+		if err := replica.ping(pingCtx); err != nil {
+			replica.healthy.Store(false)
+			slog.WarnContext(ctx, "Database replica unavailable, routing its reads elsewhere",
+				slog.String("replica", replica.Name), slog.String("error", err.Error()))
+		}
+		cancel()
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
+}
+
+func closeReplicas(replicas []*Replica) error {
+	var errs []error
+	for _, r := range replicas {
+		errs = append(errs, r.close())
+	}
+	return errors.Join(errs...)
+}
+
+// Resolver routes queries between the primary and its read replicas. Writes
+// go to the primary. Reads go to the healthy replicas in turn, or to the
+// primary if there are none, and also for a stickiness window after the same
+// caller or the same key was written, since replicas may lag behind.
+type Resolver struct {
+	primary    *gorm.DB
+	replicas   []*Replica
+	stickiness time.Duration
+	next       atomic.Uint64
+	now        func() time.Time
+
+	mu     sync.Mutex
+	sticky map[string]time.Time // end of the stickiness window by key
+	pruned time.Time
+}
+
+// NewResolver creates a Resolver. Without replicas every query goes to the primary.
+func NewResolver(primary *gorm.DB, replicas []*Replica, stickiness time.Duration) *Resolver {
+	return &Resolver{
+		primary:    primary,
+		replicas:   replicas,
+		stickiness: stickiness,
+		now:        time.Now,
+		sticky:     make(map[string]time.Time),
+	}
+}
+
+// Replicas returns the replicas of the resolver.
+func (r *Resolver) Replicas() []*Replica {
+	return r.replicas
+}
+
+// Writer returns the primary.
+func (r *Resolver) Writer() *gorm.DB {
+	return r.primary
+}
+
+// Reader returns the connection for a read by the caller of ctx that
+// concerns keys, such as the ID of the user being read.
+func (r *Resolver) Reader(ctx context.Context, keys ...string) *gorm.DB {
+	if len(r.replicas) == 0 || r.isSticky(ctx, keys) {
+		return r.primary
+	}
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if replica := r.replicas[(start+i)%n]; replica.Healthy() {
+			return replica.DB
+		}
+	}
+	return r.primary
+}
+
+// Wrote starts the stickiness window of the caller of ctx and of keys.
+func (r *Resolver) Wrote(ctx context.Context, keys ...string) {
+	if len(r.replicas) == 0 || r.stickiness <= 0 {
+		return
+	}
+	now := r.now()
+	until := now.Add(r.stickiness)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if caller := Caller(ctx); caller != "" {
+		r.sticky[callerKey(caller)] = until
+	}
+	for _, key := range keys {
+		r.sticky[key] = until
+	}
+
+	// Drop expired windows once per window, so the map stays small
+	if now.Sub(r.pruned) >= r.stickiness {
+		for key, end := range r.sticky {
+			if !now.Before(end) {
+				delete(r.sticky, key)
+			}
+		}
+		r.pruned = now
+	}
+}
+
+func (r *Resolver) isSticky(ctx context.Context, keys []string) bool {
+	if r.stickiness <= 0 {
+		return false
+	}
+	now := r.now()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if caller := Caller(ctx); caller != "" {
+		if now.Before(r.sticky[callerKey(caller)]) {
+			return true
+		}
+	}
+	for _, key := range keys {
+		if now.Before(r.sticky[key]) {
+			return true
+		}
+	}
+	return false
+}
+
+// callerKey keeps caller identities apart from the keys passed by repositories.
+func callerKey(caller string) string {
+	return "caller:" + caller
+}
+
+// CheckHealth pings every replica. Replicas that fail are skipped by Reader
+// until a later check succeeds; changes are logged.
+func (r *Resolver) CheckHealth(ctx context.Context, timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, replica := range r.replicas {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			pingCtx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+
+			err := replica.ping(pingCtx)
+			if ctx.Err() != nil {
+				return
+			}
+			switch wasHealthy := replica.healthy.Swap(err == nil); {
+			case err != nil && wasHealthy:
+				slog.WarnContext(ctx, "Database replica failed its health check, routing its reads elsewhere",
+					slog.String("replica", replica.Name), slog.String("error", err.Error()))
+			case err == nil && !wasHealthy:
+				slog.InfoContext(ctx, "Database replica recovered", slog.String("replica", replica.Name))
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Run checks the health of the replicas every interval until ctx is done.
+func (r *Resolver) Run(ctx context.Context, interval time.Duration) {
+	if len(r.replicas) == 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			r.CheckHealth(ctx, interval)
+		}
+	}
+}
+
+// Close closes the replica connections; the primary is left open.
+func (r *Resolver) Close() error {
+	return closeReplicas(r.replicas)
+}
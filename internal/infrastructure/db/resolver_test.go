@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// fakeReplica returns a replica whose pings fail while down is set.
+func fakeReplica(name string, down *atomic.Bool) *Replica {
+	return NewReplica(name, &gorm.DB{}, func(context.Context) error {
+		if down.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	})
+}
+
+func newTestResolver(replicas ...*Replica) (*Resolver, *time.Time) {
+	r := NewResolver(&gorm.DB{}, replicas, 5*time.Second)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+	return r, &now
+}
+
+func TestResolver_WithoutReplicasUsesPrimary(t *testing.T) {
+	r, _ := newTestResolver()
+	ctx := context.Background()
+
+	r.Wrote(ctx, "user:1")
+
+	assert.Same(t, r.Writer(), r.Reader(ctx))
+	assert.Same(t, r.Writer(), r.Reader(ctx, "user:1"))
+}
+
+func TestResolver_ReadsAlternateBetweenReplicas(t *testing.T) {
+	var down atomic.Bool
+	a, b := fakeReplica("a", &down), fakeReplica("b", &down)
+	r, _ := newTestResolver(a, b)
+	ctx := context.Background()
+
+	first, second := r.Reader(ctx), r.Reader(ctx)
+
+	assert.NotSame(t, r.Writer(), first)
+	assert.NotSame(t, first, second)
+	assert.ElementsMatch(t, []*gorm.DB{a.DB, b.DB}, []*gorm.DB{first, second})
+	assert.Same(t, r.Writer(), r.Writer())
+}
+
+func TestResolver_StickinessAfterWrite(t *testing.T) {
+	var down atomic.Bool
+	r, now := newTestResolver(fakeReplica("a", &down))
+	writer := WithCaller(context.Background(), "10.0.0.1")
+	other := WithCaller(context.Background(), "10.0.0.2")
+
+	r.Wrote(writer, "user:1")
+
+	assert.Same(t, r.Writer(), r.Reader(writer), "the writing caller reads its writes")
+	assert.Same(t, r.Writer(), r.Reader(other, "user:1"), "the written key is read from the primary")
+	assert.NotSame(t, r.Writer(), r.Reader(other, "user:2"))
+	assert.NotSame(t, r.Writer(), r.Reader(context.Background()))
+
+	*now = now.Add(5 * time.Second)
+	assert.NotSame(t, r.Writer(), r.Reader(writer, "user:1"), "the window has ended")
+}
+
+func TestResolver_PrunesExpiredWindows(t *testing.T) {
+	var down atomic.Bool
+	r, now := newTestResolver(fakeReplica("a", &down))
+	ctx := context.Background()
+
+	r.Wrote(ctx, "user:1")
+	*now = now.Add(10 * time.Second)
+	r.Wrote(ctx, "user:2")
+
+	assert.Len(t, r.sticky, 1)
+}
+
+func TestResolver_EvictsUnhealthyReplicas(t *testing.T) {
+	var aDown, bDown atomic.Bool
+	a, b := fakeReplica("a", &aDown), fakeReplica("b", &bDown)
+	r, _ := newTestResolver(a, b)
+	ctx := context.Background()
+
+	aDown.Store(true)
+	r.CheckHealth(ctx, time.Second)
+
+	assert.False(t, a.Healthy())
+	for i := 0; i < 4; i++ {
+		assert.Same(t, b.DB, r.Reader(ctx))
+	}
+
+	bDown.Store(true)
+	r.CheckHealth(ctx, time.Second)
+	assert.Same(t, r.Writer(), r.Reader(ctx), "reads fall back to the primary")
+
+	aDown.Store(false)
+	r.CheckHealth(ctx, time.Second)
+	assert.True(t, a.Healthy())
+	assert.Same(t, a.DB, r.Reader(ctx))
+}
@@ -0,0 +1,82 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/interimme/userapi/internal/apidocs"
+	"github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/health"
+	"github.com/interimme/userapi/internal/metrics"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/status"
+)
+
+// NewGatewayMux creates the gRPC-Gateway mux. Errors are rendered as
+// application/problem+json, matching the Gin API.
+func NewGatewayMux(opts ...runtime.ServeMuxOption) *runtime.ServeMux {
+	opts = append([]runtime.ServeMuxOption{
+		runtime.WithErrorHandler(GatewayErrorHandler),
+		runtime.WithRoutingErrorHandler(GatewayRoutingErrorHandler),
+	}, opts...)
+	return runtime.NewServeMux(opts...)
+}
+
+// RegisterHealthHandlers exposes the liveness and readiness probes on the gateway mux.
+func RegisterHealthHandlers(mux *runtime.ServeMux, healthChecker *health.Checker) error {
+	if err := mux.HandlePath(http.MethodGet, "/healthz", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		healthChecker.Liveness(w, r)
+	}); err != nil {
+		return err
+	}
+	return mux.HandlePath(http.MethodGet, "/readyz", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		healthChecker.Readiness(w, r)
+	})
+}
+
+// RegisterMetricsHandler exposes the Prometheus metrics on the gateway mux.
+func RegisterMetricsHandler(mux *runtime.ServeMux, m *metrics.Metrics) error {
+	handler := m.Handler()
+	return mux.HandlePath(http.MethodGet, "/metrics", func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// RegisterDocsHandlers exposes the OpenAPI spec and the Swagger UI page on the gateway mux.
+func RegisterDocsHandlers(mux *runtime.ServeMux) error {
+	if err := mux.HandlePath(http.MethodGet, apidocs.SpecPath, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		apidocs.SpecHandler(w, r)
+	}); err != nil {
+		return err
+	}
+	docs := apidocs.DocsHandler()
+	for _, pattern := range []string{"/docs", "/docs/{file=**}"} {
+		if err := mux.HandlePath(http.MethodGet, pattern, func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+			docs.ServeHTTP(w, r)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GatewayErrorHandler writes errors returned by the gRPC backend as problem documents.
+func GatewayErrorHandler(_ context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	var httpErr *runtime.HTTPStatusError
+	if errors.As(err, &httpErr) {
+		apperrors.WriteProblem(w, apperrors.NewProblemFromHTTPStatus(httpErr.HTTPStatus, status.Convert(httpErr.Err).Message(), r.URL.Path))
+		return
+	}
+	apperrors.WriteProblem(w, apperrors.NewProblem(err, r.URL.Path))
+}
+
+// GatewayRoutingErrorHandler keeps the HTTP status of routing failures (404, 405)
+// instead of translating them through gRPC codes.
+func GatewayRoutingErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, httpStatus int) {
+	GatewayErrorHandler(ctx, mux, marshaler, w, r, &runtime.HTTPStatusError{
+		HTTPStatus: httpStatus,
+		Err:        status.Error(apperrors.CodeFromHTTPStatus(httpStatus), http.StatusText(httpStatus)),
+	})
+}
@@ -0,0 +1,222 @@
+package infrastructure
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/auth"
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/grpcserver"
+	"github.com/interimme/userapi/internal/infrastructure/persistence"
+	"github.com/interimme/userapi/internal/usecase"
+	userapi "github.com/interimme/userapi/proto"
+)
+
+func TestRegisterDocsHandlers(t *testing.T) {
+	mux := NewGatewayMux()
+	require.NoError(t, RegisterDocsHandlers(mux))
+
+	for path, want := range map[string]int{
+		"/openapi.json":              http.StatusOK,
+		"/docs":                      http.StatusMovedPermanently,
+		"/docs/":                     http.StatusOK,
+		"/docs/swagger-ui-bundle.js": http.StatusOK,
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, want, rec.Code, path)
+	}
+}
+
+func TestGateway_SearchUsers(t *testing.T) {
+	repo := persistence.NewMemoryUserRepository()
+	for _, u := range []*entity.User{
+		{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36},
+		{ID: uuid.New(), Firstname: "Alan", Lastname: "Turing", Email: "alan@example.com", Age: 41},
+	} {
+		require.NoError(t, repo.Create(context.Background(), u))
+	}
+	mux := NewGatewayMux()
+	uc := usecase.NewUserUseCase(repo, usecase.WithAuthorizer(auth.Operator{}))
+	require.NoError(t, userapi.RegisterUserServiceHandlerServer(context.Background(), mux, grpcserver.NewServer(uc)))
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/search?q=love&pageSize=5", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var body struct {
+		Results []struct {
+			User       struct{ Firstname string }
+			Highlights map[string]string
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Results, 1)
+	assert.Equal(t, "Ada", body.Results[0].User.Firstname)
+	assert.Equal(t, map[string]string{"lastname": "<mark>Love</mark>lace"}, body.Results[0].Highlights)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users/search?q=%40", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGateway_ListUsers(t *testing.T) {
+	repo := persistence.NewMemoryUserRepository()
+	for _, u := range []*entity.User{
+		{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36},
+		{ID: uuid.New(), Firstname: "Alan", Lastname: "Turing", Email: "alan@example.com", Age: 41},
+		{ID: uuid.New(), Firstname: "Grace", Lastname: "Hopper", Email: "grace@example.org", Age: 85},
+	} {
+		require.NoError(t, repo.Create(context.Background(), u))
+	}
+	mux := NewGatewayMux()
+	uc := usecase.NewUserUseCase(repo, usecase.WithAuthorizer(auth.Operator{}))
+	require.NoError(t, userapi.RegisterUserServiceHandlerServer(context.Background(), mux, grpcserver.NewServer(uc)))
+
+	var firstnames []string
+	token := ""
+	for range 3 {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?filter=email%3A%22*%40example.com%22&pageSize=1&pageToken="+token, nil))
+		require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+		var body struct {
+			Users         []struct{ Firstname string }
+			NextPageToken string
+		}
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		for _, u := range body.Users {
+			firstnames = append(firstnames, u.Firstname)
+		}
+		if token = body.NextPageToken; token == "" {
+			break
+		}
+	}
+	assert.ElementsMatch(t, []string{"Ada", "Alan"}, firstnames)
+
+	// Invalid filters reach the client with their position
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?filter=nickname%3Dx", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `invalid filter at column 1: unknown field \"nickname\"`)
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users?pageToken=next", nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGateway_BatchUsers(t *testing.T) {
+	repo := persistence.NewMemoryUserRepository()
+	ada := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	require.NoError(t, repo.Create(context.Background(), ada))
+	mux := NewGatewayMux()
+	require.NoError(t, userapi.RegisterUserServiceHandlerServer(context.Background(), mux, grpcserver.NewServer(usecase.NewUserUseCase(repo))))
+
+	type result struct {
+		ID    string
+		User  *struct{ Firstname string }
+		Error *struct {
+			Code    int
+			Message string
+		}
+	}
+	var body struct{ Results []result }
+
+	missing := uuid.New()
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/users:batchGet?ids="+ada.ID.String()+"&ids="+missing.String(), nil))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Results, 2)
+	assert.Equal(t, "Ada", body.Results[0].User.Firstname)
+	assert.Equal(t, missing.String(), body.Results[1].ID)
+	assert.Equal(t, 5, body.Results[1].Error.Code)
+
+	// An atomic batch fails as a whole, naming the user at fault
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users:batchCreate", strings.NewReader(`{"atomic": true, "users": [
+		{"firstname": "Alan", "lastname": "Turing", "email": "alan@example.com", "age": 41},
+		{"firstname": "Ada", "lastname": "King", "email": "ada@example.com", "age": 36}]}`)))
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Contains(t, rec.Body.String(), "users[1]: email already exists")
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users:batchDelete", strings.NewReader(`{"ids": ["`+ada.ID.String()+`", "`+missing.String()+`"]}`)))
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	body.Results = nil
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	require.Len(t, body.Results, 2)
+	assert.Equal(t, result{ID: ada.ID.String()}, body.Results[0])
+	assert.Equal(t, 5, body.Results[1].Error.Code)
+}
+
+func TestGateway_LookupRequiresAuthorization(t *testing.T) {
+	repo := persistence.NewMemoryUserRepository()
+	ada := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	require.NoError(t, repo.Create(context.Background(), ada))
+	mux := NewGatewayMux()
+	uc := usecase.NewUserUseCase(repo, usecase.WithAuthorizer(auth.NewAPIKeys([]string{"secret"})))
+	require.NoError(t, userapi.RegisterUserServiceHandlerServer(context.Background(), mux, grpcserver.NewServer(uc)))
+
+	// Existing and missing users are indistinguishable without a key
+	for _, path := range []string{
+		"/users:lookup?email=ada@example.com",
+		"/users:lookup?email=nobody@example.com",
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+	}
+	for _, id := range []uuid.UUID{ada.ID, uuid.New()} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodHead, "/user/"+id.String(), nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	}
+
+	// Searching or listing by the address reveals no more than the lookup
+	for _, path := range []string{
+		"/users/search?q=ada%40example.com",
+		"/users?filter=email%3D%22ada%40example.com%22",
+	} {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code, path)
+		assert.NotContains(t, rec.Body.String(), "Lovelace", path)
+	}
+}
+
+func TestGateway_DateOfBirth(t *testing.T) {
+	repo := persistence.NewMemoryUserRepository()
+	mux := NewGatewayMux()
+	require.NoError(t, userapi.RegisterUserServiceHandlerServer(context.Background(), mux, grpcserver.NewServer(usecase.NewUserUseCase(repo))))
+	dateOfBirth := entity.BornBy(30, time.Now())
+
+	rec := httptest.NewRecorder()
+	body := `{"firstname": "Ada", "lastname": "Lovelace", "email": "ada@example.com", "dateOfBirth": "` + dateOfBirth.Format(time.DateOnly) + `"}`
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body)))
+
+	require.Equal(t, http.StatusOK, rec.Code, rec.Body.String())
+	var created struct {
+		User struct {
+			Age         uint32
+			DateOfBirth string
+		}
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &created))
+	assert.Equal(t, uint32(30), created.User.Age)
+	assert.Equal(t, dateOfBirth.Format(time.DateOnly), created.User.DateOfBirth)
+
+	rec = httptest.NewRecorder()
+	body = `{"firstname": "Alan", "lastname": "Turing", "email": "alan@example.com", "dateOfBirth": "23.06.1912"}`
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body)))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), `date_of_birth must be a date like 2000-01-31`)
+}
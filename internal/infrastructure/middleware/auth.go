@@ -0,0 +1,16 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/interimme/userapi/internal/auth"
+)
+
+// APIKey stores the API key of the "Authorization: Bearer <key>" header in
+// the request context for the use cases to authorize.
+func APIKey(c *gin.Context) {
+	if key := auth.FromHeader(c.GetHeader("Authorization")); key != "" {
+		c.Request = c.Request.WithContext(auth.WithAPIKey(c.Request.Context(), key))
+	}
+	c.Next()
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/interimme/userapi/internal/infrastructure/db"
+)
+
+// Caller identifies the client by its IP address for the database, so that
+// it reads its own writes when reads go to replicas. X-Forwarded-For is only
+// honored from the router's trusted proxies.
+func Caller(c *gin.Context) {
+	c.Request = c.Request.WithContext(db.WithCaller(c.Request.Context(), c.ClientIP()))
+	c.Next()
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/interimme/userapi/internal/apperrors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ErrorHandler renders the last error attached to the Gin context as an
+// RFC 7807 application/problem+json document.
+func ErrorHandler(c *gin.Context) {
+	c.Next() // Execute the handlers
+
+	// Nothing to do if no errors were set or a response has already been written
+	if len(c.Errors) == 0 || c.Writer.Written() {
+		return
+	}
+
+	err := c.Errors.Last().Err
+
+	// Oversized bodies are reported with 413 rather than through a gRPC code
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		detail := fmt.Sprintf("request body exceeds %d bytes", maxBytesErr.Limit)
+		apperrors.WriteProblem(c.Writer, apperrors.NewProblemFromHTTPStatus(http.StatusRequestEntityTooLarge, detail, c.Request.URL.Path))
+		return
+	}
+
+	// Errors hidden behind a generic 500 are logged so they are not lost
+	var appErr *apperrors.AppError
+	if !errors.As(err, &appErr) {
+		slog.ErrorContext(c.Request.Context(), "Request failed", slog.String("error", err.Error()))
+	}
+
+	apperrors.WriteProblem(c.Writer, apperrors.NewProblem(err, c.Request.URL.Path))
+}
+
+// Recovery converts panics into a 500 problem document instead of an empty
+// response and logs the panic. Use it with gin.CustomRecoveryWithWriter.
+func Recovery(logger *slog.Logger) gin.RecoveryFunc {
+	return func(c *gin.Context, recovered any) {
+		logger.ErrorContext(c.Request.Context(), "Panic recovered",
+			slog.Any("panic", recovered),
+			slog.String("stack", string(debug.Stack())),
+		)
+		apperrors.WriteProblem(c.Writer, apperrors.NewProblem(apperrors.ErrInternalServerError, c.Request.URL.Path))
+		c.Abort()
+	}
+}
+
+// NoRoute renders a 404 problem document for unknown paths.
+func NoRoute(c *gin.Context) {
+	apperrors.WriteProblem(c.Writer, apperrors.NewProblemFromHTTPStatus(http.StatusNotFound, http.StatusText(http.StatusNotFound), c.Request.URL.Path))
+}
+
+// NoMethod renders a 405 problem document for known paths requested with an unsupported method.
+func NoMethod(c *gin.Context) {
+	apperrors.WriteProblem(c.Writer, apperrors.NewProblemFromHTTPStatus(http.StatusMethodNotAllowed, http.StatusText(http.StatusMethodNotAllowed), c.Request.URL.Path))
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/interimme/userapi/internal/logging"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestLogger accepts the X-Request-ID header (or generates an ID), makes
+// it available to handlers through the request context, echoes it in the
+// response and writes a structured access log entry for every request.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := logging.EnsureRequestID(c.GetHeader(logging.RequestIDHeader))
+		c.Header(logging.RequestIDHeader, id)
+		c.Request = c.Request.WithContext(logging.WithRequestID(c.Request.Context(), id))
+
+		start := time.Now()
+		c.Next()
+
+		logging.LogRequest(c.Request.Context(), logger, "gin", c.Request.Method, c.Request.URL.Path, c.Writer.Status(), time.Since(start))
+	}
+}
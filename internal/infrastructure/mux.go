@@ -0,0 +1,186 @@
+package infrastructure
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/interimme/userapi/internal/apperrors"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+// SinglePortHandler multiplexes gRPC, the gRPC-Gateway and the Gin API on one listener.
+// gRPC requests are recognized by HTTP/2 and the application/grpc content type; the
+// two REST surfaces are mounted under path prefixes. An empty prefix mounts a surface
+// at the root, where it receives every request not claimed by the other prefix.
+type SinglePortHandler struct {
+	GRPC          http.Handler
+	Gateway       http.Handler
+	Gin           http.Handler
+	GatewayPrefix string
+	GinPrefix     string
+
+	// In-flight gRPC calls. Once draining, new calls are refused and idle is
+	// closed when the last call finishes.
+	grpcMu       sync.Mutex
+	grpcInflight int
+	grpcDraining bool
+	grpcIdle     chan struct{}
+}
+
+// NewSinglePortHandler creates the multiplexing handler.
+func NewSinglePortHandler(grpcHandler, gateway, gin http.Handler, gatewayPrefix, ginPrefix string) *SinglePortHandler {
+	return &SinglePortHandler{
+		GRPC:          grpcHandler,
+		Gateway:       gateway,
+		Gin:           gin,
+		GatewayPrefix: strings.TrimSuffix(gatewayPrefix, "/"),
+		GinPrefix:     strings.TrimSuffix(ginPrefix, "/"),
+	}
+}
+
+// ServeHTTP dispatches the request to gRPC, the gateway or Gin.
+func (h *SinglePortHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if isGRPCRequest(r) {
+		if !h.startGRPC() {
+			writeGRPCUnavailable(w)
+			return
+		}
+		defer h.finishGRPC()
+		h.GRPC.ServeHTTP(w, r)
+		return
+	}
+
+	switch {
+	case h.GatewayPrefix != "" && hasPathPrefix(r.URL.Path, h.GatewayPrefix):
+		http.StripPrefix(h.GatewayPrefix, h.Gateway).ServeHTTP(w, r)
+	case h.GinPrefix != "" && hasPathPrefix(r.URL.Path, h.GinPrefix):
+		http.StripPrefix(h.GinPrefix, h.Gin).ServeHTTP(w, r)
+	case h.GatewayPrefix == "":
+		h.Gateway.ServeHTTP(w, r)
+	case h.GinPrefix == "":
+		h.Gin.ServeHTTP(w, r)
+	default:
+		apperrors.WriteProblem(w, apperrors.NewProblemFromHTTPStatus(http.StatusNotFound, http.StatusText(http.StatusNotFound), r.URL.Path))
+	}
+}
+
+// isGRPCRequest reports whether r is a gRPC call rather than a REST request.
+func isGRPCRequest(r *http.Request) bool {
+	return r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc")
+}
+
+// hasPathPrefix reports whether path is prefix itself or lies below it.
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// startGRPC counts a new gRPC call, or reports false once draining started.
+// Streams can still arrive on hijacked h2c connections after GOAWAY.
+func (h *SinglePortHandler) startGRPC() bool {
+	h.grpcMu.Lock()
+	defer h.grpcMu.Unlock()
+	if h.grpcDraining {
+		return false
+	}
+	h.grpcInflight++
+	return true
+}
+
+// finishGRPC counts a gRPC call as finished.
+func (h *SinglePortHandler) finishGRPC() {
+	h.grpcMu.Lock()
+	defer h.grpcMu.Unlock()
+	h.grpcInflight--
+	if h.grpcDraining && h.grpcInflight == 0 {
+		close(h.grpcIdle)
+	}
+}
+
+// writeGRPCUnavailable answers a gRPC call with status Unavailable, so that
+// clients retry it on another instance.
+func writeGRPCUnavailable(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Grpc-Status", strconv.Itoa(int(codes.Unavailable)))
+	w.Header().Set("Grpc-Message", "server is shutting down")
+	w.WriteHeader(http.StatusOK)
+}
+
+// waitGRPC refuses new gRPC calls and blocks until in-flight calls have
+// finished or ctx is done.
+func (h *SinglePortHandler) waitGRPC(ctx context.Context) error {
+	h.grpcMu.Lock()
+	if !h.grpcDraining {
+		h.grpcDraining = true
+		h.grpcIdle = make(chan struct{})
+		if h.grpcInflight == 0 {
+			close(h.grpcIdle)
+		}
+	}
+	idle := h.grpcIdle
+	h.grpcMu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// SinglePortServer serves a SinglePortHandler over HTTP/1.1 and cleartext HTTP/2 (h2c).
+// It implements lifecycle.Component.
+type SinglePortServer struct {
+	server   *http.Server
+	listener net.Listener
+	handler  *SinglePortHandler
+	grpc     *grpc.Server
+}
+
+// NewSinglePortServer creates a server for handler on listener. grpcServer must
+// be the server behind handler.GRPC; it is stopped once in-flight calls finish.
+func NewSinglePortServer(listener net.Listener, handler *SinglePortHandler, grpcServer *grpc.Server) (*SinglePortServer, error) {
+	h2s := &http2.Server{}
+	server := &http.Server{Handler: h2c.NewHandler(handler, h2s)}
+
+	// Registers h2s with the server so that Shutdown sends GOAWAY on h2c connections
+	if err := http2.ConfigureServer(server, h2s); err != nil {
+		return nil, err
+	}
+
+	return &SinglePortServer{
+		server:   server,
+		listener: listener,
+		handler:  handler,
+		grpc:     grpcServer,
+	}, nil
+}
+
+// Start serves requests until the server is shut down.
+func (s *SinglePortServer) Start() error {
+	if err := s.server.Serve(s.listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown drains REST requests and gRPC calls. h2c connections are hijacked
+// from the HTTP server, so in-flight gRPC calls are awaited separately.
+// grpc.Server.GracefulStop is not supported for handlers served via ServeHTTP.
+func (s *SinglePortServer) Shutdown(ctx context.Context) error {
+	defer s.grpc.Stop()
+
+	if err := s.server.Shutdown(ctx); err != nil {
+		_ = s.server.Close()
+		return err
+	}
+	return s.handler.waitGRPC(ctx)
+}
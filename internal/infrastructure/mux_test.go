@@ -0,0 +1,108 @@
+package infrastructure
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// namedHandler writes its name and the path it received.
+func namedHandler(name string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(name + " " + r.URL.Path))
+	})
+}
+
+func newTestSinglePortHandler(gatewayPrefix, ginPrefix string) *SinglePortHandler {
+	return &SinglePortHandler{
+		GRPC:          namedHandler("grpc"),
+		Gateway:       namedHandler("gateway"),
+		Gin:           namedHandler("gin"),
+		GatewayPrefix: gatewayPrefix,
+		GinPrefix:     ginPrefix,
+	}
+}
+
+func TestSinglePortHandler_Routing(t *testing.T) {
+	h := newTestSinglePortHandler("/gateway", "")
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/gateway/users", "gateway /users"},
+		{"/gateway/user/123", "gateway /user/123"},
+		{"/users", "gin /users"},
+		{"/gatewayish", "gin /gatewayish"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.path, nil))
+		assert.Equal(t, tt.want, w.Body.String(), tt.path)
+	}
+}
+
+func TestSinglePortHandler_GRPC(t *testing.T) {
+	h := newTestSinglePortHandler("/gateway", "")
+
+	req := httptest.NewRequest(http.MethodPost, "/userapi.UserService/GetUser", nil)
+	req.ProtoMajor = 2
+	req.Header.Set("Content-Type", "application/grpc")
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, req)
+
+	assert.Equal(t, "grpc /userapi.UserService/GetUser", w.Body.String())
+}
+
+func TestSinglePortHandler_BothPrefixed(t *testing.T) {
+	h := newTestSinglePortHandler("/gateway", "/api")
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+	assert.Equal(t, "gin /users", w.Body.String())
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/other", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestSinglePortHandler_DrainRefusesNewGRPCCalls(t *testing.T) {
+	h := newTestSinglePortHandler("/gateway", "")
+	started, release := make(chan struct{}), make(chan struct{})
+	h.GRPC = http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		close(started)
+		<-release
+	})
+	grpcRequest := func() *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/userapi.UserService/GetUser", nil)
+		req.ProtoMajor = 2
+		req.Header.Set("Content-Type", "application/grpc")
+		return req
+	}
+
+	go h.ServeHTTP(httptest.NewRecorder(), grpcRequest())
+	<-started
+	waited := make(chan error)
+	go func() { waited <- h.waitGRPC(context.Background()) }()
+
+	// A stream arriving on an open h2c connection while draining is refused
+	require.Eventually(t, func() bool {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, grpcRequest())
+		return w.Header().Get("Grpc-Status") == "14"
+	}, time.Second, time.Millisecond)
+
+	select {
+	case <-waited:
+		t.Fatal("waitGRPC returned while a call was in flight")
+	default:
+	}
+	close(release)
+	assert.NoError(t, <-waited)
+}
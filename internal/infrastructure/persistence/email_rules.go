@@ -0,0 +1,108 @@
+package persistence
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// emailRulesSetting is the key of the settings row naming the rules the
+// stored canonical email addresses were computed with.
+const emailRulesSetting = "email_rules"
+
+// EmailRules returns the name of the rules the stored canonical email
+// addresses were computed with; see email.RuleSet.
+func EmailRules(ctx context.Context, conn *gorm.DB) (string, error) {
+	var rules string
+	err := conn.WithContext(ctx).Raw("SELECT value FROM settings WHERE key = ?", emailRulesSetting).Scan(&rules).Error
+	return rules, err
+}
+
+// StoredEmail is the email address of a user with its canonical form.
+type StoredEmail struct {
+	ID        uuid.UUID
+	Email     string
+	Canonical string
+}
+
+// Recanonicalization is the outcome of RecanonicalizeEmails.
+type Recanonicalization struct {
+	// Users is the number of users.
+	Users int
+	// Changed lists the users whose canonical form changes, with the new form.
+	Changed []StoredEmail
+	// Collisions lists the users sharing a new canonical form, by that form.
+	Collisions map[string][]StoredEmail
+}
+
+// RecanonicalizeEmails recomputes the canonical email address of every user
+// with canonical and records rules as the rules of the stored addresses.
+// Writes to the users wait meanwhile. Nothing changes with dryRun or if
+// several users would share a canonical address; the report lists the
+// changes and collisions either way.
+func RecanonicalizeEmails(ctx context.Context, conn *gorm.DB, rules string, canonical func(address string) (string, error), dryRun bool) (*Recanonicalization, error) {
+	var report *Recanonicalization
+	err := conn.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("LOCK TABLE user_gorms IN SHARE ROW EXCLUSIVE MODE").Error; err != nil {
+			return err
+		}
+		var stored []StoredEmail
+		if err := tx.Model(&UserGorm{}).Select("id", "email", "canonical_email AS canonical").Order("id").Scan(&stored).Error; err != nil {
+			return err
+		}
+
+		var err error
+		if report, err = recanonicalize(stored, canonical); err != nil {
+			return err
+		}
+		if dryRun || len(report.Collisions) > 0 {
+			return nil
+		}
+
+		// The unique index is checked row by row, so move the changed forms out
+		// of the way first in case they are swapped
+		for _, c := range report.Changed {
+			if err := tx.Model(&UserGorm{}).Where("id = ?", c.ID).Update("canonical_email", "recanonicalizing:"+c.ID.String()).Error; err != nil {
+				return err
+			}
+		}
+		for _, c := range report.Changed {
+			if err := tx.Model(&UserGorm{}).Where("id = ?", c.ID).Update("canonical_email", c.Canonical).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec("INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value",
+			emailRulesSetting, rules).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// recanonicalize computes the new canonical forms of the stored addresses
+// and the forms shared by several users.
+func recanonicalize(stored []StoredEmail, canonical func(address string) (string, error)) (*Recanonicalization, error) {
+	report := &Recanonicalization{Users: len(stored), Collisions: make(map[string][]StoredEmail)}
+	byCanonical := make(map[string][]StoredEmail, len(stored))
+	for _, s := range stored {
+		form, err := canonical(s.Email)
+		if err != nil {
+			return nil, fmt.Errorf("user %s: %q: %w", s.ID, s.Email, err)
+		}
+		if form != s.Canonical {
+			report.Changed = append(report.Changed, StoredEmail{ID: s.ID, Email: s.Email, Canonical: form})
+		}
+		byCanonical[form] = append(byCanonical[form], StoredEmail{ID: s.ID, Email: s.Email, Canonical: form})
+	}
+	for form, users := range byCanonical {
+		if len(users) > 1 {
+			sort.Slice(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+			report.Collisions[form] = users
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,51 @@
+package persistence
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/email"
+)
+
+func TestRecanonicalize(t *testing.T) {
+	withProviderRules := func(address string) (string, error) {
+		addr, err := email.Parse(address)
+		return addr.WithProviderRules().Canonical(), err
+	}
+	ada, alice, aliceDots, bob := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	stored := []StoredEmail{
+		{ID: ada, Email: "Ada+news@Gmail.com", Canonical: "ada+news@gmail.com"},
+		{ID: alice, Email: "alice@gmail.com", Canonical: "alice@gmail.com"},
+		{ID: aliceDots, Email: "a.lice@googlemail.com", Canonical: "a.lice@googlemail.com"},
+		{ID: bob, Email: "bob@example.com", Canonical: "bob@example.com"},
+	}
+
+	report, err := recanonicalize(stored, withProviderRules)
+
+	require.NoError(t, err)
+	assert.Equal(t, 4, report.Users)
+	assert.Equal(t, []StoredEmail{
+		{ID: ada, Email: "Ada+news@Gmail.com", Canonical: "ada@gmail.com"},
+		{ID: aliceDots, Email: "a.lice@googlemail.com", Canonical: "alice@gmail.com"},
+	}, report.Changed)
+	assert.Equal(t, map[string][]StoredEmail{
+		"alice@gmail.com": {
+			{ID: aliceDots, Email: "a.lice@googlemail.com", Canonical: "alice@gmail.com"},
+			{ID: alice, Email: "alice@gmail.com", Canonical: "alice@gmail.com"},
+		},
+	}, report.Collisions)
+}
+
+func TestRecanonicalize_InvalidAddress(t *testing.T) {
+	id := uuid.New()
+	_, err := recanonicalize([]StoredEmail{{ID: id, Email: "not an address"}}, func(address string) (string, error) {
+		addr, err := email.Parse(address)
+		return strings.ToLower(addr.Canonical()), err
+	})
+
+	assert.ErrorContains(t, err, "user "+id.String())
+}
@@ -0,0 +1,205 @@
+package persistence
+
+import (
+	"bytes"
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
+	"github.com/interimme/userapi/internal/search"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+// memoryUserRepository implements the UserRepository interface in memory.
+// It reports the same GORM errors as the database, including the unique email
+// constraint, so the use cases behave the same on top of it.
+type memoryUserRepository struct {
+	mu    sync.RWMutex
+	users map[uuid.UUID]entity.User
+}
+
+// NewMemoryUserRepository creates an empty in-memory UserRepository, e.g. for
+// fakes and tests.
+func NewMemoryUserRepository() usecase.UserRepository {
+	return &memoryUserRepository{users: make(map[uuid.UUID]entity.User)}
+}
+
+func (r *memoryUserRepository) Create(_ context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.users[user.ID]; ok || r.emailTaken(user) {
+		return gorm.ErrDuplicatedKey
+	}
+	setCreated(user)
+	r.store(user)
+	return nil
+}
+
+func (r *memoryUserRepository) GetByID(_ context.Context, id uuid.UUID) (*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	user, ok := r.users[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &user, nil
+}
+
+// GetByIDForUpdate is GetByID; the memory repository has no transactions.
+func (r *memoryUserRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	return r.GetByID(ctx, id)
+}
+
+func (r *memoryUserRepository) GetByEmail(_ context.Context, canonicalEmail string) (*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, user := range r.users {
+		if user.CanonicalEmail == canonicalEmail {
+			return &user, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *memoryUserRepository) Exists(_ context.Context, id uuid.UUID) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.users[id]
+	return ok, nil
+}
+
+func (r *memoryUserRepository) GetByIDs(_ context.Context, ids []uuid.UUID) ([]*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var users []*entity.User
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok {
+			users = append(users, &user)
+		}
+	}
+	return users, nil
+}
+
+// Update saves user like GORM's Save, which inserts missing rows.
+func (r *memoryUserRepository) Update(_ context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.emailTaken(user) {
+		return gorm.ErrDuplicatedKey
+	}
+	setUpdated(user)
+	r.store(user)
+	return nil
+}
+
+func (r *memoryUserRepository) Delete(_ context.Context, user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.users, user.ID)
+	return nil
+}
+
+func (r *memoryUserRepository) DeleteByIDs(_ context.Context, ids []uuid.UUID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var deleted int64
+	for _, id := range ids {
+		if _, ok := r.users[id]; ok {
+			delete(r.users, id)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+func (r *memoryUserRepository) List(_ context.Context, f *filter.Filter, afterID uuid.UUID, limit int) ([]*entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	users := make([]*entity.User, 0, len(r.users))
+	for id, user := range r.users {
+		if bytes.Compare(id[:], afterID[:]) > 0 && f.Match(userField(&user)) {
+			users = append(users, &user)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return bytes.Compare(users[i].ID[:], users[j].ID[:]) < 0
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+// Search matches the query's words against names and email addresses as
+// prefixes or substrings of their words; there is no fuzzy matching.
+func (r *memoryUserRepository) Search(_ context.Context, query string, limit int) ([]*entity.UserMatch, error) {
+	terms := search.Terms(query)
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var matches []*entity.UserMatch
+	for _, user := range r.users {
+		if score := search.Score(terms, user.Firstname, user.Lastname, user.Email); score > 0 {
+			matches = append(matches, &entity.UserMatch{User: &user, Score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return bytes.Compare(matches[i].User.ID[:], matches[j].User.ID[:]) < 0
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// userField returns the filter values of user by field name.
+func userField(user *entity.User) func(field string) any {
+	return func(field string) any {
+		switch field {
+		case "id":
+			return user.ID
+		case "firstname":
+			return user.Firstname
+		case "lastname":
+			return user.Lastname
+		case "email":
+			return user.Email
+		case "age":
+			return int64(user.CurrentAge())
+		case "date_of_birth":
+			if user.DateOfBirth.IsZero() {
+				return nil
+			}
+			return user.DateOfBirth
+		case "created":
+			return user.Created
+		case "updated":
+			return user.Updated
+		}
+		return nil
+	}
+}
+
+// store saves a copy of user with its canonical email address filled in.
+func (r *memoryUserRepository) store(user *entity.User) {
+	stored := *user
+	stored.CanonicalEmail = canonicalEmail(user)
+	r.users[user.ID] = stored
+}
+
+// emailTaken reports whether another user has the canonical email of user.
+func (r *memoryUserRepository) emailTaken(user *entity.User) bool {
+	for id, other := range r.users {
+		if id != user.ID && other.CanonicalEmail == canonicalEmail(user) {
+			return true
+		}
+	}
+	return false
+}
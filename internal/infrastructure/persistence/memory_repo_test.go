@@ -0,0 +1,114 @@
+package persistence
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+func TestMemoryUserRepository_ListFilter(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	created := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, u := range []*entity.User{
+		{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36, Created: created},
+		{ID: uuid.New(), Firstname: "Alan", Lastname: "Turing", Email: "alan@example.org", Age: 41, Created: created},
+		{ID: uuid.New(), Firstname: "Kid", Lastname: "Young", Email: "kid@example.com", Age: 12, Created: created.AddDate(-1, 0, 0)},
+	} {
+		require.NoError(t, repo.Create(context.Background(), u))
+	}
+	f, err := filter.Parse(`age >= 18 AND email:"*@EXAMPLE.com" AND created > "2025-01-01T00:00:00Z"`, usecase.UserFilterFields)
+	require.NoError(t, err)
+
+	users, err := repo.List(context.Background(), f, uuid.Nil, 10)
+
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "Ada", users[0].Firstname)
+}
+
+func TestMemoryUserRepository_ListFilterAge(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	today := time.Now().UTC()
+	for _, u := range []*entity.User{
+		{ID: uuid.New(), Firstname: "Adult", Lastname: "Today", Email: "adult@example.com", DateOfBirth: entity.BornBy(18, today)},
+		{ID: uuid.New(), Firstname: "Minor", Lastname: "Tomorrow", Email: "minor@example.com", DateOfBirth: entity.BornBy(18, today).AddDate(0, 0, 1)},
+		{ID: uuid.New(), Firstname: "Legacy", Lastname: "Age", Email: "legacy@example.com", Age: 40},
+	} {
+		require.NoError(t, repo.Create(context.Background(), u))
+	}
+	f, err := filter.Parse(`age >= 18`, usecase.UserFilterFields)
+	require.NoError(t, err)
+
+	users, err := repo.List(context.Background(), f, uuid.Nil, 10)
+
+	require.NoError(t, err)
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Firstname
+	}
+	assert.ElementsMatch(t, []string{"Adult", "Legacy"}, names)
+}
+
+// Users without a date of birth match neither a restriction on it nor its
+// negation, as with NULL in the SQL repository.
+func TestMemoryUserRepository_ListFilterMissingDateOfBirth(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	for _, u := range []*entity.User{
+		{ID: uuid.New(), Firstname: "Old", Lastname: "Born", Email: "old@example.com", DateOfBirth: time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), Firstname: "Young", Lastname: "Born", Email: "young@example.com", DateOfBirth: time.Date(2005, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{ID: uuid.New(), Firstname: "Legacy", Lastname: "Age", Email: "legacy@example.com", Age: 40},
+	} {
+		require.NoError(t, repo.Create(context.Background(), u))
+	}
+
+	for expr, want := range map[string][]string{
+		`date_of_birth < "2000-01-01"`:                           {"Old"},
+		`NOT date_of_birth < "2000-01-01"`:                       {"Young"},
+		`date_of_birth != "1980-01-01"`:                          {"Young"},
+		`NOT date_of_birth < "2000-01-01" OR firstname = Legacy`: {"Young", "Legacy"},
+	} {
+		f, err := filter.Parse(expr, usecase.UserFilterFields)
+		require.NoError(t, err, expr)
+
+		users, err := repo.List(context.Background(), f, uuid.Nil, 10)
+
+		require.NoError(t, err, expr)
+		names := []string{}
+		for _, u := range users {
+			names = append(names, u.Firstname)
+		}
+		assert.ElementsMatch(t, want, names, expr)
+	}
+}
+
+func TestMemoryUserRepository_Timestamps(t *testing.T) {
+	repo := NewMemoryUserRepository()
+	ctx := context.Background()
+
+	user := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	require.NoError(t, repo.Create(ctx, user))
+	assert.False(t, user.Created.IsZero())
+	assert.Equal(t, user.Created, user.Updated)
+	assert.Equal(t, user.Created, user.Created.Truncate(time.Microsecond), "stored with microsecond precision")
+
+	created := user.Created
+	time.Sleep(time.Millisecond)
+	require.NoError(t, repo.Update(ctx, user))
+	got, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created, got.Created)
+	assert.True(t, got.Updated.After(created))
+
+	imported := &entity.User{ID: uuid.New(), Firstname: "Alan", Lastname: "Turing", Email: "alan@example.com", Age: 41,
+		Created: time.Date(2020, 1, 2, 3, 4, 5, 123456789, time.UTC)}
+	require.NoError(t, repo.Create(ctx, imported))
+	assert.Equal(t, time.Date(2020, 1, 2, 3, 4, 5, 123456000, time.UTC), imported.Created, "imported creation times are kept")
+}
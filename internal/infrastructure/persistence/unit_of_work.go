@@ -0,0 +1,106 @@
+package persistence
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+
+	"github.com/interimme/userapi/internal/infrastructure/db"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+// retryDelay is the base delay before a transaction is retried; the delay
+// grows with every attempt and is randomized.
+const retryDelay = 10 * time.Millisecond
+
+// txOptions are the options of every transaction. Under repeatable read a
+// transaction that writes a row changed concurrently since its snapshot, as
+// the read-modify-write of an update does, fails with a serialization failure
+// instead of overwriting the change, so it can be retried.
+var txOptions = &sql.TxOptions{Isolation: sql.LevelRepeatableRead}
+
+// unitOfWork implements usecase.UnitOfWork with transactions on the primary.
+type unitOfWork struct {
+	dbs      *db.Resolver
+	attempts int
+}
+
+// NewUnitOfWork creates a UnitOfWork running repeatable read transactions on
+// the primary of resolver. A transaction that fails with a serialization failure or a
+// deadlock is run again, up to attempts times in total.
+func NewUnitOfWork(resolver *db.Resolver, attempts int) usecase.UnitOfWork {
+	return &unitOfWork{dbs: resolver, attempts: max(attempts, 1)}
+}
+
+func (u *unitOfWork) WithinTx(ctx context.Context, fn func(ctx context.Context, repos usecase.Repositories) error) error {
+	return retry(ctx, u.attempts, func() error {
+		tx := &txConns{}
+		err := u.dbs.Writer().WithContext(ctx).Transaction(func(gtx *gorm.DB) error {
+			tx.db = gtx
+			return fn(ctx, usecase.Repositories{Users: &userRepository{dbs: tx}})
+		}, txOptions)
+		if err == nil {
+			// Stickiness starts once the writes are visible
+			u.dbs.Wrote(ctx, tx.wrote...)
+		}
+		return err
+	})
+}
+
+// retry calls fn until it succeeds, fails with an error that is not
+// retryable, or has been called attempts times.
+func retry(ctx context.Context, attempts int, fn func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= attempts || !isRetryable(err) {
+			return err
+		}
+
+		delay := time.Duration(attempt)*retryDelay + rand.N(retryDelay)
+		slog.DebugContext(ctx, "Transaction conflicted with a concurrent one, retrying",
+			slog.Int("attempt", attempt), slog.Duration("retry_in", delay), slog.String("error", err.Error()))
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
+
+// isRetryable reports whether err is a serialization failure or a deadlock,
+// after which running the transaction again may succeed.
+func isRetryable(err error) bool {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return false
+	}
+	return pgErr.Code == "40001" || pgErr.Code == "40P01"
+}
+
+// txConns sends every query to a transaction and collects the stickiness
+// keys of its writes until it has committed.
+type txConns struct {
+	db    *gorm.DB
+	wrote []string
+}
+
+func (t *txConns) Writer() *gorm.DB {
+	return t.db
+}
+
+func (t *txConns) Reader(context.Context, ...string) *gorm.DB {
+	return t.db
+}
+
+func (t *txConns) Wrote(_ context.Context, keys ...string) {
+	t.wrote = append(t.wrote, keys...)
+}
@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry_RetriesConflicts(t *testing.T) {
+	conflict := fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"})
+	calls := 0
+
+	err := retry(context.Background(), 3, func() error {
+		calls++
+		if calls < 3 {
+			return conflict
+		}
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+}
+
+func TestRetry_GivesUp(t *testing.T) {
+	deadlock := &pgconn.PgError{Code: "40P01"}
+	calls := 0
+
+	err := retry(context.Background(), 2, func() error {
+		calls++
+		return deadlock
+	})
+
+	assert.ErrorIs(t, err, deadlock)
+	assert.Equal(t, 2, calls)
+}
+
+func TestRetry_OtherErrorsAreFinal(t *testing.T) {
+	for _, err := range []error{errors.New("connection reset"), &pgconn.PgError{Code: "23505"}} {
+		calls := 0
+
+		got := retry(context.Background(), 3, func() error {
+			calls++
+			return err
+		})
+
+		assert.Equal(t, err, got)
+		assert.Equal(t, 1, calls)
+	}
+}
@@ -1,33 +1,45 @@
 package persistence
 
 import (
+	"context"
 	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
+	"github.com/interimme/userapi/internal/infrastructure/db"
+	"github.com/interimme/userapi/internal/search"
 	"github.com/interimme/userapi/internal/usecase"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // UserGorm represents the GORM model for the User entity
 type UserGorm struct {
-	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
-	Firstname string
-	Lastname  string
-	Email     string `gorm:"uniqueIndex"`
-	Age       uint
-	Created   int64
+	ID             uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Firstname      string
+	Lastname       string
+	Email          string
+	CanonicalEmail string     `gorm:"not null;uniqueIndex"`
+	DateOfBirth    *time.Time `gorm:"type:date"`
+	Age            uint
+	CreatedAt      time.Time `gorm:"type:timestamptz;not null;autoCreateTime:false"`
+	UpdatedAt      time.Time `gorm:"type:timestamptz;not null;autoUpdateTime:false"`
 }
 
 // ToEntity converts UserGorm to entity.User
 func (ug *UserGorm) ToEntity() *entity.User {
 	return &entity.User{
-		ID:        ug.ID,
-		Firstname: ug.Firstname,
-		Lastname:  ug.Lastname,
-		Email:     ug.Email,
-		Age:       ug.Age,
-		Created:   ug.CreatedTime(),
+		ID:             ug.ID,
+		Firstname:      ug.Firstname,
+		Lastname:       ug.Lastname,
+		Email:          ug.Email,
+		CanonicalEmail: ug.CanonicalEmail,
+		DateOfBirth:    ug.DateOfBirthTime(),
+		Age:            ug.Age,
+		Created:        ug.CreatedAt.UTC(),
+		Updated:        ug.UpdatedAt.UTC(),
 	}
 }
 
@@ -37,57 +49,304 @@ func (ug *UserGorm) FromEntity(user *entity.User) {
 	ug.Firstname = user.Firstname
 	ug.Lastname = user.Lastname
 	ug.Email = user.Email
-	ug.Age = user.Age
-	ug.Created = user.Created.Unix()
+	ug.CanonicalEmail = canonicalEmail(user)
+	ug.DateOfBirth = nil
+	if !user.DateOfBirth.IsZero() {
+		dateOfBirth := user.DateOfBirth.UTC()
+		ug.DateOfBirth = &dateOfBirth
+	}
+	// The age as of the write, for rows without a date of birth and for
+	// readers that predate it
+	ug.Age = user.CurrentAge()
+	ug.CreatedAt = user.Created
+	ug.UpdatedAt = user.Updated
+}
+
+// now returns the current time at the microsecond precision of timestamptz,
+// so that users read back equal the users written.
+func now() time.Time {
+	return time.Now().UTC().Truncate(time.Microsecond)
 }
 
-// CreatedTime returns the Created field as time.Time
-func (ug *UserGorm) CreatedTime() time.Time {
-	return time.Unix(ug.Created, 0)
+// setCreated sets the creation and update times of a user about to be
+// inserted. Times already set, e.g. by an import, are kept at the precision
+// of the database.
+func setCreated(user *entity.User) {
+	t := now()
+	if user.Created.IsZero() {
+		user.Created = t
+	}
+	if user.Updated.IsZero() {
+		user.Updated = t
+	}
+	user.Created = user.Created.UTC().Truncate(time.Microsecond)
+	user.Updated = user.Updated.UTC().Truncate(time.Microsecond)
+}
+
+// setUpdated sets the update time of a user about to be saved.
+func setUpdated(user *entity.User) {
+	user.Updated = now()
+}
+
+// canonicalEmail returns the canonical email address of user. Users written
+// without one, bypassing the use cases, get the address in lowercase, as the
+// existing rows did when the column was added.
+func canonicalEmail(user *entity.User) string {
+	if user.CanonicalEmail != "" {
+		return user.CanonicalEmail
+	}
+	return strings.ToLower(user.Email)
+}
+
+// DateOfBirthTime returns the DateOfBirth field as midnight UTC, or the zero
+// time if it is NULL
+func (ug *UserGorm) DateOfBirthTime() time.Time {
+	if ug.DateOfBirth == nil {
+		return time.Time{}
+	}
+	year, month, day := ug.DateOfBirth.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// conns chooses the connection of each query; see db.Resolver.
+type conns interface {
+	Writer() *gorm.DB
+	Reader(ctx context.Context, keys ...string) *gorm.DB
+	Wrote(ctx context.Context, keys ...string)
 }
 
 // userRepository implements the UserRepository interface
 type userRepository struct {
-	db *gorm.DB
+	dbs conns
 }
 
 // NewUserRepository creates a new instance of UserRepository
-func NewUserRepository(db *gorm.DB) usecase.UserRepository {
+func NewUserRepository(conn *gorm.DB) usecase.UserRepository {
+	return NewReplicatedUserRepository(db.NewResolver(conn, nil, 0))
+}
+
+// NewReplicatedUserRepository creates a UserRepository that reads from the
+// replicas of resolver. Reads of a user stay on the primary for a while after
+// it was written, whoever asks, so that caches filled right after a write do
+// not pick up a stale row.
+func NewReplicatedUserRepository(resolver *db.Resolver) usecase.UserRepository {
 	return &userRepository{
-		db: db,
+		dbs: resolver,
 	}
 }
 
-func (r *userRepository) Create(user *entity.User) error {
+// userKey is the stickiness key of a user's row.
+func userKey(id uuid.UUID) string {
+	return "user:" + id.String()
+}
+
+func userKeys(ids []uuid.UUID) []string {
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = userKey(id)
+	}
+	return keys
+}
+
+func (r *userRepository) Create(ctx context.Context, user *entity.User) error {
+	setCreated(user)
 	ug := &UserGorm{}
 	ug.FromEntity(user)
-	return r.db.Create(ug).Error
+	if err := r.dbs.Writer().WithContext(ctx).Create(ug).Error; err != nil {
+		return err
+	}
+	r.dbs.Wrote(ctx, userKey(user.ID))
+	return nil
 }
 
-func (r *userRepository) GetByID(id uuid.UUID) (*entity.User, error) {
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
 	var ug UserGorm
-	if err := r.db.First(&ug, "id = ?", id).Error; err != nil {
+	if err := r.dbs.Reader(ctx, userKey(id)).WithContext(ctx).First(&ug, "id = ?", id).Error; err != nil {
 		return nil, err
 	}
 	return ug.ToEntity(), nil
 }
 
-func (r *userRepository) GetByEmail(email string) (*entity.User, error) {
+// GetByIDForUpdate reads from the primary, since rows can only be locked there.
+func (r *userRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entity.User, error) {
 	var ug UserGorm
-	if err := r.db.First(&ug, "email = ?", email).Error; err != nil {
+	err := r.dbs.Writer().WithContext(ctx).Clauses(clause.Locking{Strength: "UPDATE"}).First(&ug, "id = ?", id).Error
+	if err != nil {
 		return nil, err
 	}
 	return ug.ToEntity(), nil
 }
 
-func (r *userRepository) Update(user *entity.User) error {
+func (r *userRepository) GetByEmail(ctx context.Context, canonicalEmail string) (*entity.User, error) {
+	var ug UserGorm
+	if err := r.dbs.Reader(ctx).WithContext(ctx).First(&ug, "canonical_email = ?", canonicalEmail).Error; err != nil {
+		return nil, err
+	}
+	return ug.ToEntity(), nil
+}
+
+func (r *userRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	var count int64
+	if err := r.dbs.Reader(ctx, userKey(id)).WithContext(ctx).Model(&UserGorm{}).Where("id = ?", id).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (r *userRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entity.User, error) {
+	var ugs []UserGorm
+	if err := r.dbs.Reader(ctx, userKeys(ids)...).WithContext(ctx).Where("id IN ?", ids).Find(&ugs).Error; err != nil {
+		return nil, err
+	}
+	users := make([]*entity.User, len(ugs))
+	for i := range ugs {
+		users[i] = ugs[i].ToEntity()
+	}
+	return users, nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *entity.User) error {
+	setUpdated(user)
 	ug := &UserGorm{}
 	ug.FromEntity(user)
-	return r.db.Save(ug).Error
+	if err := r.dbs.Writer().WithContext(ctx).Save(ug).Error; err != nil {
+		return err
+	}
+	r.dbs.Wrote(ctx, userKey(user.ID))
+	return nil
 }
 
-func (r *userRepository) Delete(user *entity.User) error {
+func (r *userRepository) Delete(ctx context.Context, user *entity.User) error {
 	ug := &UserGorm{}
 	ug.FromEntity(user)
-	return r.db.Delete(ug).Error
+	if err := r.dbs.Writer().WithContext(ctx).Delete(ug).Error; err != nil {
+		return err
+	}
+	r.dbs.Wrote(ctx, userKey(user.ID))
+	return nil
+}
+
+// userColumns maps the fields of user filters to the columns of UserGorm.
+var userColumns = map[string]filter.Column{
+	"id":            {Name: "id"},
+	"firstname":     {Name: "firstname"},
+	"lastname":      {Name: "lastname"},
+	"email":         {Name: "email"},
+	"age":           {Condition: ageCondition},
+	"date_of_birth": {Name: "date_of_birth", Convert: func(v any) any { return v.(time.Time).Format(time.DateOnly) }},
+	"created":       {Name: "created_at"},
+	"updated":       {Name: "updated_at"},
+}
+
+// maxFilterAge exceeds every age while keeping the date arithmetic in range.
+const maxFilterAge = 1 << 16
+
+// ageCondition compares the current age of users with age by comparing the
+// date of birth with the range of dates it implies, today in UTC. Rows
+// without a date of birth compare the age as given.
+func ageCondition(op string, value any) (string, []any) {
+	age := value.(int64)
+	today := time.Now()
+	// atLeast is the condition that users are at least n years old
+	atLeast := func(n int64) (string, []any) {
+		switch {
+		case n <= 0:
+			return "TRUE", nil
+		case n > maxFilterAge:
+			return "FALSE", nil
+		}
+		bornBy := entity.BornBy(uint(n), today).Format(time.DateOnly)
+		return "COALESCE(date_of_birth <= ?, age >= ?)", []any{bornBy, n}
+	}
+	age = min(age, maxFilterAge)
+	switch op {
+	case ">=":
+		return atLeast(age)
+	case ">":
+		return atLeast(age + 1)
+	case "<":
+		condition, args := atLeast(age)
+		return "NOT " + condition, args
+	case "<=":
+		condition, args := atLeast(age + 1)
+		return "NOT " + condition, args
+	}
+	lower, lowerArgs := atLeast(age)
+	upper, upperArgs := atLeast(age + 1)
+	condition := lower + " AND NOT " + upper
+	if op == "!=" {
+		condition = "NOT (" + condition + ")"
+	}
+	return condition, append(lowerArgs, upperArgs...)
+}
+
+func (r *userRepository) DeleteByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	result := r.dbs.Writer().WithContext(ctx).Where("id IN ?", ids).Delete(&UserGorm{})
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	r.dbs.Wrote(ctx, userKeys(ids)...)
+	return result.RowsAffected, nil
+}
+
+func (r *userRepository) List(ctx context.Context, f *filter.Filter, afterID uuid.UUID, limit int) ([]*entity.User, error) {
+	query := r.dbs.Reader(ctx).WithContext(ctx).Order("id").Limit(limit)
+	if afterID != uuid.Nil {
+		query = query.Where("id > ?", afterID)
+	}
+	condition, args, err := f.SQL(userColumns)
+	if err != nil {
+		return nil, err
+	}
+	if condition != "" {
+		query = query.Where(condition, args...)
+	}
+	var ugs []UserGorm
+	if err := query.Find(&ugs).Error; err != nil {
+		return nil, err
+	}
+	users := make([]*entity.User, len(ugs))
+	for i := range ugs {
+		users[i] = ugs[i].ToEntity()
+	}
+	return users, nil
+}
+
+// searchQuery ranks users by full-text rank plus the best trigram word
+// similarity of a field. Full-text search matches the words of the query as
+// prefixes; trigram similarity also finds misspellings and fragments.
+const searchQuery = `
+SELECT u.*,
+       ts_rank(u.search_vector, q.words) + greatest(word_similarity(q.text, u.firstname), word_similarity(q.text, u.lastname), word_similarity(q.text, u.email)) AS score
+FROM user_gorms u, (SELECT to_tsquery('simple', @words) AS words, @text::text AS text) q
+WHERE u.search_vector @@ q.words OR q.text <% u.firstname OR q.text <% u.lastname OR q.text <% u.email
+ORDER BY score DESC, u.id
+LIMIT @limit`
+
+// searchRow is a row of searchQuery
+type searchRow struct {
+	UserGorm `gorm:"embedded"`
+	Score    float64
+}
+
+func (r *userRepository) Search(ctx context.Context, query string, limit int) ([]*entity.UserMatch, error) {
+	// The terms consist of letters and digits only, so they are valid lexemes
+	terms := search.Terms(query)
+	for i, term := range terms {
+		terms[i] = term + ":*"
+	}
+	var rows []searchRow
+	err := r.dbs.Reader(ctx).WithContext(ctx).Raw(searchQuery, map[string]any{
+		"words": strings.Join(terms, " & "),
+		"text":  query,
+		"limit": limit,
+	}).Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	matches := make([]*entity.UserMatch, len(rows))
+	for i := range rows {
+		matches[i] = &entity.UserMatch{User: rows[i].ToEntity(), Score: rows[i].Score}
+	}
+	return matches, nil
 }
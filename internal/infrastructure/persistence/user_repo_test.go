@@ -0,0 +1,54 @@
+package persistence
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
+	"github.com/interimme/userapi/internal/usecase"
+)
+
+func TestAgeCondition(t *testing.T) {
+	today := time.Now()
+	born18 := entity.BornBy(18, today).Format(time.DateOnly)
+	born19 := entity.BornBy(19, today).Format(time.DateOnly)
+
+	tests := []struct {
+		op        string
+		age       int64
+		condition string
+		args      []any
+	}{
+		{">=", 18, "COALESCE(date_of_birth <= ?, age >= ?)", []any{born18, int64(18)}},
+		{">", 18, "COALESCE(date_of_birth <= ?, age >= ?)", []any{born19, int64(19)}},
+		{"<", 18, "NOT COALESCE(date_of_birth <= ?, age >= ?)", []any{born18, int64(18)}},
+		{"=", 18, "COALESCE(date_of_birth <= ?, age >= ?) AND NOT COALESCE(date_of_birth <= ?, age >= ?)", []any{born18, int64(18), born19, int64(19)}},
+		{">=", 0, "TRUE", nil},
+		{"<", -1, "NOT TRUE", nil},
+		{">", 1 << 62, "FALSE", nil},
+	}
+	for _, tc := range tests {
+		condition, args := ageCondition(tc.op, tc.age)
+
+		assert.Equal(t, tc.condition, condition, "%s %d", tc.op, tc.age)
+		assert.Equal(t, tc.args, args, "%s %d", tc.op, tc.age)
+	}
+}
+
+// Restrictions on date_of_birth compare the nullable column directly, so for
+// users without one they are NULL and so are their negations, which is what
+// the memory repository matches too.
+func TestUserColumns_DateOfBirth(t *testing.T) {
+	f, err := filter.Parse(`NOT date_of_birth < "2000-01-01"`, usecase.UserFilterFields)
+	require.NoError(t, err)
+
+	condition, args, err := f.SQL(userColumns)
+
+	require.NoError(t, err)
+	assert.Equal(t, "NOT (date_of_birth < ?)", condition)
+	assert.Equal(t, []any{"2000-01-01"}, args)
+}
@@ -1,20 +1,91 @@
 package infrastructure
 
 import (
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/interimme/userapi/internal/apidocs"
 	"github.com/interimme/userapi/internal/controller"
+	"github.com/interimme/userapi/internal/health"
+	"github.com/interimme/userapi/internal/infrastructure/middleware"
+	"github.com/interimme/userapi/internal/metrics"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
 // NewRouter initializes the Gin router with routes and handlers
-func NewRouter(userController *controller.UserController) *gin.Engine {
-	router := gin.Default()
+func NewRouter(userController *controller.UserController, healthChecker *health.Checker, m *metrics.Metrics, logger *slog.Logger) *gin.Engine {
+	router := gin.New()
+	router.HandleMethodNotAllowed = true
+	// Ignore X-Forwarded-For until the trusted proxies are configured
+	_ = router.SetTrustedProxies(nil)
+
+	// Errors are rendered as application/problem+json, matching the gRPC-Gateway
+	router.Use(
+		otelgin.Middleware("gin"),
+		middleware.RequestLogger(logger),
+		m.GinMiddleware(),
+		gin.CustomRecoveryWithWriter(io.Discard, middleware.Recovery(logger)),
+		middleware.ErrorHandler,
+		middleware.Caller,
+		middleware.APIKey,
+	)
+	router.NoRoute(middleware.NoRoute)
+	router.NoMethod(middleware.NoMethod)
+
+	// Liveness and readiness probes
+	router.GET("/healthz", gin.WrapF(healthChecker.Liveness))
+	router.GET("/readyz", gin.WrapF(healthChecker.Readiness))
+
+	// Prometheus metrics
+	router.GET("/metrics", gin.WrapH(m.Handler()))
+
+	// OpenAPI spec and Swagger UI
+	router.GET(apidocs.SpecPath, gin.WrapF(apidocs.SpecHandler))
+	router.GET("/docs", gin.WrapH(apidocs.DocsHandler()))
+	router.GET("/docs/*file", gin.WrapH(apidocs.DocsHandler()))
 
 	// Define the routes and handlers
 	router.POST("/users", userController.CreateUser)
+	router.GET("/users", userController.ListUsers)
+	router.GET("/users/search", userController.SearchUsers)
 	router.GET("/user/:id", userController.GetUser)
+	router.HEAD("/user/:id", userController.CheckUserExists)
+	users := customMethods(map[string]customMethod{
+		"lookup":      {http.MethodGet, userController.LookupUser},
+		"batchGet":    {http.MethodGet, userController.BatchGetUsers},
+		"batchCreate": {http.MethodPost, userController.BatchCreateUsers},
+		"batchDelete": {http.MethodPost, userController.BatchDeleteUsers},
+	})
+	router.GET("/users:method", users)
+	router.POST("/users:method", users)
 	router.PATCH("/user/:id", userController.UpdateUser)
 	router.DELETE("/user/:id", userController.DeleteUser)
 
 	return router
 }
+
+// customMethod is a custom method of a collection such as /users:batchGet.
+type customMethod struct {
+	httpMethod string
+	handler    gin.HandlerFunc
+}
+
+// customMethods dispatches the custom methods of a collection by name. Gin
+// matches the name, including its colon, as the :method parameter.
+func customMethods(methods map[string]customMethod) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		m, ok := methods[strings.TrimPrefix(c.Param("method"), ":")]
+		switch {
+		case !ok:
+			middleware.NoRoute(c)
+		case c.Request.Method != m.httpMethod:
+			middleware.NoMethod(c)
+		default:
+			m.handler(c)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package infrastructure
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCustomMethods(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/users", func(c *gin.Context) { c.String(http.StatusCreated, "create") })
+	methods := customMethods(map[string]customMethod{
+		"batchGet":    {http.MethodGet, func(c *gin.Context) { c.String(http.StatusOK, "batchGet") }},
+		"batchDelete": {http.MethodPost, func(c *gin.Context) { c.String(http.StatusOK, "batchDelete") }},
+	})
+	router.GET("/users:method", methods)
+	router.POST("/users:method", methods)
+
+	for _, tc := range []struct {
+		method, path string
+		code         int
+		body         string
+	}{
+		{http.MethodGet, "/users:batchGet", http.StatusOK, "batchGet"},
+		{http.MethodPost, "/users:batchDelete", http.StatusOK, "batchDelete"},
+		{http.MethodPost, "/users", http.StatusCreated, "create"},
+		{http.MethodPost, "/users:batchGet", http.StatusMethodNotAllowed, ""},
+		{http.MethodGet, "/users:unknown", http.StatusNotFound, ""},
+	} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(tc.method, tc.path, nil))
+		assert.Equal(t, tc.code, rec.Code, tc.method+" "+tc.path)
+		if tc.body != "" {
+			assert.Equal(t, tc.body, rec.Body.String(), tc.method+" "+tc.path)
+		}
+	}
+}
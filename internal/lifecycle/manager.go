@@ -0,0 +1,134 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// Component is a long-running part of the application, such as a server.
+type Component interface {
+	// Start runs the component and blocks until it has stopped.
+	Start() error
+	// Shutdown stops the component gracefully. When ctx is done the
+	// component must stop immediately, abandoning in-flight work.
+	Shutdown(ctx context.Context) error
+}
+
+// namedComponent pairs a component with the name used in logs and errors.
+type namedComponent struct {
+	name string
+	Component
+}
+
+// result is the outcome of a component's Start.
+type result struct {
+	name string
+	err  error
+}
+
+// Manager starts components, waits for a stop signal and shuts everything
+// down in reverse registration order within the drain timeout.
+type Manager struct {
+	drainDelay   time.Duration
+	drainTimeout time.Duration
+	components   []namedComponent
+	drainHooks   []func()
+	ready        atomic.Bool
+}
+
+// NewManager creates a Manager that keeps serving for drainDelay after
+// reporting not ready, so that load balancers stop routing new traffic, and
+// then gives components drainTimeout to finish in-flight work.
+func NewManager(drainDelay, drainTimeout time.Duration) *Manager {
+	return &Manager{drainDelay: drainDelay, drainTimeout: drainTimeout}
+}
+
+// Add registers a component. Components are shut down in reverse order, so
+// dependencies (e.g. the gRPC server behind the gateway) should be added first.
+func (m *Manager) Add(name string, c Component) {
+	m.components = append(m.components, namedComponent{name: name, Component: c})
+}
+
+// OnDrain registers a hook that runs when shutdown starts, right after
+// readiness has been flipped, such as reporting NOT_SERVING to gRPC health
+// checks. Components are drained once the drain delay has passed.
+func (m *Manager) OnDrain(fn func()) {
+	m.drainHooks = append(m.drainHooks, fn)
+}
+
+// Ready reports whether the application is serving and not shutting down.
+func (m *Manager) Ready() bool {
+	return m.ready.Load()
+}
+
+// Run starts all components and blocks until ctx is done or a component
+// fails, then shuts everything down. When Run returns every component has
+// stopped, so shared resources such as the database can be closed safely.
+// It returns the component failure, if any, joined with shutdown errors.
+func (m *Manager) Run(ctx context.Context) error {
+	results := make(chan result, len(m.components))
+	for _, c := range m.components {
+		go func() {
+			results <- result{name: c.name, err: c.Start()}
+		}()
+	}
+	m.ready.Store(true)
+
+	// Wait for a stop signal or the first component to exit
+	var runErr error
+	running := len(m.components)
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutting down", slog.String("reason", context.Cause(ctx).Error()))
+	case res := <-results:
+		running--
+		runErr = res.err
+		if runErr == nil {
+			runErr = errors.New("stopped unexpectedly")
+		}
+		runErr = fmt.Errorf("%s: %w", res.name, runErr)
+		slog.Error("Shutting down", slog.String("error", runErr.Error()))
+	}
+
+	return errors.Join(runErr, m.shutdown(results, running))
+}
+
+// shutdown drains components and waits for them to exit.
+func (m *Manager) shutdown(results <-chan result, running int) error {
+	// Stop receiving new traffic before draining in-flight requests
+	m.ready.Store(false)
+	for _, hook := range m.drainHooks {
+		hook()
+	}
+
+	// Probes only notice the change on their next run, and traffic keeps
+	// arriving until then
+	if m.drainDelay > 0 {
+		slog.Info("Waiting for load balancers to stop routing traffic", slog.Duration("delay", m.drainDelay))
+		time.Sleep(m.drainDelay)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), m.drainTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(m.components) - 1; i >= 0; i-- {
+		c := m.components[i]
+		if err := c.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%s shutdown: %w", c.name, err))
+		}
+	}
+
+	// Components have been stopped, forcibly if the drain timeout expired
+	for ; running > 0; running-- {
+		if res := <-results; res.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", res.name, res.err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,140 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeComponent runs until Shutdown is called or fails with startErr.
+type fakeComponent struct {
+	name     string
+	startErr error
+	drain    time.Duration // simulated in-flight work
+	stopped  chan struct{}
+	once     sync.Once
+	log      *[]string
+	mu       *sync.Mutex
+}
+
+func newFakeComponent(name string, log *[]string, mu *sync.Mutex) *fakeComponent {
+	return &fakeComponent{name: name, stopped: make(chan struct{}), log: log, mu: mu}
+}
+
+func (f *fakeComponent) Start() error {
+	if f.startErr != nil {
+		return f.startErr
+	}
+	<-f.stopped
+	return nil
+}
+
+func (f *fakeComponent) Shutdown(ctx context.Context) error {
+	defer f.once.Do(func() { close(f.stopped) })
+
+	f.mu.Lock()
+	*f.log = append(*f.log, f.name)
+	f.mu.Unlock()
+
+	select {
+	case <-time.After(f.drain):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestManager_ShutdownOnSignal(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	m := NewManager(0, time.Second)
+	m.Add("grpc", newFakeComponent("grpc", &log, &mu))
+	m.Add("gateway", newFakeComponent("gateway", &log, &mu))
+
+	readyDuringDrain := true
+	m.OnDrain(func() { readyDuringDrain = m.Ready() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	require.Eventually(t, m.Ready, time.Second, time.Millisecond)
+	cancel()
+
+	require.NoError(t, <-done)
+	assert.False(t, readyDuringDrain, "readiness must be flipped before draining")
+	assert.False(t, m.Ready())
+	assert.Equal(t, []string{"gateway", "grpc"}, log, "components are shut down in reverse order")
+}
+
+// timedComponent records when its shutdown started.
+type timedComponent struct {
+	*fakeComponent
+	shutdownAt time.Time
+}
+
+func (c *timedComponent) Shutdown(ctx context.Context) error {
+	c.shutdownAt = time.Now()
+	return c.fakeComponent.Shutdown(ctx)
+}
+
+func TestManager_DrainDelay(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	m := NewManager(50*time.Millisecond, time.Second)
+	grpc := &timedComponent{fakeComponent: newFakeComponent("grpc", &log, &mu)}
+	m.Add("grpc", grpc)
+
+	var flippedAt time.Time
+	m.OnDrain(func() { flippedAt = time.Now() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- m.Run(ctx) }()
+
+	require.Eventually(t, m.Ready, time.Second, time.Millisecond)
+	cancel()
+
+	require.NoError(t, <-done)
+	assert.False(t, flippedAt.IsZero())
+	assert.GreaterOrEqual(t, grpc.shutdownAt.Sub(flippedAt), 50*time.Millisecond, "components are drained after the delay")
+}
+
+func TestManager_ComponentFailure(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	m := NewManager(0, time.Second)
+	failing := newFakeComponent("gin", &log, &mu)
+	failing.startErr = errors.New("address already in use")
+	m.Add("grpc", newFakeComponent("grpc", &log, &mu))
+	m.Add("gin", failing)
+
+	err := m.Run(context.Background())
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "gin: address already in use")
+	assert.Equal(t, []string{"gin", "grpc"}, log)
+}
+
+func TestManager_DrainTimeout(t *testing.T) {
+	var log []string
+	var mu sync.Mutex
+	m := NewManager(0, 10*time.Millisecond)
+	slow := newFakeComponent("slow", &log, &mu)
+	slow.drain = time.Minute
+	m.Add("slow", slow)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	start := time.Now()
+	err := m.Run(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+}
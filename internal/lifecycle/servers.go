@@ -0,0 +1,64 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"google.golang.org/grpc"
+)
+
+// HTTPServer adapts an *http.Server serving on Listener to a Component.
+type HTTPServer struct {
+	Server   *http.Server
+	Listener net.Listener
+}
+
+// Start serves HTTP until the server is shut down.
+func (s *HTTPServer) Start() error {
+	if err := s.Server.Serve(s.Listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown waits for in-flight requests and closes remaining connections when ctx is done.
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	if err := s.Server.Shutdown(ctx); err != nil {
+		_ = s.Server.Close()
+		return err
+	}
+	return nil
+}
+
+// GRPCServer adapts a *grpc.Server serving on Listener to a Component.
+type GRPCServer struct {
+	Server   *grpc.Server
+	Listener net.Listener
+}
+
+// Start serves gRPC until the server is stopped.
+func (s *GRPCServer) Start() error {
+	if err := s.Server.Serve(s.Listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops the server gracefully, cancelling in-flight RPCs when ctx is done.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.Server.Stop()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,72 @@
+package logging
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc/metadata"
+)
+
+// HTTPMiddleware accepts the X-Request-ID header (or generates an ID), echoes
+// it in the response and logs every request. server names the HTTP surface.
+func HTTPMiddleware(logger *slog.Logger, server string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := EnsureRequestID(r.Header.Get(RequestIDHeader))
+		w.Header().Set(RequestIDHeader, id)
+		r = r.WithContext(WithRequestID(r.Context(), id))
+
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(rec, r)
+
+		LogRequest(r.Context(), logger, server, r.Method, r.URL.Path, rec.status, time.Since(start))
+	})
+}
+
+// GatewayMetadata forwards the request ID to the gRPC backend. It is
+// installed with runtime.WithMetadata.
+func GatewayMetadata(ctx context.Context, _ *http.Request) metadata.MD {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return metadata.Pairs(requestIDMetadataKey, id)
+	}
+	return nil
+}
+
+// LogRequest writes the access log entry of an HTTP request. The query string
+// is never logged since it may contain personal data.
+func LogRequest(ctx context.Context, logger *slog.Logger, server, method, path string, status int, duration time.Duration) {
+	level := slog.LevelInfo
+	switch {
+	case status >= http.StatusInternalServerError:
+		level = slog.LevelError
+	case status >= http.StatusBadRequest && status != http.StatusNotFound:
+		level = slog.LevelWarn
+	}
+	logger.LogAttrs(ctx, level, "HTTP request",
+		slog.String("server", server),
+		slog.String("method", method),
+		slog.String("path", path),
+		slog.Int("status", status),
+		slog.Duration("duration", duration),
+	)
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming responses working through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
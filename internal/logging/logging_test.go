@@ -0,0 +1,122 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func newTestLogger(t *testing.T) (*slog.Logger, *bytes.Buffer) {
+	t.Helper()
+	var buf bytes.Buffer
+	logger, err := New(&buf, Config{Level: "debug", Format: FormatJSON})
+	require.NoError(t, err)
+	return logger, &buf
+}
+
+func decodeRecord(t *testing.T, buf *bytes.Buffer) map[string]any {
+	t.Helper()
+	var record map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	return record
+}
+
+func TestRedaction(t *testing.T) {
+	logger, buf := newTestLogger(t)
+
+	logger.Info("user alice@example.com created",
+		slog.String("email", "alice@example.com"),
+		slog.Group("user", slog.String("firstname", "Alice"), slog.String("lastname", "Smith")),
+		slog.Any("error", errors.New(`duplicate key: email "Bob@Example.com"`)),
+		slog.Int("age", 28),
+		slog.String("detail", "renamed jürgen@bücher.рф to info@xn--bcher-kva.xn--p1ai"),
+	)
+
+	record := decodeRecord(t, buf)
+	assert.Equal(t, "user [REDACTED] created", record["msg"])
+	assert.Equal(t, Redacted, record["email"])
+	assert.Equal(t, map[string]any{"firstname": Redacted, "lastname": Redacted}, record["user"])
+	assert.Equal(t, `duplicate key: email "[REDACTED]"`, record["error"])
+	assert.Equal(t, 28.0, record["age"])
+	assert.Equal(t, "renamed [REDACTED] to [REDACTED]", record["detail"])
+	assert.NotContains(t, buf.String(), "example.com")
+}
+
+func TestContextAttributes(t *testing.T) {
+	logger, buf := newTestLogger(t)
+
+	logger.InfoContext(WithRequestID(context.Background(), "req-1"), "hello")
+
+	assert.Equal(t, "req-1", decodeRecord(t, buf)["request_id"])
+}
+
+func TestEnsureRequestID(t *testing.T) {
+	assert.Equal(t, "abc-123", EnsureRequestID("abc-123"))
+	assert.NotEmpty(t, EnsureRequestID(""))
+	assert.NotEqual(t, "bad id\nwith newline", EnsureRequestID("bad id\nwith newline"))
+}
+
+func TestParseLevel(t *testing.T) {
+	level, err := ParseLevel("warn")
+	require.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = ParseLevel("verbose")
+	assert.Error(t, err)
+}
+
+func TestHTTPMiddleware_RequestID(t *testing.T) {
+	logger, buf := newTestLogger(t)
+	var seen string
+	handler := HTTPMiddleware(logger, "gateway", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/users?email=alice@example.com", nil)
+	req.Header.Set(RequestIDHeader, "client-id-1")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	assert.Equal(t, "client-id-1", seen)
+	assert.Equal(t, "client-id-1", w.Header().Get(RequestIDHeader))
+	record := decodeRecord(t, buf)
+	assert.Equal(t, "/users", record["path"])
+	assert.Equal(t, 204.0, record["status"])
+	assert.Equal(t, "client-id-1", record["request_id"])
+}
+
+func TestGatewayMetadata(t *testing.T) {
+	md := GatewayMetadata(WithRequestID(context.Background(), "req-7"), nil)
+
+	assert.Equal(t, []string{"req-7"}, md.Get("x-request-id"))
+}
+
+func TestUnaryServerInterceptor_RequestIDFromMetadata(t *testing.T) {
+	logger, buf := newTestLogger(t)
+	interceptor := UnaryServerInterceptor(logger)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("x-request-id", "req-9"))
+
+	var seen string
+	_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/userapi.UserService/GetUser"},
+		func(ctx context.Context, _ any) (any, error) {
+			seen = RequestIDFromContext(ctx)
+			return nil, nil
+		})
+
+	require.NoError(t, err)
+	assert.Equal(t, "req-9", seen)
+	record := decodeRecord(t, buf)
+	assert.Equal(t, "OK", record["grpc_code"])
+	assert.Equal(t, "req-9", record["request_id"])
+}
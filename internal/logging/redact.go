@@ -0,0 +1,68 @@
+package logging
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces the value of personal data in log records.
+const Redacted = "[REDACTED]"
+
+// sensitiveKeys are attribute keys whose values are always redacted.
+var sensitiveKeys = map[string]bool{
+	"email":      true,
+	"firstname":  true,
+	"first_name": true,
+	"lastname":   true,
+	"last_name":  true,
+	"full_name":  true,
+	"name":       true,
+
+	"date_of_birth": true,
+	"dateOfBirth":   true,
+}
+
+// emailPattern finds email addresses embedded in messages and errors,
+// including internationalized ones such as jürgen@bücher.рф.
+var emailPattern = regexp.MustCompile(`[^\s@"'<>(),;:]+@[^\s@"'<>(),;:]+\.(?:xn--[A-Za-z0-9-]+|\pL{2,})`)
+
+// redactAttr is the slog ReplaceAttr hook. It redacts sensitive keys, and
+// email addresses anywhere in string values, including the message itself.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || !isBuiltinKey(a.Key) {
+		if sensitiveKeys[strings.ToLower(a.Key)] {
+			return slog.String(a.Key, Redacted)
+		}
+	}
+
+	switch a.Value.Kind() {
+	case slog.KindString:
+		return slog.String(a.Key, RedactEmails(a.Value.String()))
+	case slog.KindAny:
+		switch v := a.Value.Any().(type) {
+		case error:
+			return slog.String(a.Key, RedactEmails(v.Error()))
+		case fmt.Stringer:
+			return slog.String(a.Key, RedactEmails(v.String()))
+		}
+	}
+	return a
+}
+
+// RedactEmails replaces every email address in s.
+func RedactEmails(s string) string {
+	if !strings.Contains(s, "@") {
+		return s
+	}
+	return emailPattern.ReplaceAllString(s, Redacted)
+}
+
+func isBuiltinKey(key string) bool {
+	switch key {
+	case slog.TimeKey, slog.LevelKey, slog.MessageKey, slog.SourceKey:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package metrics
+
+// CacheHit implements cache.Metrics.
+func (m *Metrics) CacheHit(cache string) {
+	m.cacheLookups.WithLabelValues(cache, "hit").Inc()
+}
+
+// CacheMiss implements cache.Metrics.
+func (m *Metrics) CacheMiss(cache string) {
+	m.cacheLookups.WithLabelValues(cache, "miss").Inc()
+}
+
+// CacheEvicted implements cache.Metrics.
+func (m *Metrics) CacheEvicted(cache string) {
+	m.cacheEvictions.WithLabelValues(cache).Inc()
+}
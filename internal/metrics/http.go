@@ -0,0 +1,66 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+)
+
+// unmatchedRoute labels requests that did not match any route, keeping label cardinality bounded.
+const unmatchedRoute = "unmatched"
+
+// GinMiddleware records count, latency and status code per Gin route.
+func (m *Metrics) GinMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		m.observeHTTP("gin", route, c.Request.Method, c.Writer.Status(), start)
+	}
+}
+
+// GatewayMiddleware records count, latency and status code per gRPC-Gateway route.
+// It is installed with runtime.WithMiddlewares.
+func (m *Metrics) GatewayMiddleware(next runtime.HandlerFunc) runtime.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request, pathParams map[string]string) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(rec, r, pathParams)
+
+		route := unmatchedRoute
+		if pattern, ok := runtime.HTTPPattern(r.Context()); ok {
+			route = pattern.String()
+		}
+		m.observeHTTP("gateway", route, r.Method, rec.status, start)
+	}
+}
+
+func (m *Metrics) observeHTTP(server, route, method string, status int, start time.Time) {
+	m.httpRequests.WithLabelValues(server, route, method, strconv.Itoa(status)).Inc()
+	m.httpDuration.WithLabelValues(server, route, method).Observe(time.Since(start).Seconds())
+}
+
+// statusRecorder captures the status code written by the wrapped handler.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(code int) {
+	r.status = code
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Flush keeps streaming responses working through the recorder.
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
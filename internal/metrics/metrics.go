@@ -0,0 +1,118 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes application-specific metrics.
+const namespace = "userapi"
+
+// Metrics owns the Prometheus registry and all collectors of the service.
+type Metrics struct {
+	registry *prometheus.Registry
+
+	grpcHandled  *prometheus.CounterVec
+	grpcDuration *prometheus.HistogramVec
+
+	httpRequests *prometheus.CounterVec
+	httpDuration *prometheus.HistogramVec
+
+	usersCreated       prometheus.Counter
+	usersUpdated       prometheus.Counter
+	usersDeleted       prometheus.Counter
+	validationFailures *prometheus.CounterVec
+
+	cacheLookups   *prometheus.CounterVec
+	cacheEvictions *prometheus.CounterVec
+}
+
+// New creates the metrics registry including Go runtime and process collectors.
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		grpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of RPCs completed on the server, regardless of success or failure.",
+		}, []string{"grpc_service", "grpc_method", "grpc_code"}),
+		grpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Histogram of RPC handling latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_service", "grpc_method"}),
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_requests_total",
+			Help: "Total number of HTTP requests by server, route, method and status code.",
+		}, []string{"server", "route", "method", "code"}),
+		httpDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_duration_seconds",
+			Help:    "Histogram of HTTP request latency in seconds.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"server", "route", "method"}),
+		usersCreated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_created_total",
+			Help:      "Total number of users created.",
+		}),
+		usersUpdated: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_updated_total",
+			Help:      "Total number of users updated.",
+		}),
+		usersDeleted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "users_deleted_total",
+			Help:      "Total number of users deleted.",
+		}),
+		validationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "validation_failures_total",
+			Help:      "Total number of requests rejected by user validation, by operation.",
+		}, []string{"operation"}),
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Total number of cache lookups, by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+		cacheEvictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_evictions_total",
+			Help:      "Total number of entries evicted from a full cache.",
+		}, []string{"cache"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.grpcHandled,
+		m.grpcDuration,
+		m.httpRequests,
+		m.httpDuration,
+		m.usersCreated,
+		m.usersUpdated,
+		m.usersDeleted,
+		m.validationFailures,
+		m.cacheLookups,
+		m.cacheEvictions,
+	)
+	return m
+}
+
+// Registerer exposes the registry so that other components can add collectors.
+func (m *Metrics) Registerer() prometheus.Registerer {
+	return m.registry
+}
+
+// RegisterDB exports sql.DBStats of the connection pool under dbName.
+func (m *Metrics) RegisterDB(db *sql.DB, dbName string) error {
+	return m.registry.Register(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// Handler serves the /metrics endpoint.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
@@ -0,0 +1,70 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := New()
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/userapi.UserService/GetUser"}
+
+	_, err := interceptor(context.Background(), nil, info, func(context.Context, any) (any, error) {
+		return nil, status.Error(codes.NotFound, "user not found")
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.grpcHandled.WithLabelValues("userapi.UserService", "GetUser", "NotFound")))
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	m := New()
+	router := gin.New()
+	router.Use(m.GinMiddleware())
+	router.GET("/user/:id", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/1", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/user/2", nil))
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/nope", nil))
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("gin", "/user/:id", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("gin", unmatchedRoute, "GET", "404")))
+}
+
+func TestHandlerExposesUseCaseCounters(t *testing.T) {
+	m := New()
+	m.UserCreated()
+	m.ValidationFailed("create")
+
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	body := w.Body.String()
+	assert.True(t, strings.Contains(body, "userapi_users_created_total 1"))
+	assert.True(t, strings.Contains(body, `userapi_validation_failures_total{operation="create"} 1`))
+}
+
+func TestCacheCounters(t *testing.T) {
+	m := New()
+	m.CacheHit("users")
+	m.CacheHit("users")
+	m.CacheMiss("users")
+	m.CacheEvicted("users")
+
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.cacheLookups.WithLabelValues("users", "hit")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.cacheLookups.WithLabelValues("users", "miss")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.cacheEvictions.WithLabelValues("users")))
+}
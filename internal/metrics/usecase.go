@@ -0,0 +1,21 @@
+package metrics
+
+// UserCreated implements usecase.Metrics.
+func (m *Metrics) UserCreated() {
+	m.usersCreated.Inc()
+}
+
+// UserUpdated implements usecase.Metrics.
+func (m *Metrics) UserUpdated() {
+	m.usersUpdated.Inc()
+}
+
+// UserDeleted implements usecase.Metrics.
+func (m *Metrics) UserDeleted() {
+	m.usersDeleted.Inc()
+}
+
+// ValidationFailed implements usecase.Metrics.
+func (m *Metrics) ValidationFailed(operation string) {
+	m.validationFailures.WithLabelValues(operation).Inc()
+}
@@ -0,0 +1,92 @@
+// Package search splits user search queries into terms and matches them
+// against text, for highlighting and for repositories without full-text
+// search.
+package search
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+// Terms returns the lowercase words of query. Everything but letters and
+// digits separates words, so "ada@exa" yields "ada" and "exa".
+func Terms(query string) []string {
+	words := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	terms := words[:0]
+	seen := make(map[string]bool, len(words))
+	for _, w := range words {
+		if !seen[w] {
+			seen[w] = true
+			terms = append(terms, w)
+		}
+	}
+	return terms
+}
+
+// Score rates how well terms match fields between 0 and 1. A term scores
+// fully when a word of a field starts with it and half when it only occurs
+// inside a word; the score is the average over the terms.
+func Score(terms []string, fields ...string) float64 {
+	if len(terms) == 0 {
+		return 0
+	}
+	var total float64
+	for _, term := range terms {
+		best := 0.0
+		for _, field := range fields {
+			for _, word := range Terms(field) {
+				switch {
+				case strings.HasPrefix(word, term):
+					best = 1
+				case strings.Contains(word, term):
+					best = max(best, 0.5)
+				}
+			}
+		}
+		total += best
+	}
+	return total / float64(len(terms))
+}
+
+// Highlight returns text as HTML with every case-insensitive occurrence of
+// a term wrapped in <mark>, and whether there was any.
+func Highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// Mark the runes covered by a term; overlapping occurrences merge
+	marked := make([]bool, len(runes))
+	found := false
+	for _, term := range terms {
+		t := []rune(term)
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == term {
+				for j := i; j < i+len(t); j++ {
+					marked[j] = true
+				}
+				found = true
+			}
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	var b strings.Builder
+	for i, r := range runes {
+		if marked[i] && (i == 0 || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(r)))
+		if marked[i] && (i == len(runes)-1 || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	return b.String(), true
+}
@@ -0,0 +1,35 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTerms(t *testing.T) {
+	assert.Equal(t, []string{"ada", "exa"}, Terms(" Ada@EXA ada "))
+	assert.Equal(t, []string{"zoë", "42"}, Terms("Zoë-42"))
+	assert.Empty(t, Terms(" @.- "))
+}
+
+func TestScore(t *testing.T) {
+	assert.Equal(t, 1.0, Score([]string{"ada"}, "Ada", "Lovelace"))
+	assert.Equal(t, 1.0, Score([]string{"love", "ada"}, "Ada", "Lovelace"))
+	assert.Equal(t, 0.5, Score([]string{"lace"}, "Ada", "Lovelace"))
+	assert.Equal(t, 0.5, Score([]string{"ada", "bob"}, "Ada", "Lovelace"))
+	assert.Equal(t, 0.0, Score([]string{"bob"}, "Ada", "Lovelace"))
+	assert.Equal(t, 0.0, Score(nil, "Ada"))
+}
+
+func TestHighlight(t *testing.T) {
+	got, ok := Highlight("ada.lovelace@example.com", []string{"ada", "love", "lace"})
+	assert.True(t, ok)
+	assert.Equal(t, "<mark>ada</mark>.<mark>lovelace</mark>@example.com", got)
+
+	got, ok = Highlight("Zoë <Smith>", []string{"zoë"})
+	assert.True(t, ok)
+	assert.Equal(t, "<mark>Zoë</mark> &lt;Smith&gt;", got)
+
+	_, ok = Highlight("Ada", []string{"bob"})
+	assert.False(t, ok)
+}
@@ -0,0 +1,86 @@
+package tracing
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	userapi "github.com/interimme/userapi/proto"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// traceRecordingServer captures the span context seen by the gRPC handler.
+type traceRecordingServer struct {
+	userapi.UnimplementedUserServiceServer
+	spanContext trace.SpanContext
+}
+
+func (s *traceRecordingServer) GetUser(ctx context.Context, req *userapi.GetUserRequest) (*userapi.GetUserResponse, error) {
+	s.spanContext = trace.SpanContextFromContext(ctx)
+	return &userapi.GetUserResponse{User: &userapi.User{Id: req.GetId()}}, nil
+}
+
+func TestPropagationFromGatewayToGRPC(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	// In-process gRPC server
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	backend := &traceRecordingServer{}
+	userapi.RegisterUserServiceServer(grpcServer, backend)
+	go func() { _ = grpcServer.Serve(listener) }()
+	defer grpcServer.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return listener.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	gwMux := runtime.NewServeMux(runtime.WithMiddlewares(GatewayMiddleware))
+	require.NoError(t, userapi.RegisterUserServiceHandler(context.Background(), gwMux, conn))
+	handler := HTTPHandler(gwMux, "gateway")
+
+	// The caller's trace context must be continued all the way to the gRPC handler
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest(http.MethodGet, "/user/42", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	w := httptest.NewRecorder()
+
+	handler.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, traceID, backend.spanContext.TraceID().String())
+
+	var names []string
+	for _, span := range recorder.Ended() {
+		assert.Equal(t, traceID, span.SpanContext().TraceID().String())
+		names = append(names, span.Name())
+	}
+	assert.Contains(t, names, "GET /user/{id=*}")
+	assert.Contains(t, names, "userapi.UserService/GetUser")
+}
+
+func TestSetup_UnknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+
+	assert.Error(t, err)
+}
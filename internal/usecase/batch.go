@@ -0,0 +1,214 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+
+	appErrors "github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/entity"
+)
+
+// Batch limits used without WithBatchLimits
+const (
+	DefaultMaxBatchGet   = 1000
+	DefaultMaxBatchWrite = 100
+)
+
+// WithBatchLimits sets the maximum number of IDs in a BatchGetUsers request
+// and of users in a BatchCreateUsers or BatchDeleteUsers request
+func WithBatchLimits(maxGet, maxWrite int) Option {
+	return func(uc *UserUseCase) {
+		uc.maxBatchGet = maxGet
+		uc.maxBatchWrite = maxWrite
+	}
+}
+
+// BatchGetUsers retrieves the users with the given IDs in one lookup. The
+// results are in the order of ids; missing users have ErrNotFound.
+func (uc *UserUseCase) BatchGetUsers(ctx context.Context, ids []uuid.UUID) (_ []*entity.UserResult, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.BatchGetUsers")
+	defer func() { endSpan(span, err) }()
+
+	if err := checkBatchSize("ids", len(ids), uc.maxBatchGet); err != nil {
+		return nil, err
+	}
+	users, err := uc.repo.GetByIDs(ctx, distinct(ids))
+	if err != nil {
+		return nil, appErrors.ErrInternalServerError
+	}
+	byID := make(map[uuid.UUID]*entity.User, len(users))
+	for _, user := range users {
+		byID[user.ID] = user
+	}
+
+	results := make([]*entity.UserResult, len(ids))
+	for i, id := range ids {
+		if user, ok := byID[id]; ok {
+			results[i] = &entity.UserResult{ID: id, User: user}
+		} else {
+			results[i] = &entity.UserResult{ID: id, Err: appErrors.ErrNotFound}
+		}
+	}
+	return results, nil
+}
+
+// BatchCreateUsers creates users. Unless atomic is set, every user is created
+// as by CreateUser and the results report each outcome. With atomic set, all
+// users are created in one transaction, or none if any of them fails, in
+// which case the error names the failing user.
+func (uc *UserUseCase) BatchCreateUsers(ctx context.Context, users []*entity.User, atomic bool) (_ []*entity.UserResult, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.BatchCreateUsers")
+	defer func() { endSpan(span, err) }()
+
+	if err := checkBatchSize("users", len(users), uc.maxBatchWrite); err != nil {
+		return nil, err
+	}
+	results := make([]*entity.UserResult, len(users))
+	if !atomic {
+		for i, user := range users {
+			if err := uc.CreateUser(ctx, user); err != nil {
+				results[i] = &entity.UserResult{Err: err}
+				continue
+			}
+			results[i] = &entity.UserResult{ID: user.ID, User: user}
+		}
+		return results, nil
+	}
+
+	emails := make(map[string]int, len(users))
+	for i, user := range users {
+		user.ID = uuid.New()
+		if err := uc.validate(user); err != nil {
+			uc.metrics.ValidationFailed("create")
+			return nil, itemError("users", i, appErrors.NewAppError(codes.InvalidArgument, err.Error()))
+		}
+		uc.setCanonicalEmail(user)
+		if j, ok := emails[user.CanonicalEmail]; ok {
+			return nil, itemError("users", i, appErrors.NewAppError(codes.AlreadyExists, fmt.Sprintf("email already used by users[%d]", j)))
+		}
+		emails[user.CanonicalEmail] = i
+	}
+
+	// failed is the index of the user being created when the transaction fails
+	failed := -1
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context, repos Repositories) error {
+		for i, user := range users {
+			failed = i
+			if existingUser, _ := repos.Users.GetByEmail(ctx, user.CanonicalEmail); existingUser != nil {
+				return appErrors.ErrConflict
+			}
+			if err := repos.Users.Create(ctx, user); err != nil {
+				return err
+			}
+		}
+		failed = -1
+		return nil
+	})
+	if err != nil {
+		if failed >= 0 {
+			return nil, itemError("users", failed, txError(err))
+		}
+		return nil, txError(err)
+	}
+
+	for i, user := range users {
+		results[i] = &entity.UserResult{ID: user.ID, User: user}
+		uc.metrics.UserCreated()
+	}
+	return results, nil
+}
+
+// BatchDeleteUsers deletes the users with the given IDs in one statement.
+// Unless atomic is set, missing users have ErrNotFound in the results and
+// the others are deleted. With atomic set, nothing is deleted if any user is
+// missing.
+func (uc *UserUseCase) BatchDeleteUsers(ctx context.Context, ids []uuid.UUID, atomic bool) (_ []*entity.UserResult, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.BatchDeleteUsers")
+	defer func() { endSpan(span, err) }()
+
+	if err := checkBatchSize("ids", len(ids), uc.maxBatchWrite); err != nil {
+		return nil, err
+	}
+	var found map[uuid.UUID]bool
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context, repos Repositories) error {
+		existing, err := repos.Users.GetByIDs(ctx, distinct(ids))
+		if err != nil {
+			return err
+		}
+		found = make(map[uuid.UUID]bool, len(existing))
+		present := make([]uuid.UUID, len(existing))
+		for i, user := range existing {
+			found[user.ID] = true
+			present[i] = user.ID
+		}
+		if atomic {
+			for i, id := range ids {
+				if !found[id] {
+					return itemError("ids", i, appErrors.ErrNotFound)
+				}
+			}
+		}
+		if len(present) == 0 {
+			return nil
+		}
+
+		deleted, err := repos.Users.DeleteByIDs(ctx, present)
+		if err != nil {
+			return err
+		}
+		// Users deleted concurrently count as deleted, except in atomic mode
+		if atomic && deleted != int64(len(present)) {
+			return appErrors.ErrNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, txError(err)
+	}
+
+	results := make([]*entity.UserResult, len(ids))
+	for i, id := range ids {
+		if found[id] {
+			results[i] = &entity.UserResult{ID: id}
+		} else {
+			results[i] = &entity.UserResult{ID: id, Err: appErrors.ErrNotFound}
+		}
+	}
+	for range found {
+		uc.metrics.UserDeleted()
+	}
+	return results, nil
+}
+
+// checkBatchSize checks that a batch of n entries in the request field name is within limit.
+func checkBatchSize(name string, n, limit int) error {
+	switch {
+	case n == 0:
+		return appErrors.NewAppError(codes.InvalidArgument, name+" must not be empty")
+	case n > limit:
+		return appErrors.NewAppError(codes.InvalidArgument, fmt.Sprintf("%s must not have more than %d entries, got %d", name, limit, n))
+	}
+	return nil
+}
+
+// itemError prefixes the message of a use case error with the batch entry it concerns, e.g. "users[3]: email already exists".
+func itemError(name string, i int, err error) *appErrors.AppError {
+	appErr := appErrors.FromError(err)
+	return appErrors.NewAppError(appErr.Code, fmt.Sprintf("%s[%d]: %s", name, i, appErr.Message))
+}
+
+// distinct returns ids without repetitions, in their order.
+func distinct(ids []uuid.UUID) []uuid.UUID {
+	seen := make(map[uuid.UUID]bool, len(ids))
+	unique := make([]uuid.UUID, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
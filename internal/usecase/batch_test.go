@@ -0,0 +1,155 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	appErrors "github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/usecase/mocks"
+)
+
+func TestBatchGetUsers_ResultsInRequestOrder(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	alice := &entity.User{ID: uuid.New(), Firstname: "Alice"}
+	bob := &entity.User{ID: uuid.New(), Firstname: "Bob"}
+	missing := uuid.New()
+	// Repeated IDs are looked up once
+	mockRepo.On("GetByIDs", mock.Anything, []uuid.UUID{bob.ID, missing, alice.ID}).Return([]*entity.User{alice, bob}, nil)
+
+	results, err := userUseCase.BatchGetUsers(context.Background(), []uuid.UUID{bob.ID, missing, alice.ID, bob.ID})
+
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.UserResult{
+		{ID: bob.ID, User: bob},
+		{ID: missing, Err: appErrors.ErrNotFound},
+		{ID: alice.ID, User: alice},
+		{ID: bob.ID, User: bob},
+	}, results)
+}
+
+func TestBatchGetUsers_BatchSize(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithBatchLimits(2, 2))
+
+	_, err := userUseCase.BatchGetUsers(context.Background(), nil)
+	assert.Equal(t, appErrors.NewAppError(codes.InvalidArgument, "ids must not be empty"), err)
+
+	_, err = userUseCase.BatchGetUsers(context.Background(), []uuid.UUID{uuid.New(), uuid.New(), uuid.New()})
+	assert.Equal(t, appErrors.NewAppError(codes.InvalidArgument, "ids must not have more than 2 entries, got 3"), err)
+	mockRepo.AssertNotCalled(t, "GetByIDs", mock.Anything, mock.Anything)
+}
+
+func TestBatchCreateUsers_PartialSuccess(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(nil, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "bob@example.com").Return(&entity.User{ID: uuid.New()}, nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil).Once()
+
+	alice := &entity.User{Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28}
+	bob := &entity.User{Firstname: "Bob", Lastname: "Jones", Email: "bob@example.com", Age: 30}
+	results, err := userUseCase.BatchCreateUsers(context.Background(), []*entity.User{alice, bob}, false)
+
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	assert.Equal(t, &entity.UserResult{ID: alice.ID, User: alice}, results[0])
+	assert.Equal(t, &entity.UserResult{Err: appErrors.ErrConflict}, results[1])
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBatchCreateUsers_AtomicFailureCreatesNothing(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	uow := &fakeUnitOfWork{repos: Repositories{Users: mockRepo}}
+	userUseCase := NewUserUseCase(mockRepo, WithUnitOfWork(uow))
+
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(nil, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "bob@example.com").Return(&entity.User{ID: uuid.New()}, nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil)
+
+	results, err := userUseCase.BatchCreateUsers(context.Background(), []*entity.User{
+		{Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28},
+		{Firstname: "Bob", Lastname: "Jones", Email: "bob@example.com", Age: 30},
+	}, true)
+
+	assert.Nil(t, results)
+	assert.Equal(t, appErrors.NewAppError(codes.AlreadyExists, "users[1]: email already exists"), err)
+	// Alice was created in the transaction, which the failure rolls back
+	require.Len(t, uow.errs, 1)
+	assert.Error(t, uow.errs[0])
+}
+
+func TestBatchCreateUsers_AtomicValidatesFirst(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	for _, tc := range []struct {
+		name  string
+		users []*entity.User
+		want  error
+	}{
+		{
+			name: "invalid user",
+			users: []*entity.User{
+				{Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28},
+				{Firstname: "Bob", Lastname: "Jones", Email: "bob@example.com"},
+			},
+			want: appErrors.NewAppError(codes.InvalidArgument, "users[1]: date_of_birth is required"),
+		},
+		{
+			name: "email repeated in the batch",
+			users: []*entity.User{
+				{Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28},
+				{Firstname: "Alice", Lastname: "Jones", Email: "alice@example.com", Age: 30},
+			},
+			want: appErrors.NewAppError(codes.AlreadyExists, "users[1]: email already used by users[0]"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := userUseCase.BatchCreateUsers(context.Background(), tc.users, true)
+			assert.Equal(t, tc.want, err)
+		})
+	}
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+func TestBatchDeleteUsers_PartialSuccess(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	existing, missing := uuid.New(), uuid.New()
+	mockRepo.On("GetByIDs", mock.Anything, []uuid.UUID{missing, existing}).Return([]*entity.User{{ID: existing}}, nil)
+	mockRepo.On("DeleteByIDs", mock.Anything, []uuid.UUID{existing}).Return(int64(1), nil)
+
+	results, err := userUseCase.BatchDeleteUsers(context.Background(), []uuid.UUID{missing, existing}, false)
+
+	require.NoError(t, err)
+	assert.Equal(t, []*entity.UserResult{
+		{ID: missing, Err: appErrors.ErrNotFound},
+		{ID: existing},
+	}, results)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestBatchDeleteUsers_AtomicMissingUserDeletesNothing(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	existing, missing := uuid.New(), uuid.New()
+	mockRepo.On("GetByIDs", mock.Anything, []uuid.UUID{existing, missing}).Return([]*entity.User{{ID: existing}}, nil)
+
+	results, err := userUseCase.BatchDeleteUsers(context.Background(), []uuid.UUID{existing, missing}, true)
+
+	assert.Nil(t, results)
+	assert.Equal(t, appErrors.NewAppError(codes.NotFound, "ids[1]: user not found"), err)
+	mockRepo.AssertNotCalled(t, "DeleteByIDs", mock.Anything, mock.Anything)
+}
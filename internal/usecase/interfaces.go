@@ -1,7 +1,11 @@
 package usecase
 
 import (
+	"context"
+
+	appErrors "github.com/interimme/userapi/internal/apperrors"
 	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
 
 	"github.com/google/uuid"
 )
@@ -9,9 +13,87 @@ import (
 // UserRepository interface defines the methods that any
 // data storage provider must implement to get and store users
 type UserRepository interface {
-	Create(user *entity.User) error
-	GetByID(id uuid.UUID) (*entity.User, error)
-	GetByEmail(email string) (*entity.User, error)
-	Update(user *entity.User) error
-	Delete(user *entity.User) error
+	Create(ctx context.Context, user *entity.User) error
+	GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error)
+	// GetByIDForUpdate is GetByID that also locks the user until the
+	// surrounding transaction ends; outside a transaction it is GetByID
+	GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entity.User, error)
+	// GetByEmail returns the user with the canonical email address
+	GetByEmail(ctx context.Context, canonicalEmail string) (*entity.User, error)
+	// Exists reports whether a user with the ID exists
+	Exists(ctx context.Context, id uuid.UUID) (bool, error)
+	// GetByIDs returns the users with the given IDs in no particular order,
+	// leaving out missing ones
+	GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entity.User, error)
+	Update(ctx context.Context, user *entity.User) error
+	Delete(ctx context.Context, user *entity.User) error
+	// DeleteByIDs deletes the users with the given IDs and returns how many existed
+	DeleteByIDs(ctx context.Context, ids []uuid.UUID) (int64, error)
+	// List returns up to limit users matching f ordered by ID, starting after
+	// afterID (uuid.Nil starts at the beginning). A nil f matches all users
+	List(ctx context.Context, f *filter.Filter, afterID uuid.UUID, limit int) ([]*entity.User, error)
+	// Search returns up to limit users whose names or email match query,
+	// best matches first. Highlights are filled in by the use case
+	Search(ctx context.Context, query string, limit int) ([]*entity.UserMatch, error)
+}
+
+// Repositories are the repositories bound to a transaction.
+type Repositories struct {
+	Users UserRepository
+}
+
+// UnitOfWork runs use case steps in a transaction.
+type UnitOfWork interface {
+	// WithinTx runs fn in a transaction, passing repositories bound to it.
+	// The transaction commits if fn returns nil and rolls back otherwise, and
+	// fn's error is returned as is. When the transaction fails because of a
+	// conflict with concurrent transactions it is retried, so fn may run more
+	// than once and must not have effects outside the repositories.
+	WithinTx(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error
+}
+
+// noTx is used when no UnitOfWork is configured; it runs fn directly on the
+// use case's repositories
+type noTx struct {
+	repos Repositories
+}
+
+func (t noTx) WithinTx(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	return fn(ctx, t.repos)
 }
+
+// Metrics records business-level events of the use cases.
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	UserCreated()
+	UserUpdated()
+	UserDeleted()
+	ValidationFailed(operation string)
+}
+
+// noopMetrics is used when no Metrics implementation is configured
+type noopMetrics struct{}
+
+func (noopMetrics) UserCreated()            {}
+func (noopMetrics) UserUpdated()            {}
+func (noopMetrics) UserDeleted()            {}
+func (noopMetrics) ValidationFailed(string) {}
+
+// PermissionLookupUsers allows finding users by unique keys other than their
+// ID, checking whether users exist, and listing and searching users. Such
+// calls reveal whether someone has an account, e.g. for a guessed email
+// address, so they need authorization.
+const PermissionLookupUsers = "users.lookup"
+
+// Authorizer decides whether the caller of ctx holds a permission. It
+// returns nil if so and an application error, typically ErrUnauthorized or
+// ErrForbidden, otherwise.
+type Authorizer interface {
+	Authorize(ctx context.Context, permission string) error
+}
+
+// denyAll is used when no Authorizer is configured, so that privileged use
+// cases stay closed by default
+type denyAll struct{}
+
+func (denyAll) Authorize(context.Context, string) error { return appErrors.ErrForbidden }
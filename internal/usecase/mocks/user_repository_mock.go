@@ -1,7 +1,10 @@
 package mocks
 
 import (
+	"context"
+
 	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/mock"
@@ -12,33 +15,75 @@ type UserRepository struct {
 	mock.Mock
 }
 
-func (m *UserRepository) Create(user *entity.User) error {
-	args := m.Called(user)
+func (m *UserRepository) Create(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
-func (m *UserRepository) GetByID(id uuid.UUID) (*entity.User, error) {
-	args := m.Called(id)
+func (m *UserRepository) GetByID(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	args := m.Called(ctx, id)
+	if user, ok := args.Get(0).(*entity.User); ok {
+		return user, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserRepository) GetByIDForUpdate(ctx context.Context, id uuid.UUID) (*entity.User, error) {
+	args := m.Called(ctx, id)
 	if user, ok := args.Get(0).(*entity.User); ok {
 		return user, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *UserRepository) GetByEmail(email string) (*entity.User, error) {
-	args := m.Called(email)
+func (m *UserRepository) GetByEmail(ctx context.Context, canonicalEmail string) (*entity.User, error) {
+	args := m.Called(ctx, canonicalEmail)
 	if user, ok := args.Get(0).(*entity.User); ok {
 		return user, args.Error(1)
 	}
 	return nil, args.Error(1)
 }
 
-func (m *UserRepository) Update(user *entity.User) error {
-	args := m.Called(user)
+func (m *UserRepository) Exists(ctx context.Context, id uuid.UUID) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *UserRepository) GetByIDs(ctx context.Context, ids []uuid.UUID) ([]*entity.User, error) {
+	args := m.Called(ctx, ids)
+	if users, ok := args.Get(0).([]*entity.User); ok {
+		return users, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserRepository) Update(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
 
-func (m *UserRepository) Delete(user *entity.User) error {
-	args := m.Called(user)
+func (m *UserRepository) Delete(ctx context.Context, user *entity.User) error {
+	args := m.Called(ctx, user)
 	return args.Error(0)
 }
+
+func (m *UserRepository) DeleteByIDs(ctx context.Context, ids []uuid.UUID) (int64, error) {
+	args := m.Called(ctx, ids)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *UserRepository) List(ctx context.Context, f *filter.Filter, afterID uuid.UUID, limit int) ([]*entity.User, error) {
+	args := m.Called(ctx, f, afterID, limit)
+	if users, ok := args.Get(0).([]*entity.User); ok {
+		return users, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *UserRepository) Search(ctx context.Context, query string, limit int) ([]*entity.UserMatch, error) {
+	args := m.Called(ctx, query, limit)
+	if matches, ok := args.Get(0).([]*entity.UserMatch); ok {
+		return matches, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
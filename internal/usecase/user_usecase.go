@@ -1,55 +1,138 @@
 package usecase
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	appErrors "github.com/interimme/userapi/internal/apperrors"
+	"github.com/interimme/userapi/internal/email"
 	"github.com/interimme/userapi/internal/entity"
-	"net/http"
-	"time"
+	"github.com/interimme/userapi/internal/filter"
+	"github.com/interimme/userapi/internal/search"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
 	"gorm.io/gorm"
 )
 
+// tracer records a span for every use case invocation
+var tracer = otel.Tracer("github.com/interimme/userapi/internal/usecase")
+
 // UserUseCase struct implements the methods required by the controller's UserUseCase interface
 type UserUseCase struct {
-	repo UserRepository
+	repo          UserRepository
+	tx            UnitOfWork
+	authz         Authorizer
+	metrics       Metrics
+	maxBatchGet   int
+	maxBatchWrite int
+	minAge        uint
+
+	providerEmailRules bool
+}
+
+// Option configures optional dependencies of UserUseCase
+type Option func(*UserUseCase)
+
+// WithMetrics records use case events with m
+func WithMetrics(m Metrics) Option {
+	return func(uc *UserUseCase) {
+		uc.metrics = m
+	}
+}
+
+// WithUnitOfWork runs the steps of each use case in a transaction of u.
+// Without it, steps run one by one on the repository.
+func WithUnitOfWork(u UnitOfWork) Option {
+	return func(uc *UserUseCase) {
+		uc.tx = u
+	}
+}
+
+// WithAuthorizer authorizes the privileged use cases with a. Without it they
+// are refused.
+func WithAuthorizer(a Authorizer) Option {
+	return func(uc *UserUseCase) {
+		uc.authz = a
+	}
+}
+
+// WithProviderEmailRules makes the canonical email addresses, which must be
+// unique, also merge the variants that well-known providers deliver to the
+// same mailbox, such as a.lice+news@gmail.com and alice@gmail.com; see
+// email.Address.WithProviderRules.
+func WithProviderEmailRules() Option {
+	return func(uc *UserUseCase) {
+		uc.providerEmailRules = true
+	}
+}
+
+// WithMinimumAge refuses to create users younger than years and updates that
+// leave a user younger. Imported users are not checked.
+func WithMinimumAge(years uint) Option {
+	return func(uc *UserUseCase) {
+		uc.minAge = years
+	}
 }
 
 // NewUserUseCase creates a new instance of UserUseCase
-func NewUserUseCase(repo UserRepository) *UserUseCase {
-	return &UserUseCase{
-		repo: repo,
+func NewUserUseCase(repo UserRepository, opts ...Option) *UserUseCase {
+	uc := &UserUseCase{
+		repo:          repo,
+		tx:            noTx{repos: Repositories{Users: repo}},
+		authz:         denyAll{},
+		metrics:       noopMetrics{},
+		maxBatchGet:   DefaultMaxBatchGet,
+		maxBatchWrite: DefaultMaxBatchWrite,
+	}
+	for _, opt := range opts {
+		opt(uc)
 	}
+	return uc
 }
 
 // CreateUser creates a new user
-func (uc *UserUseCase) CreateUser(user *entity.User) error {
+func (uc *UserUseCase) CreateUser(ctx context.Context, user *entity.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.CreateUser")
+	defer func() { endSpan(span, err) }()
+
 	user.ID = uuid.New()
-	user.Created = time.Now().UTC()
 
 	// Validate the user entity
-	if err := user.Validate(); err != nil {
-		return &appErrors.AppError{Code: http.StatusBadRequest, Message: err.Error()}
+	if err := uc.validate(user); err != nil {
+		uc.metrics.ValidationFailed("create")
+		return appErrors.NewAppError(codes.InvalidArgument, err.Error())
 	}
+	uc.setCanonicalEmail(user)
 
-	// Check if the email already exists
-	existingUser, _ := uc.repo.GetByEmail(user.Email)
-	if existingUser != nil {
-		return appErrors.ErrConflict
-	}
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context, repos Repositories) error {
+		// Check if the email already exists
+		if existingUser, _ := repos.Users.GetByEmail(ctx, user.CanonicalEmail); existingUser != nil {
+			return appErrors.ErrConflict
+		}
 
-	// Create the user in the repository
-	if err := uc.repo.Create(user); err != nil {
-		return appErrors.ErrInternalServerError
+		// Create the user in the repository
+		return repos.Users.Create(ctx, user)
+	})
+	if err != nil {
+		return txError(err)
 	}
 
+	uc.metrics.UserCreated()
 	return nil
 }
 
 // GetUser retrieves a user by ID
-func (uc *UserUseCase) GetUser(id uuid.UUID) (*entity.User, error) {
-	user, err := uc.repo.GetByID(id)
+func (uc *UserUseCase) GetUser(ctx context.Context, id uuid.UUID) (_ *entity.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.GetUser")
+	defer func() { endSpan(span, err) }()
+
+	user, err := uc.repo.GetByID(ctx, id)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, appErrors.ErrNotFound
@@ -59,49 +142,321 @@ func (uc *UserUseCase) GetUser(id uuid.UUID) (*entity.User, error) {
 	return user, nil
 }
 
-// UpdateUser updates an existing user
-func (uc *UserUseCase) UpdateUser(user *entity.User) error {
-	// Validate the user entity
-	if err := user.Validate(); err != nil {
-		return &appErrors.AppError{Code: http.StatusBadRequest, Message: err.Error()}
+// LookupUser retrieves a user by email address, compared in canonical form.
+// It requires PermissionLookupUsers, which is checked first, so unauthorized
+// callers learn nothing about the address.
+func (uc *UserUseCase) LookupUser(ctx context.Context, address string) (_ *entity.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.LookupUser")
+	defer func() { endSpan(span, err) }()
+
+	if err := uc.authz.Authorize(ctx, PermissionLookupUsers); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(address) == "" {
+		return nil, appErrors.NewAppError(codes.InvalidArgument, "email is required")
+	}
+	addr, err := uc.parseEmail(address)
+	if err != nil {
+		return nil, appErrors.NewAppError(codes.InvalidArgument, err.Error())
 	}
 
-	// Retrieve the existing user
-	existingUser, err := uc.repo.GetByID(user.ID)
+	user, err := uc.repo.GetByEmail(ctx, addr.Canonical())
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound), err == nil && user == nil:
+		return nil, appErrors.ErrNotFound
+	case err != nil:
+		return nil, appErrors.ErrInternalServerError
+	}
+	return user, nil
+}
+
+// CheckUserExists returns nil if the user exists and ErrNotFound otherwise,
+// without reading the user. Like LookupUser it requires PermissionLookupUsers.
+func (uc *UserUseCase) CheckUserExists(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.CheckUserExists")
+	defer func() { endSpan(span, err) }()
+
+	if err := uc.authz.Authorize(ctx, PermissionLookupUsers); err != nil {
+		return err
+	}
+	exists, err := uc.repo.Exists(ctx, id)
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return appErrors.ErrNotFound
-		}
 		return appErrors.ErrInternalServerError
 	}
+	if !exists {
+		return appErrors.ErrNotFound
+	}
+	return nil
+}
 
-	// Update the user's information
-	existingUser.Firstname = user.Firstname
-	existingUser.Lastname = user.Lastname
-	existingUser.Email = user.Email
-	existingUser.Age = user.Age
+// UpdateUser updates an existing user
+func (uc *UserUseCase) UpdateUser(ctx context.Context, user *entity.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.UpdateUser")
+	defer func() { endSpan(span, err) }()
 
-	// Save the updated user
-	if err := uc.repo.Update(existingUser); err != nil {
-		return appErrors.ErrInternalServerError
+	// Validate the user entity
+	if err := uc.validate(user); err != nil {
+		uc.metrics.ValidationFailed("update")
+		return appErrors.NewAppError(codes.InvalidArgument, err.Error())
 	}
+	uc.setCanonicalEmail(user)
 
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context, repos Repositories) error {
+		// Retrieve and lock the existing user, so concurrent updates apply one after the other
+		existingUser, err := repos.Users.GetByIDForUpdate(ctx, user.ID)
+		if err != nil {
+			return err
+		}
+
+		// Update the user's information
+		existingUser.Firstname = user.Firstname
+		existingUser.Lastname = user.Lastname
+		existingUser.Email = user.Email
+		existingUser.CanonicalEmail = user.CanonicalEmail
+		// Clients that predate dates of birth send the age only; unless
+		// they change it, the stored date of birth is kept
+		if !user.DateOfBirth.IsZero() || user.Age != existingUser.CurrentAge() {
+			existingUser.DateOfBirth = user.DateOfBirth
+			existingUser.Age = user.Age
+		}
+
+		// Save the updated user
+		return repos.Users.Update(ctx, existingUser)
+	})
+	if err != nil {
+		return txError(err)
+	}
+
+	uc.metrics.UserUpdated()
 	return nil
 }
 
 // DeleteUser deletes a user by ID
-func (uc *UserUseCase) DeleteUser(id uuid.UUID) error {
-	user, err := uc.repo.GetByID(id)
+func (uc *UserUseCase) DeleteUser(ctx context.Context, id uuid.UUID) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.DeleteUser")
+	defer func() { endSpan(span, err) }()
+
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context, repos Repositories) error {
+		user, err := repos.Users.GetByIDForUpdate(ctx, id)
+		if err != nil {
+			return err
+		}
+		return repos.Users.Delete(ctx, user)
+	})
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return appErrors.ErrNotFound
+		return txError(err)
+	}
+
+	uc.metrics.UserDeleted()
+	return nil
+}
+
+// UserFilterFields are the fields that filters of users may refer to.
+var UserFilterFields = filter.Fields{
+	"id":            filter.UUID,
+	"firstname":     filter.String,
+	"lastname":      filter.String,
+	"email":         filter.String,
+	"age":           filter.Int,
+	"date_of_birth": filter.Date,
+	"created":       filter.Timestamp,
+	"updated":       filter.Timestamp,
+}
+
+// Limits of ListUsers
+const (
+	DefaultListLimit = 50
+	MaxListLimit     = 1000
+)
+
+// ListLimit returns the number of users ListUsers returns at most for limit,
+// so that callers can tell a full page from the last one.
+func ListLimit(limit int) int {
+	if limit == 0 {
+		return DefaultListLimit
+	}
+	return min(limit, MaxListLimit)
+}
+
+// ListUsers returns up to limit users matching filterExpr, an AIP-160 filter
+// over UserFilterFields, ordered by ID and starting after afterID. An empty
+// filterExpr matches all users. Callers page through all users by passing the
+// ID of the last user returned. A limit of 0 selects DefaultListLimit and
+// larger limits are reduced to MaxListLimit. Filters can probe email
+// addresses, so like LookupUser it requires PermissionLookupUsers.
+func (uc *UserUseCase) ListUsers(ctx context.Context, filterExpr string, afterID uuid.UUID, limit int) (_ []*entity.User, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.ListUsers")
+	defer func() { endSpan(span, err) }()
+
+	if err := uc.authz.Authorize(ctx, PermissionLookupUsers); err != nil {
+		return nil, err
+	}
+	if limit < 0 {
+		return nil, appErrors.NewAppError(codes.InvalidArgument, "limit must not be negative")
+	}
+	limit = ListLimit(limit)
+	f, err := filter.Parse(filterExpr, UserFilterFields)
+	if err != nil {
+		return nil, appErrors.NewAppError(codes.InvalidArgument, err.Error())
+	}
+	users, err := uc.repo.List(ctx, f, afterID, limit)
+	if err != nil {
+		return nil, appErrors.ErrInternalServerError
+	}
+	return users, nil
+}
+
+// Limits of SearchUsers
+const (
+	DefaultSearchLimit = 20
+	MaxSearchLimit     = 100
+	MaxSearchQueryLen  = 200
+)
+
+// SearchUsers returns up to limit users whose names or email match query,
+// best matches first, with the matches highlighted. A limit of 0 selects
+// DefaultSearchLimit and larger limits are reduced to MaxSearchLimit. A
+// query for an email address finds its user, so like LookupUser it requires
+// PermissionLookupUsers.
+func (uc *UserUseCase) SearchUsers(ctx context.Context, query string, limit int) (_ []*entity.UserMatch, err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.SearchUsers")
+	defer func() { endSpan(span, err) }()
+
+	if err := uc.authz.Authorize(ctx, PermissionLookupUsers); err != nil {
+		return nil, err
+	}
+
+	query = strings.TrimSpace(query)
+	terms := search.Terms(query)
+	switch {
+	case len(terms) == 0:
+		return nil, appErrors.NewAppError(codes.InvalidArgument, "query must contain a letter or digit")
+	case utf8.RuneCountInString(query) > MaxSearchQueryLen:
+		return nil, appErrors.NewAppError(codes.InvalidArgument, fmt.Sprintf("query must not be longer than %d characters", MaxSearchQueryLen))
+	case limit < 0:
+		return nil, appErrors.NewAppError(codes.InvalidArgument, "limit must not be negative")
+	case limit == 0:
+		limit = DefaultSearchLimit
+	}
+	limit = min(limit, MaxSearchLimit)
+
+	matches, err := uc.repo.Search(ctx, query, limit)
+	if err != nil {
+		return nil, appErrors.ErrInternalServerError
+	}
+	for _, m := range matches {
+		m.Highlights = make(map[string]string)
+		for field, text := range map[string]string{"firstname": m.User.Firstname, "lastname": m.User.Lastname, "email": m.User.Email} {
+			if highlighted, ok := search.Highlight(text, terms); ok {
+				m.Highlights[field] = highlighted
+			}
 		}
-		return appErrors.ErrInternalServerError
 	}
+	return matches, nil
+}
 
-	if err := uc.repo.Delete(user); err != nil {
-		return appErrors.ErrInternalServerError
+// ImportUser creates a user that keeps its ID and creation time, e.g. one
+// exported from another database. Missing values are assigned as in CreateUser.
+func (uc *UserUseCase) ImportUser(ctx context.Context, user *entity.User) (err error) {
+	ctx, span := tracer.Start(ctx, "UserUseCase.ImportUser")
+	defer func() { endSpan(span, err) }()
+
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+
+	// Validate the user entity
+	if err := user.Validate(); err != nil {
+		uc.metrics.ValidationFailed("import")
+		return appErrors.NewAppError(codes.InvalidArgument, err.Error())
 	}
+	uc.setCanonicalEmail(user)
 
+	err = uc.tx.WithinTx(ctx, func(ctx context.Context, repos Repositories) error {
+		// Check if the ID or the email already exists
+		if existingUser, _ := repos.Users.GetByID(ctx, user.ID); existingUser != nil {
+			return appErrors.NewAppError(codes.AlreadyExists, "user already exists")
+		}
+		if existingUser, _ := repos.Users.GetByEmail(ctx, user.CanonicalEmail); existingUser != nil {
+			return appErrors.ErrConflict
+		}
+
+		// Create the user in the repository
+		return repos.Users.Create(ctx, user)
+	})
+	if err != nil {
+		return txError(err)
+	}
+
+	uc.metrics.UserCreated()
 	return nil
 }
+
+// validate validates a user written by a client and applies the minimum age.
+func (uc *UserUseCase) validate(user *entity.User) error {
+	if err := user.Validate(); err != nil {
+		return err
+	}
+	if user.CurrentAge() < uc.minAge {
+		return fmt.Errorf("user must be at least %d years old", uc.minAge)
+	}
+	return nil
+}
+
+// parseEmail parses an email address and computes its canonical form.
+func (uc *UserUseCase) parseEmail(s string) (email.Address, error) {
+	addr, err := email.Parse(s)
+	if err != nil {
+		return email.Address{}, err
+	}
+	if uc.providerEmailRules {
+		addr = addr.WithProviderRules()
+	}
+	return addr, nil
+}
+
+// CanonicalEmail returns the canonical form of an email address under the
+// configured rules; see email.RuleSet.
+func (uc *UserUseCase) CanonicalEmail(address string) (string, error) {
+	addr, err := uc.parseEmail(strings.TrimSpace(address))
+	if err != nil {
+		return "", err
+	}
+	return addr.Canonical(), nil
+}
+
+// setCanonicalEmail sets the canonical form of the email address of a
+// validated user, trimming the address as given.
+func (uc *UserUseCase) setCanonicalEmail(user *entity.User) {
+	if addr, err := uc.parseEmail(user.Email); err == nil {
+		user.Email = addr.String()
+		user.CanonicalEmail = addr.Canonical()
+	}
+}
+
+// txError maps the error of a transaction to the error of a use case.
+// Application errors are returned as is, a missing user is ErrNotFound, a
+// unique constraint violation (e.g. an email taken concurrently) ErrConflict,
+// and anything else ErrInternalServerError.
+func txError(err error) error {
+	var appErr *appErrors.AppError
+	switch {
+	case errors.As(err, &appErr):
+		return appErr
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return appErrors.ErrNotFound
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return appErrors.ErrConflict
+	default:
+		return appErrors.ErrInternalServerError
+	}
+}
+
+// endSpan marks the span as failed when err is set and ends it
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
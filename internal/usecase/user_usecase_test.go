@@ -1,14 +1,21 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	appErrors "github.com/interimme/userapi/internal/apperrors"
 	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/filter"
 	"github.com/interimme/userapi/internal/usecase/mocks"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
 	"gorm.io/gorm"
 )
 
@@ -24,11 +31,11 @@ func TestCreateUser_Success(t *testing.T) {
 	}
 
 	// Mock GetByEmail to return nil, indicating email does not exist
-	mockRepo.On("GetByEmail", "alice@example.com").Return(nil, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(nil, nil)
 	// Mock Create to return nil, indicating successful creation
-	mockRepo.On("Create", mock.AnythingOfType("*entity.User")).Return(nil)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil)
 
-	err := userUseCase.CreateUser(user)
+	err := userUseCase.CreateUser(context.Background(), user)
 
 	require.NoError(t, err, "Expected no error when creating user")
 	mockRepo.AssertExpectations(t)
@@ -45,7 +52,7 @@ func TestCreateUser_ValidationError(t *testing.T) {
 		Age:       28,
 	}
 
-	err := userUseCase.CreateUser(user)
+	err := userUseCase.CreateUser(context.Background(), user)
 
 	require.Error(t, err, "Expected an error due to validation failure")
 	assert.Equal(t, "firstname is required", err.Error())
@@ -63,9 +70,9 @@ func TestCreateUser_EmailExists(t *testing.T) {
 	}
 
 	// Mock existing user with the same email
-	mockRepo.On("GetByEmail", "alice@example.com").Return(&entity.User{}, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(&entity.User{}, nil)
 
-	err := userUseCase.CreateUser(user)
+	err := userUseCase.CreateUser(context.Background(), user)
 
 	require.Error(t, err, "Expected an error due to existing email")
 	assert.Equal(t, "email already exists", err.Error())
@@ -85,9 +92,9 @@ func TestGetUser_Success(t *testing.T) {
 	}
 
 	// Mock GetByID to return the user
-	mockRepo.On("GetByID", userID).Return(user, nil)
+	mockRepo.On("GetByID", mock.Anything, userID).Return(user, nil)
 
-	result, err := userUseCase.GetUser(userID)
+	result, err := userUseCase.GetUser(context.Background(), userID)
 
 	require.NoError(t, err, "Expected no error when getting user")
 	assert.Equal(t, user, result)
@@ -100,9 +107,9 @@ func TestGetUser_NotFound(t *testing.T) {
 	userID := uuid.New()
 
 	// Mock GetByID to return ErrRecordNotFound
-	mockRepo.On("GetByID", userID).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("GetByID", mock.Anything, userID).Return(nil, gorm.ErrRecordNotFound)
 
-	result, err := userUseCase.GetUser(userID)
+	result, err := userUseCase.GetUser(context.Background(), userID)
 
 	require.Error(t, err, "Expected an error due to user not found")
 	require.Nil(t, result)
@@ -130,12 +137,12 @@ func TestUpdateUser_Success(t *testing.T) {
 		Age:       28,
 	}
 
-	// Mock GetByID to return the existing user
-	mockRepo.On("GetByID", userID).Return(existingUser, nil)
+	// Mock GetByIDForUpdate to return the existing user
+	mockRepo.On("GetByIDForUpdate", mock.Anything, userID).Return(existingUser, nil)
 	// Mock Update to return nil
-	mockRepo.On("Update", existingUser).Return(nil)
+	mockRepo.On("Update", mock.Anything, existingUser).Return(nil)
 
-	err := userUseCase.UpdateUser(user)
+	err := userUseCase.UpdateUser(context.Background(), user)
 
 	require.NoError(t, err, "Expected no error when updating user")
 	assert.Equal(t, "Alice", existingUser.Firstname)
@@ -157,7 +164,7 @@ func TestUpdateUser_ValidationError(t *testing.T) {
 		Age:       29,
 	}
 
-	err := userUseCase.UpdateUser(user)
+	err := userUseCase.UpdateUser(context.Background(), user)
 
 	require.Error(t, err, "Expected an error due to validation failure")
 	assert.Equal(t, "firstname is required", err.Error())
@@ -176,10 +183,10 @@ func TestUpdateUser_NotFound(t *testing.T) {
 		Age:       29,
 	}
 
-	// Mock GetByID to return ErrRecordNotFound
-	mockRepo.On("GetByID", userID).Return(nil, gorm.ErrRecordNotFound)
+	// Mock GetByIDForUpdate to return ErrRecordNotFound
+	mockRepo.On("GetByIDForUpdate", mock.Anything, userID).Return(nil, gorm.ErrRecordNotFound)
 
-	err := userUseCase.UpdateUser(user)
+	err := userUseCase.UpdateUser(context.Background(), user)
 
 	require.Error(t, err, "Expected an error due to user not found")
 	assert.Equal(t, "user not found", err.Error())
@@ -194,12 +201,12 @@ func TestDeleteUser_Success(t *testing.T) {
 		ID: userID,
 	}
 
-	// Mock GetByID to return the user
-	mockRepo.On("GetByID", userID).Return(user, nil)
+	// Mock GetByIDForUpdate to return the user
+	mockRepo.On("GetByIDForUpdate", mock.Anything, userID).Return(user, nil)
 	// Mock Delete to return nil
-	mockRepo.On("Delete", user).Return(nil)
+	mockRepo.On("Delete", mock.Anything, user).Return(nil)
 
-	err := userUseCase.DeleteUser(userID)
+	err := userUseCase.DeleteUser(context.Background(), userID)
 
 	require.NoError(t, err, "Expected no error when deleting user")
 }
@@ -210,11 +217,388 @@ func TestDeleteUser_NotFound(t *testing.T) {
 
 	userID := uuid.New()
 
-	// Mock GetByID to return ErrRecordNotFound
-	mockRepo.On("GetByID", userID).Return(nil, gorm.ErrRecordNotFound)
+	// Mock GetByIDForUpdate to return ErrRecordNotFound
+	mockRepo.On("GetByIDForUpdate", mock.Anything, userID).Return(nil, gorm.ErrRecordNotFound)
 
-	err := userUseCase.DeleteUser(userID)
+	err := userUseCase.DeleteUser(context.Background(), userID)
 
 	require.Error(t, err, "Expected an error due to user not found")
 	assert.Equal(t, "user not found", err.Error())
 }
+
+func TestListUsers_Success(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	afterID := uuid.New()
+	users := []*entity.User{{ID: uuid.New()}, {ID: uuid.New()}}
+
+	// Mock List to return one page of users
+	mockRepo.On("List", mock.Anything, (*filter.Filter)(nil), afterID, 2).Return(users, nil)
+
+	result, err := userUseCase.ListUsers(context.Background(), "", afterID, 2)
+
+	require.NoError(t, err, "Expected no error when listing users")
+	assert.Equal(t, users, result)
+}
+
+func TestListUsers_DefaultAndMaximumLimit(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	mockRepo.On("List", mock.Anything, (*filter.Filter)(nil), uuid.Nil, DefaultListLimit).Return([]*entity.User{}, nil).Once()
+	mockRepo.On("List", mock.Anything, (*filter.Filter)(nil), uuid.Nil, MaxListLimit).Return([]*entity.User{}, nil).Once()
+
+	_, err := userUseCase.ListUsers(context.Background(), "", uuid.Nil, 0)
+	require.NoError(t, err)
+	_, err = userUseCase.ListUsers(context.Background(), "", uuid.Nil, MaxListLimit+1)
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestListUsers_InvalidLimit(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	_, err := userUseCase.ListUsers(context.Background(), "", uuid.Nil, -1)
+
+	require.Error(t, err, "Expected an error due to invalid limit")
+	assert.Equal(t, "limit must not be negative", err.Error())
+}
+
+func TestListUsers_RequiresPermission(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	_, err := userUseCase.ListUsers(context.Background(), `email = "ada@example.com"`, uuid.Nil, 10)
+
+	assert.Equal(t, appErrors.ErrForbidden, err)
+	mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestImportUser_KeepsIDAndCreated(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	userID := uuid.New()
+	created := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	user := &entity.User{
+		ID:        userID,
+		Firstname: "Alice",
+		Lastname:  "Smith",
+		Email:     "alice@example.com",
+		Age:       28,
+		Created:   created,
+	}
+
+	// Mock lookups to find neither the ID nor the email
+	mockRepo.On("GetByID", mock.Anything, userID).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything, user).Return(nil)
+
+	err := userUseCase.ImportUser(context.Background(), user)
+
+	require.NoError(t, err, "Expected no error when importing user")
+	assert.Equal(t, userID, user.ID)
+	assert.Equal(t, created, user.Created)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestImportUser_IDExists(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	userID := uuid.New()
+	user := &entity.User{
+		ID:        userID,
+		Firstname: "Alice",
+		Lastname:  "Smith",
+		Email:     "alice@example.com",
+		Age:       28,
+	}
+
+	// Mock GetByIDForUpdate to return the existing user
+	mockRepo.On("GetByID", mock.Anything, userID).Return(user, nil)
+
+	err := userUseCase.ImportUser(context.Background(), user)
+
+	require.Error(t, err, "Expected an error due to existing ID")
+	assert.Equal(t, "user already exists", err.Error())
+	mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+}
+
+// fakeUnitOfWork passes repos to every transaction and records their outcome.
+type fakeUnitOfWork struct {
+	repos Repositories
+	errs  []error
+}
+
+func (u *fakeUnitOfWork) WithinTx(ctx context.Context, fn func(ctx context.Context, repos Repositories) error) error {
+	err := fn(ctx, u.repos)
+	u.errs = append(u.errs, err)
+	return err
+}
+
+func TestUpdateUser_RunsInTransaction(t *testing.T) {
+	mockRepo, txRepo := new(mocks.UserRepository), new(mocks.UserRepository)
+	uow := &fakeUnitOfWork{repos: Repositories{Users: txRepo}}
+	userUseCase := NewUserUseCase(mockRepo, WithUnitOfWork(uow))
+
+	userID := uuid.New()
+	existingUser := &entity.User{ID: userID, Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28}
+	txRepo.On("GetByIDForUpdate", mock.Anything, userID).Return(existingUser, nil)
+	txRepo.On("Update", mock.Anything, existingUser).Return(nil)
+
+	err := userUseCase.UpdateUser(context.Background(), &entity.User{ID: userID, Firstname: "Bob", Lastname: "Smith", Email: "bob@example.com", Age: 30})
+
+	require.NoError(t, err)
+	txRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetByID", mock.Anything, mock.Anything)
+	assert.Equal(t, []error{nil}, uow.errs)
+}
+
+func TestDeleteUser_RepositoryErrorRollsBack(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	uow := &fakeUnitOfWork{repos: Repositories{Users: mockRepo}}
+	userUseCase := NewUserUseCase(mockRepo, WithUnitOfWork(uow))
+
+	user := &entity.User{ID: uuid.New()}
+	failure := errors.New("connection reset")
+	mockRepo.On("GetByIDForUpdate", mock.Anything, user.ID).Return(user, nil)
+	mockRepo.On("Delete", mock.Anything, user).Return(failure)
+
+	err := userUseCase.DeleteUser(context.Background(), user.ID)
+
+	assert.Equal(t, appErrors.ErrInternalServerError, err)
+	// The transaction sees the repository's error, so it can be rolled back or retried
+	assert.Equal(t, []error{failure}, uow.errs)
+}
+
+func TestCreateUser_ConcurrentDuplicateIsConflict(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	// Another user with the email is created after the check
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.User")).Return(gorm.ErrDuplicatedKey)
+
+	err := userUseCase.CreateUser(context.Background(), &entity.User{Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", Age: 28})
+
+	assert.Equal(t, appErrors.ErrConflict, err)
+}
+
+func TestSearchUsers_HighlightsMatches(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	user := &entity.User{ID: uuid.New(), Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+	mockRepo.On("Search", mock.Anything, "ada love", DefaultSearchLimit).Return([]*entity.UserMatch{{User: user, Score: 0.9}}, nil)
+
+	matches, err := userUseCase.SearchUsers(context.Background(), "  ada love ", 0)
+
+	require.NoError(t, err)
+	require.Len(t, matches, 1)
+	assert.Equal(t, map[string]string{
+		"firstname": "<mark>Ada</mark>",
+		"lastname":  "<mark>Love</mark>lace",
+		"email":     "<mark>ada</mark>@example.com",
+	}, matches[0].Highlights)
+}
+
+func TestSearchUsers_LimitsResults(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	mockRepo.On("Search", mock.Anything, "ada", MaxSearchLimit).Return([]*entity.UserMatch{}, nil)
+
+	_, err := userUseCase.SearchUsers(context.Background(), "ada", 1000)
+
+	require.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSearchUsers_InvalidQuery(t *testing.T) {
+	userUseCase := NewUserUseCase(new(mocks.UserRepository), WithAuthorizer(allowAll{}))
+
+	for _, tc := range []struct {
+		query string
+		limit int
+	}{
+		{query: " "},
+		{query: "@.-"},
+		{query: strings.Repeat("a", MaxSearchQueryLen+1)},
+		{query: "ada", limit: -1},
+	} {
+		_, err := userUseCase.SearchUsers(context.Background(), tc.query, tc.limit)
+
+		var appErr *appErrors.AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, codes.InvalidArgument, appErr.Code)
+	}
+}
+
+func TestSearchUsers_RequiresPermission(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	_, err := userUseCase.SearchUsers(context.Background(), "ada@example.com", 0)
+
+	assert.Equal(t, appErrors.ErrForbidden, err)
+	mockRepo.AssertNotCalled(t, "Search", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestListUsers_InvalidFilter(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	_, err := userUseCase.ListUsers(context.Background(), "age >= 18 AND nickname = x", uuid.Nil, 10)
+
+	var appErr *appErrors.AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, codes.InvalidArgument, appErr.Code)
+	assert.Contains(t, appErr.Message, `invalid filter at column 15: unknown field "nickname"`)
+	mockRepo.AssertNotCalled(t, "List", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// allowAll authorizes every call
+type allowAll struct{}
+
+func (allowAll) Authorize(context.Context, string) error { return nil }
+
+func TestLookupUser_NormalizesEmail(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	user := &entity.User{ID: uuid.New(), Email: "alice@example.com"}
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(user, nil)
+	mockRepo.On("GetByEmail", mock.Anything, "bob@example.com").Return(nil, gorm.ErrRecordNotFound)
+
+	got, err := userUseCase.LookupUser(context.Background(), " Alice@Example.com ")
+	require.NoError(t, err)
+	assert.Equal(t, user, got)
+
+	_, err = userUseCase.LookupUser(context.Background(), "bob@example.com")
+	assert.Equal(t, appErrors.ErrNotFound, err)
+
+	_, err = userUseCase.LookupUser(context.Background(), " ")
+	assert.Equal(t, codes.InvalidArgument, err.(*appErrors.AppError).Code)
+}
+
+func TestLookupUser_RequiresAuthorization(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+
+	// Without an Authorizer lookups are refused
+	_, err := NewUserUseCase(mockRepo).LookupUser(context.Background(), "alice@example.com")
+	assert.Equal(t, appErrors.ErrForbidden, err)
+	err = NewUserUseCase(mockRepo).CheckUserExists(context.Background(), uuid.New())
+	assert.Equal(t, appErrors.ErrForbidden, err)
+
+	mockRepo.AssertNotCalled(t, "GetByEmail", mock.Anything, mock.Anything)
+	mockRepo.AssertNotCalled(t, "Exists", mock.Anything, mock.Anything)
+}
+
+func TestCheckUserExists(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithAuthorizer(allowAll{}))
+
+	existing, missing := uuid.New(), uuid.New()
+	mockRepo.On("Exists", mock.Anything, existing).Return(true, nil)
+	mockRepo.On("Exists", mock.Anything, missing).Return(false, nil)
+
+	assert.NoError(t, userUseCase.CheckUserExists(context.Background(), existing))
+	assert.Equal(t, appErrors.ErrNotFound, userUseCase.CheckUserExists(context.Background(), missing))
+}
+
+func TestCreateUser_ComparesCanonicalEmail(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo)
+
+	mockRepo.On("GetByEmail", mock.Anything, "alice@example.com").Return(&entity.User{ID: uuid.New()}, nil)
+
+	user := &entity.User{Firstname: "Alice", Lastname: "Smith", Email: " Alice@Example.com", Age: 28}
+	err := userUseCase.CreateUser(context.Background(), user)
+
+	assert.Equal(t, appErrors.ErrConflict, err)
+	assert.Equal(t, "Alice@Example.com", user.Email, "the address is kept as given")
+	assert.Equal(t, "alice@example.com", user.CanonicalEmail)
+}
+
+func TestCreateUser_ProviderEmailRules(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithProviderEmailRules())
+
+	mockRepo.On("GetByEmail", mock.Anything, "alicesmith@gmail.com").Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil)
+
+	user := &entity.User{Firstname: "Alice", Lastname: "Smith", Email: "Alice.Smith+news@googlemail.com", Age: 28}
+	require.NoError(t, userUseCase.CreateUser(context.Background(), user))
+
+	assert.Equal(t, "Alice.Smith+news@googlemail.com", user.Email)
+	assert.Equal(t, "alicesmith@gmail.com", user.CanonicalEmail)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestCreateUser_MinimumAge(t *testing.T) {
+	mockRepo := new(mocks.UserRepository)
+	userUseCase := NewUserUseCase(mockRepo, WithMinimumAge(16))
+	today := time.Now().UTC()
+
+	mockRepo.On("GetByEmail", mock.Anything, mock.Anything).Return(nil, gorm.ErrRecordNotFound)
+	mockRepo.On("Create", mock.Anything, mock.AnythingOfType("*entity.User")).Return(nil)
+
+	tooYoung := &entity.User{Firstname: "Kid", Lastname: "Young", Email: "kid@example.com", DateOfBirth: entity.BornBy(16, today).AddDate(0, 0, 1)}
+	err := userUseCase.CreateUser(context.Background(), tooYoung)
+	assert.Equal(t, appErrors.NewAppError(codes.InvalidArgument, "user must be at least 16 years old"), err)
+
+	legacy := &entity.User{Firstname: "Kid", Lastname: "Young", Email: "kid@example.com", Age: 15}
+	err = userUseCase.CreateUser(context.Background(), legacy)
+	assert.Equal(t, appErrors.NewAppError(codes.InvalidArgument, "user must be at least 16 years old"), err)
+
+	oldEnough := &entity.User{Firstname: "Teen", Lastname: "Young", Email: "teen@example.com", DateOfBirth: entity.BornBy(16, today)}
+	require.NoError(t, userUseCase.CreateUser(context.Background(), oldEnough))
+	assert.Equal(t, uint(16), oldEnough.CurrentAge())
+}
+
+func TestCreateUser_InvalidDateOfBirth(t *testing.T) {
+	userUseCase := NewUserUseCase(new(mocks.UserRepository))
+	today := time.Now().UTC()
+
+	for dateOfBirth, msg := range map[time.Time]string{
+		today.AddDate(0, 0, 1):                "date_of_birth must not be in the future",
+		entity.BornBy(entity.MaxAge+1, today): "date_of_birth must not be more than 150 years ago",
+	} {
+		user := &entity.User{Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", DateOfBirth: dateOfBirth, Age: 28}
+		err := userUseCase.CreateUser(context.Background(), user)
+		assert.Equal(t, appErrors.NewAppError(codes.InvalidArgument, msg), err)
+	}
+}
+
+func TestUpdateUser_AgeOnlyKeepsDateOfBirth(t *testing.T) {
+	userID := uuid.New()
+	dateOfBirth := time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC)
+	age := entity.AgeOn(dateOfBirth, time.Now())
+
+	for _, tc := range []struct {
+		name            string
+		age             uint
+		wantDateOfBirth time.Time
+	}{
+		{name: "age unchanged", age: age, wantDateOfBirth: dateOfBirth},
+		{name: "age changed", age: age + 1, wantDateOfBirth: time.Time{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mockRepo := new(mocks.UserRepository)
+			userUseCase := NewUserUseCase(mockRepo)
+			existingUser := &entity.User{ID: userID, Firstname: "Alice", Lastname: "Smith", Email: "alice@example.com", DateOfBirth: dateOfBirth}
+			mockRepo.On("GetByIDForUpdate", mock.Anything, userID).Return(existingUser, nil)
+			mockRepo.On("Update", mock.Anything, existingUser).Return(nil)
+
+			user := &entity.User{ID: userID, Firstname: "Alice", Lastname: "Jones", Email: "alice@example.com", Age: tc.age}
+			require.NoError(t, userUseCase.UpdateUser(context.Background(), user))
+
+			assert.Equal(t, "Jones", existingUser.Lastname)
+			assert.Equal(t, tc.wantDateOfBirth, existingUser.DateOfBirth)
+			assert.Equal(t, tc.age, existingUser.CurrentAge())
+		})
+	}
+}
@@ -0,0 +1,230 @@
+// Package userclient is the Go client for the UserService gRPC API.
+//
+// It wraps the generated stub with domain types, default deadlines, retries
+// of idempotent calls and errors that can be compared with the sentinel
+// errors of this package:
+//
+//	conn, err := grpc.NewClient("users:9090", grpc.WithTransportCredentials(creds))
+//	...
+//	users := userclient.New(conn)
+//	user, err := users.GetUser(ctx, id)
+//	if errors.Is(err, userclient.ErrNotFound) {
+//		...
+//	}
+//
+// Tests can run against the in-process fake in package userclienttest.
+package userclient
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	userapi "github.com/interimme/userapi/proto"
+)
+
+// User is a user of the UserService.
+type User struct {
+	ID        uuid.UUID // Set by the service on creation
+	Firstname string
+	Lastname  string
+	Email     string
+	// DateOfBirth is a date at midnight UTC. Without one, the user has the
+	// Age as given; with one, the service computes Age from it.
+	DateOfBirth time.Time
+	Age         uint
+	Created     time.Time // Set by the service on creation
+	Updated     time.Time // Set by the service on every write
+}
+
+// Defaults of the client options.
+const (
+	DefaultTimeout        = 10 * time.Second
+	DefaultMaxAttempts    = 4
+	DefaultInitialBackoff = 100 * time.Millisecond
+	DefaultMaxBackoff     = 2 * time.Second
+)
+
+// Client calls the UserService. It is safe for concurrent use.
+type Client struct {
+	stub           userapi.UserServiceClient
+	timeout        time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithTimeout sets the deadline of calls whose context has none, covering all
+// attempts of a call. Zero disables the default deadline.
+func WithTimeout(d time.Duration) Option {
+	return func(c *Client) { c.timeout = d }
+}
+
+// WithRetries sets how often idempotent calls are attempted in total and the
+// backoff between attempts, which doubles up to maxBackoff. One attempt
+// disables retries.
+func WithRetries(maxAttempts int, initialBackoff, maxBackoff time.Duration) Option {
+	return func(c *Client) {
+		c.maxAttempts = max(maxAttempts, 1)
+		c.initialBackoff = initialBackoff
+		c.maxBackoff = max(maxBackoff, initialBackoff)
+	}
+}
+
+// New returns a client that calls the service over cc. The caller owns cc and
+// closes it when done.
+func New(cc grpc.ClientConnInterface, opts ...Option) *Client {
+	c := &Client{
+		stub:           userapi.NewUserServiceClient(cc),
+		timeout:        DefaultTimeout,
+		maxAttempts:    DefaultMaxAttempts,
+		initialBackoff: DefaultInitialBackoff,
+		maxBackoff:     DefaultMaxBackoff,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// CreateUser creates a user and returns it with its ID and creation time.
+// It is not retried because a retry could create the user twice.
+func (c *Client) CreateUser(ctx context.Context, user *User) (*User, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	resp, err := c.stub.CreateUser(ctx, &userapi.CreateUserRequest{User: toProto(user)})
+	if err != nil {
+		return nil, fromError(err)
+	}
+	return fromProto(resp.GetUser())
+}
+
+// GetUser returns the user with the given ID.
+func (c *Client) GetUser(ctx context.Context, id uuid.UUID) (*User, error) {
+	var resp *userapi.GetUserResponse
+	err := c.retry(ctx, func(ctx context.Context, _ int) (err error) {
+		resp, err = c.stub.GetUser(ctx, &userapi.GetUserRequest{Id: id.String()})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fromProto(resp.GetUser())
+}
+
+// UpdateUser replaces the fields of the user with user.ID and returns the
+// updated user.
+func (c *Client) UpdateUser(ctx context.Context, user *User) (*User, error) {
+	var resp *userapi.UpdateUserResponse
+	err := c.retry(ctx, func(ctx context.Context, _ int) (err error) {
+		resp, err = c.stub.UpdateUser(ctx, &userapi.UpdateUserRequest{Id: user.ID.String(), User: toProto(user)})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fromProto(resp.GetUser())
+}
+
+// DeleteUser deletes the user with the given ID. A retry that finds the user
+// gone counts as success, since an earlier attempt must have deleted it.
+func (c *Client) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	return c.retry(ctx, func(ctx context.Context, attempt int) error {
+		_, err := c.stub.DeleteUser(ctx, &userapi.DeleteUserRequest{Id: id.String()})
+		if attempt > 1 && status.Code(err) == codes.NotFound {
+			return nil
+		}
+		return err
+	})
+}
+
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.timeout)
+}
+
+// retry runs call until it succeeds, fails with a code that is not worth
+// retrying, runs out of attempts or the deadline passes.
+func (c *Client) retry(ctx context.Context, call func(ctx context.Context, attempt int) error) error {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+
+	backoff := c.initialBackoff
+	for attempt := 1; ; attempt++ {
+		err := fromError(call(ctx, attempt))
+		if err == nil || attempt >= c.maxAttempts || !retryable(err.(*Error).Code) {
+			return err
+		}
+
+		// Full jitter spreads out clients that failed at the same time
+		timer := time.NewTimer(time.Duration(rand.Int64N(int64(backoff) + 1)))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff = min(2*backoff, c.maxBackoff)
+	}
+}
+
+// retryable reports whether a failed idempotent call may succeed when repeated.
+func retryable(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.Aborted, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
+
+func toProto(u *User) *userapi.User {
+	pu := &userapi.User{
+		Firstname: u.Firstname,
+		Lastname:  u.Lastname,
+		Email:     u.Email,
+		Age:       uint32(u.Age),
+	}
+	if !u.DateOfBirth.IsZero() {
+		pu.DateOfBirth = u.DateOfBirth.Format(time.DateOnly)
+	}
+	return pu
+}
+
+func fromProto(u *userapi.User) (*User, error) {
+	id, err := uuid.Parse(u.GetId())
+	if err != nil {
+		return nil, &Error{Code: codes.Internal, Message: "invalid user ID in response: " + u.GetId()}
+	}
+	user := &User{
+		ID:        id,
+		Firstname: u.GetFirstname(),
+		Lastname:  u.GetLastname(),
+		Email:     u.GetEmail(),
+		Age:       uint(u.GetAge()),
+	}
+	if u.GetDateOfBirth() != "" {
+		user.DateOfBirth, err = time.Parse(time.DateOnly, u.GetDateOfBirth())
+		if err != nil {
+			return nil, &Error{Code: codes.Internal, Message: "invalid date of birth in response: " + u.GetDateOfBirth()}
+		}
+	}
+	if u.GetCreated() != nil {
+		user.Created = u.GetCreated().AsTime()
+	}
+	if u.GetUpdated() != nil {
+		user.Updated = u.GetUpdated().AsTime()
+	}
+	return user, nil
+}
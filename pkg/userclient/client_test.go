@@ -0,0 +1,136 @@
+package userclient_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/interimme/userapi/pkg/userclient"
+	"github.com/interimme/userapi/pkg/userclient/userclienttest"
+)
+
+func newUser() *userclient.User {
+	return &userclient.User{Firstname: "Ada", Lastname: "Lovelace", Email: "ada@example.com", Age: 36}
+}
+
+func TestClient_CRUD(t *testing.T) {
+	client := userclienttest.NewServer(t).Client()
+	ctx := context.Background()
+
+	created, err := client.CreateUser(ctx, newUser())
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.ID)
+	assert.False(t, created.Created.IsZero())
+
+	got, err := client.GetUser(ctx, created.ID)
+	require.NoError(t, err)
+	assert.Equal(t, created.Email, got.Email)
+
+	got.Age = 37
+	updated, err := client.UpdateUser(ctx, got)
+	require.NoError(t, err)
+	assert.Equal(t, uint(37), updated.Age)
+	assert.Equal(t, created.Created, updated.Created)
+	assert.False(t, updated.Updated.Before(created.Updated))
+
+	require.NoError(t, client.DeleteUser(ctx, created.ID))
+	_, err = client.GetUser(ctx, created.ID)
+	assert.ErrorIs(t, err, userclient.ErrNotFound)
+}
+
+func TestClient_Errors(t *testing.T) {
+	srv := userclienttest.NewServer(t)
+	client := srv.Client()
+	ctx := context.Background()
+	existing := srv.AddUser(*newUser())
+
+	_, err := client.CreateUser(ctx, newUser())
+	assert.ErrorIs(t, err, userclient.ErrConflict)
+
+	invalid := newUser()
+	invalid.Email = "not-an-email"
+	_, err = client.CreateUser(ctx, invalid)
+	assert.ErrorIs(t, err, userclient.ErrBadRequest)
+	assert.EqualError(t, err, "invalid email format", "the server's message is kept")
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	assert.ErrorIs(t, client.DeleteUser(ctx, uuid.New()), userclient.ErrNotFound)
+	assert.False(t, errors.Is(client.DeleteUser(ctx, uuid.New()), userclient.ErrConflict))
+
+	existing.Age = 0
+	_, err = client.UpdateUser(ctx, &existing)
+	assert.ErrorIs(t, err, userclient.ErrBadRequest)
+}
+
+func TestClient_RetriesIdempotentCalls(t *testing.T) {
+	srv := userclienttest.NewServer(t)
+	client := srv.Client()
+	user := srv.AddUser(*newUser())
+
+	srv.Fail(2, codes.Unavailable)
+	got, err := client.GetUser(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, got.ID)
+	assert.Equal(t, 3, srv.Calls("GetUser"))
+
+	srv.Fail(userclient.DefaultMaxAttempts, codes.Unavailable)
+	_, err = client.GetUser(context.Background(), user.ID)
+	assert.ErrorIs(t, err, userclient.ErrUnavailable)
+	assert.Equal(t, 3+userclient.DefaultMaxAttempts, srv.Calls("GetUser"))
+
+	srv.Fail(1, codes.PermissionDenied)
+	_, err = client.GetUser(context.Background(), user.ID)
+	assert.ErrorIs(t, err, userclient.ErrForbidden)
+	assert.Equal(t, 4+userclient.DefaultMaxAttempts, srv.Calls("GetUser"), "only transient errors are retried")
+}
+
+func TestClient_DoesNotRetryCreate(t *testing.T) {
+	srv := userclienttest.NewServer(t)
+	client := srv.Client()
+
+	srv.Fail(1, codes.Unavailable)
+	_, err := client.CreateUser(context.Background(), newUser())
+	assert.ErrorIs(t, err, userclient.ErrUnavailable)
+	assert.Equal(t, 1, srv.Calls("CreateUser"))
+}
+
+func TestClient_RetriedDeleteOfDeletedUser(t *testing.T) {
+	srv := userclienttest.NewServer(t)
+	client := srv.Client()
+	user := srv.AddUser(*newUser())
+
+	require.NoError(t, client.DeleteUser(context.Background(), user.ID))
+
+	// The user is gone, as if the response of a first attempt had been lost
+	srv.Fail(1, codes.Unavailable)
+	assert.NoError(t, client.DeleteUser(context.Background(), user.ID))
+	assert.Equal(t, 3, srv.Calls("DeleteUser"))
+}
+
+func TestClient_DefaultTimeout(t *testing.T) {
+	srv := userclienttest.NewServer(t)
+	client := srv.Client(
+		userclient.WithTimeout(20*time.Millisecond),
+		userclient.WithRetries(100, 10*time.Millisecond, 10*time.Millisecond),
+	)
+
+	srv.Fail(100, codes.Unavailable)
+	start := time.Now()
+	_, err := client.GetUser(context.Background(), uuid.New())
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second, "the deadline covers all attempts")
+	assert.Less(t, srv.Calls("GetUser"), 100)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	<-ctx.Done()
+	_, err = client.GetUser(ctx, uuid.New())
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,60 @@
+package userclient
+
+import (
+	"context"
+	"errors"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Error is an error returned by the UserService. Compare it with the sentinel
+// errors using errors.Is; the message is the one sent by the server.
+type Error struct {
+	Code    codes.Code // gRPC status code
+	Message string     // Error message
+}
+
+// Error implements the error interface.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is a sentinel error with the same code. Errors
+// with the codes Canceled and DeadlineExceeded also match the context errors.
+func (e *Error) Is(target error) bool {
+	switch target {
+	case context.Canceled:
+		return e.Code == codes.Canceled
+	case context.DeadlineExceeded:
+		return e.Code == codes.DeadlineExceeded
+	}
+	var t *Error
+	return errors.As(target, &t) && t.Code == e.Code
+}
+
+// GRPCStatus allows status.FromError and status.Code to inspect the error.
+func (e *Error) GRPCStatus() *status.Status {
+	return status.New(e.Code, e.Message)
+}
+
+// Sentinel errors matching those of the service. Codes without a sentinel
+// still produce an *Error carrying the code.
+var (
+	ErrBadRequest   = &Error{Code: codes.InvalidArgument, Message: "bad request"}
+	ErrUnauthorized = &Error{Code: codes.Unauthenticated, Message: "unauthorized"}
+	ErrForbidden    = &Error{Code: codes.PermissionDenied, Message: "forbidden"}
+	ErrNotFound     = &Error{Code: codes.NotFound, Message: "user not found"}
+	ErrConflict     = &Error{Code: codes.AlreadyExists, Message: "email already exists"}
+	ErrUnavailable  = &Error{Code: codes.Unavailable, Message: "service unavailable"}
+	ErrInternal     = &Error{Code: codes.Internal, Message: "internal server error"}
+)
+
+// fromError converts an error returned by a gRPC call into an *Error.
+func fromError(err error) error {
+	if err == nil {
+		return nil
+	}
+	s := status.Convert(err)
+	return &Error{Code: s.Code(), Message: s.Message()}
+}
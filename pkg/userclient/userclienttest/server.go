@@ -0,0 +1,151 @@
+// Package userclienttest provides an in-process UserService for tests of code
+// that uses package userclient.
+//
+// The fake runs the service's own gRPC handlers and use cases on an in-memory
+// store, so validation and errors match a real deployment:
+//
+//	srv := userclienttest.NewServer(t)
+//	client := srv.Client()
+//	srv.Fail(2, codes.Unavailable) // the next two calls fail
+package userclienttest
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/interimme/userapi/internal/entity"
+	"github.com/interimme/userapi/internal/grpcserver"
+	"github.com/interimme/userapi/internal/infrastructure/persistence"
+	"github.com/interimme/userapi/internal/usecase"
+	"github.com/interimme/userapi/pkg/userclient"
+	userapi "github.com/interimme/userapi/proto"
+)
+
+// Server is an in-process UserService listening on an in-memory connection.
+type Server struct {
+	tb   testing.TB
+	repo usecase.UserRepository
+	conn *grpc.ClientConn
+
+	mu       sync.Mutex
+	failures []codes.Code
+	calls    map[string]int
+}
+
+// NewServer starts a fake UserService that is stopped when the test ends.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	s := &Server{
+		tb:    tb,
+		repo:  persistence.NewMemoryUserRepository(),
+		calls: make(map[string]int),
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer(grpc.UnaryInterceptor(s.intercept))
+	userapi.RegisterUserServiceServer(gs, grpcserver.NewServer(usecase.NewUserUseCase(s.repo)))
+	go func() { _ = gs.Serve(lis) }()
+
+	conn, err := grpc.NewClient("passthrough:///userclienttest",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		tb.Fatalf("userclienttest: %v", err)
+	}
+	s.conn = conn
+
+	tb.Cleanup(func() {
+		_ = conn.Close()
+		gs.Stop()
+	})
+	return s
+}
+
+// Conn returns the client connection to the server, e.g. for the generated stub.
+func (s *Server) Conn() *grpc.ClientConn {
+	return s.conn
+}
+
+// Client returns a userclient connected to the server. Unless opts say
+// otherwise, retries wait only a millisecond so that tests stay fast.
+func (s *Server) Client(opts ...userclient.Option) *userclient.Client {
+	opts = append([]userclient.Option{
+		userclient.WithRetries(userclient.DefaultMaxAttempts, time.Millisecond, time.Millisecond),
+	}, opts...)
+	return userclient.New(s.conn, opts...)
+}
+
+// Fail makes the next n calls fail with code before reaching the service.
+func (s *Server) Fail(n int, code codes.Code) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, code)
+	}
+}
+
+// Calls returns how often the method, e.g. "GetUser", was called, including
+// failed calls.
+func (s *Server) Calls(method string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[method]
+}
+
+// AddUser stores a user directly, bypassing validation. Missing IDs and
+// timestamps are filled in; the stored user is returned.
+func (s *Server) AddUser(user userclient.User) userclient.User {
+	s.tb.Helper()
+	if user.ID == uuid.Nil {
+		user.ID = uuid.New()
+	}
+	stored := &entity.User{
+		ID:          user.ID,
+		Firstname:   user.Firstname,
+		Lastname:    user.Lastname,
+		Email:       user.Email,
+		DateOfBirth: user.DateOfBirth,
+		Age:         user.Age,
+		Created:     user.Created,
+		Updated:     user.Updated,
+	}
+	if err := s.repo.Create(context.Background(), stored); err != nil {
+		s.tb.Fatalf("userclienttest: adding user %s: %v", user.Email, err)
+	}
+	user.Created, user.Updated = stored.Created, stored.Updated
+	return user
+}
+
+// intercept counts calls and injects the failures queued by Fail.
+func (s *Server) intercept(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	s.mu.Lock()
+	s.calls[methodName(info.FullMethod)]++
+	var code codes.Code
+	if len(s.failures) > 0 {
+		code, s.failures = s.failures[0], s.failures[1:]
+	}
+	s.mu.Unlock()
+
+	if code != codes.OK {
+		return nil, status.Error(code, "injected by userclienttest")
+	}
+	return handler(ctx, req)
+}
+
+// methodName returns the method of a full gRPC method name like
+// "/userapi.UserService/GetUser".
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}